@@ -9,18 +9,57 @@ to stdout in this format:
 
 where a single space separates each of the 5 fields.
 
+If the '-sort' flag is set, then the windows of every chain are sorted by the
+RMSD between each window and its best fragment (smallest first), and the RMSD
+is printed as a sixth field. Windows with equal RMSD are kept in order of
+position.
+
+If the '-tie-lowest' flag is set, then when more than one fragment has the
+best RMSD for a window, the fragment with the lowest number is chosen.
+Otherwise, ties are broken by the fragment library.
+
+If the '-pml' flag is set, then a PyMOL script is also written that sets the
+B-factor of each residue in a printed window to the smallest RMSD of the
+windows containing it. Residues are then colored from blue (smallest RMSD,
+most fragment-like) to red (largest RMSD). Residues that are not in any
+window have a B-factor of -1 and are colored gray. The script should be run
+after loading the PDB file in PyMOL (e.g., '@out.pml').
+
+If the '-gaps' flag is set, then the residues of each scanned chain that are
+not in any window where every residue has an alpha-carbon atom (e.g.,
+disordered regions) are written to the file given as tab-separated lines of
+the form 'chain start end length'. Positions are indices into the chain's
+sequence (i.e., SEQRES) starting at 1. The whole chain is reported even when
+only some regions of it are scanned.
+
+If the '-het-breaks' flag is set, then windows that would span a non-standard
+residue (e.g., a ligand or an ion within a chain) are skipped. Atom indices
+are unchanged.
+
 The region specified should be inclusive starting with the number one.
 
+If the '-resnum' flag is set, then the start and end of each window are
+printed as the PDB residue numbers (including insertion codes) of the
+corresponding alpha-carbon atoms. If the residue numbers cannot be determined,
+then atom indices are printed instead along with a warning.
+
 If no region is specified, then the best fragment for every region in the given
 chain will be computed.
 
 If no chain is specified, then the best fragment for every chain in the
 given PDB file will be computed.
 
+Alternatively, a file of regions may be given with the '-regions' flag, where
+each line has the form 'chain-id start stop'. The best fragments for each
+region are printed in the order in which the regions are listed. Regions that
+are invalid are skipped with a warning.
+
 A PDB file may either be plain text or compressed using the Lempel-Ziv coding
 (i.e., gzip). If the PDB file is gzipped, it must end with a '.gz' extension.
+If the PDB file is '-', then it is read from stdin, and gzip compression is
+detected automatically.
 
 Usage:
-	bestfrag fraglib pdb-file [ chain-id [ start stop ] ]
+	bestfrag [flags] fraglib pdb-file [ chain-id [ start stop ] ]
 */
 package main
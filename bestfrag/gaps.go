@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/TuftsBCB/io/pdb"
+)
+
+// scanned is every chain scanned for best fragments, in order and without
+// duplicates. It is only kept when '-gaps' is set.
+var scanned []*pdb.Chain
+
+// addScanned records that the chain given was scanned.
+func addScanned(chain *pdb.Chain) {
+	if len(flagGaps) == 0 {
+		return
+	}
+	for _, c := range scanned {
+		if c == chain {
+			return
+		}
+	}
+	scanned = append(scanned, chain)
+}
+
+// sequenceGaps returns the ranges of residues of the chain's sequence that
+// are not in any window of `fsize` residues where every residue has an
+// alpha-carbon atom (i.e., where SequenceCaAtomSlice is not nil). Ranges are
+// inclusive and start at 1.
+func sequenceGaps(chain *pdb.Chain, fsize int) [][2]int {
+	n := len(chain.Sequence)
+	covered := make([]bool, n)
+	for i := 0; i+fsize <= n; i++ {
+		if chain.SequenceCaAtomSlice(i, i+fsize) == nil {
+			continue
+		}
+		for j := i; j < i+fsize; j++ {
+			covered[j] = true
+		}
+	}
+
+	var gaps [][2]int
+	for i := 0; i < n; i++ {
+		if covered[i] {
+			continue
+		}
+		start := i
+		for i+1 < n && !covered[i+1] {
+			i++
+		}
+		gaps = append(gaps, [2]int{start + 1, i + 1})
+	}
+	return gaps
+}
+
+// writeGaps writes the gaps of every chain given with one gap per line in
+// the form 'chain start end length'. Positions are indices of residues in
+// the chain's sequence (e.g., SEQRES) starting at 1.
+func writeGaps(w io.Writer, chains []*pdb.Chain, fsize int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "chain\tstart\tend\tlength\n")
+	for _, chain := range chains {
+		for _, gap := range sequenceGaps(chain, fsize) {
+			fmt.Fprintf(bw, "%s%c\t%d\t%d\t%d\n",
+				chain.Entry.IdCode, chain.Ident,
+				gap[0], gap[1], gap[1]-gap[0]+1)
+		}
+	}
+	return bw.Flush()
+}
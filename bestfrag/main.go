@@ -1,42 +1,115 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
-	"github.com/ndaniels/esfragbag"
 	"github.com/TuftsBCB/io/pdb"
 	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
 	"github.com/ndaniels/tools/util"
 )
 
-var lib fragbag.StructureLibrary
+var (
+	lib fragbag.StructureLibrary
+
+	flagSort    = false
+	flagRegions = ""
+	flagResnum  = false
+	flagLowest  = false
+	flagPml     = ""
+	flagGaps    = ""
+
+	// printed is every window printed, in order. It is only kept when
+	// '-pml' is set.
+	printed []window
+)
 
 func init() {
+	flag.BoolVar(&flagSort, "sort", flagSort,
+		"When set, all windows are sorted by the RMSD between the window\n"+
+			"and its best fragment (smallest first), and the RMSD is shown\n"+
+			"as an additional column. Ties are broken by position.")
+	flag.StringVar(&flagRegions, "regions", flagRegions,
+		"When set, the best fragments are computed for each region in the\n"+
+			"file given, where each line has the form 'chain-id start stop'.\n"+
+			"Results are grouped by region. Invalid regions are skipped with\n"+
+			"a warning. This may not be used with a chain or range argument.")
+	flag.BoolVar(&flagResnum, "resnum", flagResnum,
+		"When set, the start and end of each window are shown as PDB\n"+
+			"residue numbers (with insertion codes) instead of alpha-carbon\n"+
+			"atom indices. Regions given as input are still atom indices.")
+	flag.BoolVar(&flagLowest, "tie-lowest", flagLowest,
+		"When set, fragments that tie for the best RMSD in a window are\n"+
+			"broken in favor of the lowest fragment number, so that output\n"+
+			"does not depend on how the library breaks ties. This requires\n"+
+			"comparing each window with every fragment.")
+	flag.StringVar(&flagPml, "pml", flagPml,
+		"When set, a PyMOL script is written to this file that sets the\n"+
+			"B-factor of each residue to the smallest RMSD of the windows\n"+
+			"containing it, and colors residues by B-factor from blue\n"+
+			"(most fragment-like) to red. Residues in no window are gray.")
+	flag.StringVar(&flagGaps, "gaps", flagGaps,
+		"When set, the ranges of residues of each chain's sequence that\n"+
+			"are in no window with an alpha-carbon atom for every residue\n"+
+			"(e.g., disordered regions) are written to this file. Ranges are\n"+
+			"sequence (SEQRES) indices starting at 1.")
+}
+
+// window is the best fragment for a region of a chain, where the region is
+// described by inclusive alpha-carbon atom indices starting at 1. The score
+// is the RMSD between the region and the best fragment.
+//
+// When '-resnum' is set, startRes and endRes are the PDB residue numbers
+// corresponding to start and end.
+type window struct {
+	chain            *pdb.Chain
+	start, end       int
+	startRes, endRes string
+	frag             int
+	score            float64
+}
+
+func main() {
 	u := "fraglib pdb-file [ chain-id [ start stop ] ]"
+	util.FlagUse("cpu", "altloc", "het-breaks")
 	util.FlagParse(u, "")
 	util.AssertLeastNArg(2)
-}
 
-func main() {
 	lib = util.StructureLibrary(util.Arg(0))
 	pdbEntry := util.PDBRead(util.Arg(1))
 
-	if util.NArg() == 2 {
+	if len(flagRegions) > 0 {
+		if util.NArg() != 2 {
+			log.Println("The '-regions' flag cannot be used with a chain id.")
+			util.Usage()
+		}
+		bestFragsForRegionsFile(pdbEntry, flagRegions)
+	} else if util.NArg() == 2 {
 		for _, chain := range pdbEntry.Chains {
-			atoms := chain.CaAtoms()
-			bestFragsForRegion(chain, atoms, 0, len(atoms))
+			if chain.IsProtein() {
+				addScanned(chain)
+			}
 		}
+		printWindows(entryWindows(pdbEntry, util.FlagCpu))
 	} else {
 		chainId := util.Arg(2)
 		chain := pdbEntry.Chain(chainId[0])
 		if chain == nil || !chain.IsProtein() {
-			util.Fatalf("Could not find protein chain with id '%c'.", chainId)
+			util.Fatalf("Could not find protein chain with id '%s'.", chainId)
 		}
 		atoms := chain.CaAtoms()
+		util.Assert(util.CheckFragmentSize(lib, len(atoms)),
+			"Chain '%c' is too short", chain.Ident)
+		addScanned(chain)
 
 		if util.NArg() == 3 {
-			bestFragsForRegion(chain, atoms, 0, len(atoms))
+			printWindows(bestFragsForRegion(chain, atoms, 0, len(atoms)))
 		} else {
 			if util.NArg() != 5 {
 				log.Println("Both a start and end must be provided.")
@@ -44,22 +117,229 @@ func main() {
 			}
 
 			s, e := util.Arg(3), util.Arg(4)
-			sn, en := util.ParseInt(s)-1, util.ParseInt(e)
-			if en-sn < lib.FragmentSize() {
-				util.Fatalf("The range [%s, %s] specifies %d alpha-carbon "+
-					"atoms while at least %d alpha-carbon atoms are required "+
-					"for the given fragment library.",
-					s, e, en-sn, lib.FragmentSize())
+			sn, en, err := parseRange(s, e, len(atoms))
+			util.Assert(err)
+			printWindows(bestFragsForRegion(chain, atoms, sn, en))
+		}
+	}
+	if len(flagPml) > 0 {
+		f := util.CreateFile(flagPml)
+		util.Assert(writePml(f, printed), "Could not write '%s'", flagPml)
+		util.Assert(f.Close(), "Could not write '%s'", flagPml)
+	}
+	if len(flagGaps) > 0 {
+		f := util.CreateFile(flagGaps)
+		util.Assert(writeGaps(f, scanned, lib.FragmentSize()),
+			"Could not write '%s'", flagGaps)
+		util.Assert(f.Close(), "Could not write '%s'", flagGaps)
+	}
+}
+
+// entryWindows returns the best fragments for every chain of the entry.
+// Chains are computed in parallel by `workers` goroutines, but their windows
+// are returned in the order in which the chains appear in the entry.
+func entryWindows(entry *pdb.Entry, workers int) []window {
+	results := make([][]window, len(entry.Chains))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ci := range jobs {
+				chain := entry.Chains[ci]
+				atoms := chain.CaAtoms()
+				err := util.CheckFragmentSize(lib, len(atoms))
+				if util.Warning(err, "Skipping chain '%c'", chain.Ident) {
+					continue
+				}
+				results[ci] = bestFragsForRegion(chain, atoms, 0, len(atoms))
 			}
-			bestFragsForRegion(chain, atoms, sn, en)
+		}()
+	}
+	for ci := range entry.Chains {
+		jobs <- ci
+	}
+	close(jobs)
+	wg.Wait()
+
+	all := make([]window, 0)
+	for _, windows := range results {
+		all = append(all, windows...)
+	}
+	return all
+}
+
+// bestFragsForRegionsFile prints the best fragments for every region listed
+// in the file at `fpath`. Each region is printed separately.
+func bestFragsForRegionsFile(entry *pdb.Entry, fpath string) {
+	f := util.OpenFile(fpath)
+	defer f.Close()
+
+	for i, line := range util.ReadLines(f) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			util.Warnf("Skipping line %d in '%s': expected 'chain-id start "+
+				"stop' but got '%s'.", i+1, fpath, line)
+			continue
+		}
+
+		chainId, s, e := fields[0], fields[1], fields[2]
+		chain := entry.Chain(chainId[0])
+		if len(chainId) != 1 || chain == nil || !chain.IsProtein() {
+			util.Warnf("Skipping line %d in '%s': could not find protein "+
+				"chain with id '%s'.", i+1, fpath, chainId)
+			continue
 		}
+		atoms := chain.CaAtoms()
+		sn, en, err := parseRange(s, e, len(atoms))
+		if util.Warning(err, "Skipping line %d in '%s'", i+1, fpath) {
+			continue
+		}
+		addScanned(chain)
+		printWindows(bestFragsForRegion(chain, atoms, sn, en))
 	}
 }
 
-func bestFragsForRegion(chain *pdb.Chain, atoms []structure.Coords, s, e int) {
+// parseRange converts an inclusive range of alpha-carbon atoms starting at 1
+// to a half-open range starting at 0. An error is returned if the range is
+// not within a chain with `natoms` alpha-carbon atoms or if it is too small
+// for the fragment library.
+func parseRange(s, e string, natoms int) (int, int, error) {
+	sn, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse '%s' as an integer", s)
+	}
+	en, err := strconv.Atoi(e)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not parse '%s' as an integer", e)
+	}
+	sn -= 1
+
+	if sn < 0 || en > natoms {
+		return 0, 0, fmt.Errorf("The range [%s, %s] is not within the "+
+			"chain's %d alpha-carbon atoms.", s, e, natoms)
+	}
+	if err := util.CheckFragmentSize(lib, en-sn); err != nil {
+		return 0, 0, fmt.Errorf("The range [%s, %s] is too short: %s", s, e, err)
+	}
+	return sn, en, nil
+}
+
+func bestFragsForRegion(
+	chain *pdb.Chain,
+	atoms []structure.Coords,
+	s, e int,
+) []window {
+	var resnums []string
+	if flagResnum {
+		resnums = residueNumbers(chain, len(atoms))
+	}
+
 	fsize := lib.FragmentSize()
+	segs := util.CaSegments(chain.Models[0])
+	windows := make([]window, 0, e-s)
 	for i := s; i <= e-fsize; i++ {
-		best := lib.BestStructureFragment(atoms[i : i+fsize])
-		fmt.Println(chain.Entry.IdCode, string(chain.Ident), i+1, i+fsize, best)
+		if !util.InCaSegments(segs, i, i+fsize) {
+			continue
+		}
+		region := atoms[i : i+fsize]
+		best := lib.BestStructureFragment(region)
+		score := structure.RMSD(region, lib.Atoms(best))
+		if flagLowest {
+			best = lowestTie(region, best, score)
+		}
+		w := window{
+			chain: chain,
+			start: i + 1,
+			end:   i + fsize,
+			frag:  best,
+			score: score,
+		}
+		if resnums != nil {
+			w.startRes, w.endRes = resnums[i], resnums[i+fsize-1]
+		} else {
+			w.startRes, w.endRes = strconv.Itoa(w.start), strconv.Itoa(w.end)
+		}
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// lowestTie returns the lowest numbered fragment whose RMSD with `region` is
+// equal to `score`, which is the RMSD of the fragment `best`.
+func lowestTie(region []structure.Coords, best int, score float64) int {
+	for i := 0; i < best; i++ {
+		if structure.RMSD(region, lib.Atoms(i)) == score {
+			return i
+		}
 	}
+	return best
 }
+
+// residueNumbers returns the PDB residue number (including any insertion
+// code) of every residue with an alpha-carbon atom in the first model of the
+// chain given, in the same order as the chain's alpha-carbon atoms.
+//
+// If the residues cannot be matched up with the `natoms` alpha-carbon atoms,
+// then a warning is emitted and nil is returned.
+func residueNumbers(chain *pdb.Chain, natoms int) []string {
+	var resnums []string
+	if len(chain.Models) > 0 {
+		for _, r := range chain.Models[0].Residues {
+			for _, atom := range r.Atoms {
+				if atom.Name != "CA" {
+					continue
+				}
+
+				num := strconv.Itoa(r.SequenceNum)
+				if r.InsertionCode != ' ' && r.InsertionCode != 0 {
+					num += string(r.InsertionCode)
+				}
+				resnums = append(resnums, num)
+				break
+			}
+		}
+	}
+	if len(resnums) != natoms {
+		util.Warnf("Could not determine residue numbers for chain '%c' in "+
+			"'%s'. Alpha-carbon atom indices will be used instead.",
+			chain.Ident, chain.Entry.IdCode)
+		return nil
+	}
+	return resnums
+}
+
+// printWindows prints each window on its own line. If '-sort' is set, then
+// the windows are sorted by score first (which requires that the windows
+// given are in order of position).
+func printWindows(windows []window) {
+	if flagSort {
+		sort.Stable(windowsByScore(windows))
+	}
+	if len(flagPml) > 0 {
+		printed = append(printed, windows...)
+	}
+	for _, w := range windows {
+		start, end := strconv.Itoa(w.start), strconv.Itoa(w.end)
+		if flagResnum {
+			start, end = w.startRes, w.endRes
+		}
+		if flagSort {
+			fmt.Println(w.chain.Entry.IdCode, string(w.chain.Ident),
+				start, end, w.frag, fmt.Sprintf("%0.4f", w.score))
+		} else {
+			fmt.Println(w.chain.Entry.IdCode, string(w.chain.Ident),
+				start, end, w.frag)
+		}
+	}
+}
+
+type windowsByScore []window
+
+func (ws windowsByScore) Len() int           { return len(ws) }
+func (ws windowsByScore) Less(i, j int) bool { return ws[i].score < ws[j].score }
+func (ws windowsByScore) Swap(i, j int)      { ws[i], ws[j] = ws[j], ws[i] }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// randomLib is a structure library of random fragments. The best fragment
+// of a region is the one with the smallest sum of squared distances between
+// corresponding atoms, so finding it costs about as much as in a real
+// library.
+type randomLib struct {
+	frags [][]structure.Coords
+}
+
+func newRandomLib(rng *rand.Rand, size, fsize int) randomLib {
+	lib := randomLib{make([][]structure.Coords, size)}
+	for i := range lib.frags {
+		lib.frags[i] = randomCoords(rng, fsize)
+	}
+	return lib
+}
+
+func (lib randomLib) Save(w io.Writer) error      { return nil }
+func (lib randomLib) Size() int                   { return len(lib.frags) }
+func (lib randomLib) FragmentSize() int           { return len(lib.frags[0]) }
+func (lib randomLib) String() string              { return "random" }
+func (lib randomLib) Name() string                { return "random" }
+func (lib randomLib) Tag() string                 { return "structure" }
+func (lib randomLib) Fragment(i int) interface{}  { return lib.frags[i] }
+func (lib randomLib) SubLibrary() fragbag.Library { return nil }
+
+func (lib randomLib) Atoms(i int) []structure.Coords { return lib.frags[i] }
+
+func (lib randomLib) BestStructureFragment(atoms []structure.Coords) int {
+	best, bestDist := -1, 0.0
+	for i, frag := range lib.frags {
+		dist := 0.0
+		for j := range frag {
+			dx, dy := atoms[j].X-frag[j].X, atoms[j].Y-frag[j].Y
+			dz := atoms[j].Z - frag[j].Z
+			dist += dx*dx + dy*dy + dz*dz
+		}
+		if best == -1 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func randomCoords(rng *rand.Rand, n int) []structure.Coords {
+	coords := make([]structure.Coords, n)
+	for i := range coords {
+		coords[i] = structure.Coords{
+			X: rng.Float64() * 10, Y: rng.Float64() * 10, Z: rng.Float64() * 10,
+		}
+	}
+	return coords
+}
+
+// randomEntry returns an entry of `nchains` chains, each with `n` residues
+// with random alpha-carbon atoms.
+func randomEntry(rng *rand.Rand, nchains, n int) *pdb.Entry {
+	entry := &pdb.Entry{IdCode: "1abc"}
+	for c := 0; c < nchains; c++ {
+		chain := &pdb.Chain{Entry: entry, Ident: byte('A' + c)}
+		model := &pdb.Model{Entry: entry, Chain: chain, Num: 1}
+		for i, ca := range randomCoords(rng, n) {
+			model.Residues = append(model.Residues, &pdb.Residue{
+				Name:        'A',
+				SequenceNum: i + 1,
+				Atoms:       []pdb.Atom{{Name: "CA", Coords: ca}},
+			})
+		}
+		chain.Models = []*pdb.Model{model}
+		entry.Chains = append(entry.Chains, chain)
+	}
+	return entry
+}
+
+func TestEntryWindowsOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	lib = newRandomLib(rng, 50, 5)
+	defer func() { lib = nil }()
+
+	entry := randomEntry(rng, 8, 40)
+	serial := entryWindows(entry, 1)
+	if len(serial) != 8*(40-5+1) {
+		t.Fatalf("got %d windows, want %d", len(serial), 8*(40-5+1))
+	}
+	parallel := entryWindows(entry, 4)
+	if !reflect.DeepEqual(parallel, serial) {
+		t.Errorf("parallel windows differ from serial windows")
+	}
+}
+
+func benchmarkEntryWindows(b *testing.B, workers int) {
+	rng := rand.New(rand.NewSource(1))
+	lib = newRandomLib(rng, 400, 11)
+	defer func() { lib = nil }()
+
+	entry := randomEntry(rng, 16, 300)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entryWindows(entry, workers)
+	}
+}
+
+func BenchmarkEntryWindowsSerial(b *testing.B) {
+	benchmarkEntryWindows(b, 1)
+}
+
+func BenchmarkEntryWindowsParallel(b *testing.B) {
+	benchmarkEntryWindows(b, runtime.NumCPU())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/TuftsBCB/io/pdb"
+)
+
+// residueScore is the best score of any window containing a residue.
+type residueScore struct {
+	chain  *pdb.Chain
+	resnum string
+	score  float64
+}
+
+// writePml writes a PyMOL script that sets the B-factor of every residue in
+// the windows given to the smallest score of the windows containing it. All
+// other residues are reset to a B-factor of -1 and colored gray. Residues are
+// identified by PDB residue number when possible, and by alpha-carbon atom
+// index otherwise.
+func writePml(w io.Writer, windows []window) error {
+	scores := make([]residueScore, 0)
+	index := make(map[*pdb.Chain]map[int]int) // chain -> atom -> scores index
+	resnums := make(map[*pdb.Chain][]string)
+	for _, win := range windows {
+		if _, ok := index[win.chain]; !ok {
+			index[win.chain] = make(map[int]int)
+			resnums[win.chain] = residueNumbers(
+				win.chain, len(win.chain.CaAtoms()))
+		}
+		for atom := win.start; atom <= win.end; atom++ {
+			if i, ok := index[win.chain][atom]; ok {
+				if win.score < scores[i].score {
+					scores[i].score = win.score
+				}
+				continue
+			}
+
+			resnum := strconv.Itoa(atom)
+			if nums := resnums[win.chain]; nums != nil {
+				resnum = nums[atom-1]
+			}
+			index[win.chain][atom] = len(scores)
+			scores = append(scores, residueScore{win.chain, resnum, win.score})
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "alter all, b=-1.0")
+	for _, rs := range scores {
+		fmt.Fprintf(bw, "alter %s, b=%0.4f\n", pmlSelection(rs), rs.score)
+	}
+	fmt.Fprintln(bw, "color gray, all")
+	fmt.Fprintln(bw, "spectrum b, blue_red, b > -0.5")
+	return bw.Flush()
+}
+
+// pmlSelection returns a PyMOL selection of the residue given.
+func pmlSelection(rs residueScore) string {
+	// Negative residue numbers must be escaped in PyMOL selections.
+	resi := rs.resnum
+	if strings.HasPrefix(resi, "-") {
+		resi = "\\" + resi
+	}
+	if rs.chain.Ident == ' ' {
+		return fmt.Sprintf("resi %s", resi)
+	}
+	return fmt.Sprintf("chain %c and resi %s", rs.chain.Ident, resi)
+}
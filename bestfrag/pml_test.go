@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+)
+
+// numberedChain returns a chain whose residues have an alpha-carbon atom
+// and the residue numbers and insertion codes given.
+func numberedChain(ident byte, nums []int, codes string) *pdb.Chain {
+	entry := &pdb.Entry{IdCode: "1abc"}
+	chain := &pdb.Chain{Entry: entry, Ident: ident}
+	model := &pdb.Model{Entry: entry, Chain: chain, Num: 1}
+	for i, num := range nums {
+		model.Residues = append(model.Residues, &pdb.Residue{
+			Name:          'A',
+			SequenceNum:   num,
+			InsertionCode: codes[i],
+			Atoms:         []pdb.Atom{{Name: "N"}, {Name: "CA"}},
+		})
+	}
+	chain.Models = []*pdb.Model{model}
+	entry.Chains = []*pdb.Chain{chain}
+	return chain
+}
+
+func TestWritePml(t *testing.T) {
+	a := numberedChain('A', []int{-1, 1, 2, 2, 3}, "   A ")
+	blank := numberedChain(' ', []int{10, 11, 12}, "   ")
+	windows := []window{
+		{chain: a, start: 1, end: 3, score: 0.5},
+		{chain: a, start: 2, end: 4, score: 0.25},
+		{chain: blank, start: 1, end: 3, score: 1.5},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writePml(buf, windows); err != nil {
+		t.Fatal(err)
+	}
+	want := `alter all, b=-1.0
+alter chain A and resi \-1, b=0.5000
+alter chain A and resi 1, b=0.2500
+alter chain A and resi 2, b=0.2500
+alter chain A and resi 2A, b=0.2500
+alter resi 10, b=1.5000
+alter resi 11, b=1.5000
+alter resi 12, b=1.5000
+color gray, all
+spectrum b, blue_red, b > -0.5
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got script\n%s\nwant\n%s", got, want)
+	}
+}
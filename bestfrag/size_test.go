@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// fourLib is a structure library of one fragment of four residues.
+type fourLib struct{}
+
+func (fourLib) Save(w io.Writer) error      { return nil }
+func (fourLib) Size() int                   { return 1 }
+func (fourLib) FragmentSize() int           { return 4 }
+func (fourLib) String() string              { return "four" }
+func (fourLib) Name() string                { return "four" }
+func (fourLib) Tag() string                 { return "structure" }
+func (fourLib) Fragment(i int) interface{}  { return nil }
+func (fourLib) SubLibrary() fragbag.Library { return nil }
+
+func (fourLib) BestStructureFragment([]structure.Coords) int { return 0 }
+func (fourLib) Atoms(i int) []structure.Coords               { return nil }
+
+func TestParseRangeTooShort(t *testing.T) {
+	lib = fourLib{}
+	defer func() { lib = nil }()
+
+	_, _, err := parseRange("2", "4", 10)
+	want := "The range [2, 4] is too short: input has 3 residues, " +
+		"library 'four' requires at least 4"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+
+	s, e, err := parseRange("2", "5", 10)
+	if err != nil || s != 1 || e != 5 {
+		t.Errorf("parseRange(2, 5) = (%d, %d, %v), want (1, 5, nil)",
+			s, e, err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TuftsBCB/structure"
+)
+
+func TestLowestTie(t *testing.T) {
+	region := []structure.Coords{
+		{X: 0, Y: 0, Z: 0}, {X: 3.8, Y: 0, Z: 0},
+		{X: 5, Y: 3.6, Z: 0}, {X: 8, Y: 4, Z: 2.5},
+	}
+	shifted := make([]structure.Coords, len(region))
+	stretched := make([]structure.Coords, len(region))
+	for i, c := range region {
+		shifted[i] = structure.Coords{X: c.X + 1, Y: c.Y - 2, Z: c.Z + 3}
+		stretched[i] = structure.Coords{X: 2 * c.X, Y: c.Y, Z: c.Z}
+	}
+	defer func() { lib = nil }()
+
+	// Fragments 1 and 2 are identical, so they tie for the best RMSD.
+	// The library may report either one.
+	lib = randomLib{[][]structure.Coords{stretched, shifted, shifted}}
+	score := structure.RMSD(region, lib.Atoms(2))
+	if got := lowestTie(region, 2, score); got != 1 {
+		t.Errorf("lowestTie = %d, want 1", got)
+	}
+	if got := lowestTie(region, 1, score); got != 1 {
+		t.Errorf("lowestTie of the lowest fragment = %d, want 1", got)
+	}
+
+	// Without a tie, the best fragment is kept.
+	lib = randomLib{[][]structure.Coords{stretched, stretched, shifted}}
+	if got := lowestTie(region, 2, score); got != 2 {
+		t.Errorf("lowestTie without a tie = %d, want 2", got)
+	}
+}
@@ -0,0 +1,135 @@
+// Command bow-agreement compares the fragments assigned to a chain by a
+// structure library with those assigned by its sequence library.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+func init() {
+	util.FlagUse("alphabet", "mask", "altloc")
+	util.FlagParse("struct-frag-lib seq-frag-lib pdb-file chain",
+		"Computes the best fragment of every window of a chain with both a\n"+
+			"structure library and a sequence library, where fragment 'i' of\n"+
+			"the sequence library corresponds to fragment 'i' of the\n"+
+			"structure library (e.g., a sequence library built from the\n"+
+			"structure library).\n\n"+
+			"Each window is printed as 'start end struct-frag seq-frag same',\n"+
+			"where 'start' and 'end' are inclusive alpha-carbon indices\n"+
+			"starting at 1. A summary follows with the fraction of windows\n"+
+			"assigned the same fragment and the cosine distance and\n"+
+			"Pearson correlation of the two BOWs.")
+	util.AssertNArg(4)
+}
+
+func main() {
+	slib := util.StructureLibrary(util.Arg(0))
+	qlib := util.SequenceLibrary(util.Arg(1))
+	if slib.Size() != qlib.Size() {
+		util.Fatalf("The structure library has %d fragments, but the sequence "+
+			"library has %d.", slib.Size(), qlib.Size())
+	}
+	if slib.FragmentSize() != qlib.FragmentSize() {
+		util.Fatalf("The structure library has fragments of size %d, but the "+
+			"sequence library has fragments of size %d.",
+			slib.FragmentSize(), qlib.FragmentSize())
+	}
+
+	entry := util.PDBRead(util.Arg(2))
+	chainId := util.Arg(3)
+	if len(chainId) != 1 {
+		util.Fatalf("Could not find protein chain with id '%s'.", chainId)
+	}
+	chain := entry.Chain(chainId[0])
+	if chain == nil || !chain.IsProtein() {
+		util.Fatalf("Could not find protein chain with id '%s'.", chainId)
+	}
+
+	atoms := chain.CaAtoms()
+	residues := util.BowSequence(caSequence(chain))
+	if residues.Len() != len(atoms) {
+		util.Fatalf("Chain '%s' has %d alpha-carbon atoms, but %d residues "+
+			"with alpha-carbon atoms.", chainId, len(atoms), residues.Len())
+	}
+	util.Assert(util.CheckFragmentSize(slib, len(atoms)),
+		"Chain '%s' is too short", chainId)
+
+	w := bufio.NewWriter(os.Stdout)
+	fsize := slib.FragmentSize()
+	windows, same := 0, 0
+	for i := 0; i <= len(atoms)-fsize; i++ {
+		sfrag := slib.BestStructureFragment(atoms[i : i+fsize])
+		qfrag := qlib.BestSequenceFragment(residues.Slice(i, i+fsize))
+		windows++
+		isSame := 0
+		if sfrag == qfrag {
+			same++
+			isSame = 1
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\n",
+			i+1, i+fsize, sfrag, qfrag, isSame)
+	}
+
+	sbow := bow.BowerFromChain(chain).StructureBow(slib)
+	qbow := bow.BowerFromSequence(residues).SequenceBow(qlib)
+	fmt.Fprintf(w, "\nSame fragment: %d/%d (%0.4f)\n",
+		same, windows, float64(same)/float64(windows))
+	fmt.Fprintf(w, "BOW cosine distance: %0.4f\n",
+		math.Abs(sbow.Bow.Cosine(qbow.Bow)))
+	fmt.Fprintf(w, "BOW Pearson correlation: %0.4f\n",
+		pearson(sbow.Bow, qbow.Bow))
+	util.Assert(w.Flush())
+}
+
+// caSequence returns the residues of the first model of the chain given that
+// have an alpha-carbon atom, in the same order as the chain's alpha-carbon
+// atoms.
+func caSequence(chain *pdb.Chain) seq.Sequence {
+	s := seq.Sequence{
+		Name: fmt.Sprintf("%s%c", chain.Entry.IdCode, chain.Ident),
+	}
+	if len(chain.Models) == 0 {
+		return s
+	}
+	for _, r := range chain.Models[0].Residues {
+		for _, atom := range r.Atoms {
+			if atom.Name == "CA" {
+				s.Residues = append(s.Residues, r.Name)
+				break
+			}
+		}
+	}
+	return s
+}
+
+// pearson returns the Pearson correlation coefficient of the frequencies of
+// two BOWs of the same size. If either BOW has no variance, 0 is returned.
+func pearson(b1, b2 bow.Bow) float64 {
+	n := float64(len(b1.Freqs))
+	var mean1, mean2 float64
+	for i := range b1.Freqs {
+		mean1 += float64(b1.Freqs[i])
+		mean2 += float64(b2.Freqs[i])
+	}
+	mean1, mean2 = mean1/n, mean2/n
+
+	var cov, var1, var2 float64
+	for i := range b1.Freqs {
+		d1, d2 := float64(b1.Freqs[i])-mean1, float64(b2.Freqs[i])-mean2
+		cov += d1 * d2
+		var1 += d1 * d1
+		var2 += d2 * d2
+	}
+	if var1 == 0 || var2 == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(var1*var2)
+}
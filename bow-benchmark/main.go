@@ -0,0 +1,213 @@
+// Command bow-benchmark measures how well cosine distances between the BOWs
+// of a database agree with a known classification of its entries.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var (
+	flagK        = 10
+	flagPerQuery = false
+)
+
+func init() {
+	flag.IntVar(&flagK, "k", flagK,
+		"The number of nearest neighbors used to compute precision@k.")
+	flag.BoolVar(&flagPerQuery, "per-query", flagPerQuery,
+		"When set, a line 'id class auc precision' is printed for every\n"+
+			"query before the summary.")
+
+	util.FlagUse("cpu")
+	util.FlagParse("bowdb-path class-file",
+		"Each entry of the BOW database that has a class in 'class-file'\n"+
+			"is used as a query, and every other such entry is ranked by its\n"+
+			"cosine distance to the query. An entry is relevant to a query\n"+
+			"if they have the same class. Each line of 'class-file' has the\n"+
+			"form 'id class'.\n\n"+
+			"For each query, the area under the ROC curve (AUC) and the\n"+
+			"fraction of the k nearest entries that are relevant\n"+
+			"(precision@k) are computed. The mean of each over all queries\n"+
+			"is printed. Queries whose class has no other members (or that\n"+
+			"include every entry) are skipped.")
+	util.AssertNArg(2)
+	if flagK < 1 {
+		util.FatalfCode(util.ExitUsage, "The '-k' flag must be at least 1.")
+	}
+}
+
+// entry is a BOW along with its class and L2 norm.
+type entry struct {
+	bow.Bowed
+	class string
+	norm  float64
+}
+
+// result is the benchmark metrics of a single query.
+type result struct {
+	ok        bool
+	auc, prec float64
+	id, class string
+}
+
+// neighbor is an entry ranked with respect to a query.
+type neighbor struct {
+	dist     float64
+	relevant bool
+}
+
+type neighborsByDist []neighbor
+
+func (ns neighborsByDist) Len() int           { return len(ns) }
+func (ns neighborsByDist) Less(i, j int) bool { return ns[i].dist < ns[j].dist }
+func (ns neighborsByDist) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
+
+func main() {
+	classes := readClasses(util.Arg(1))
+
+	db := util.OpenBowDB(util.Arg(0))
+	all, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	entries := make([]entry, 0, len(all))
+	for _, b := range all {
+		if class, ok := classes[b.Id]; ok {
+			entries = append(entries, entry{b, class, b.Bow.Magnitude()})
+		}
+	}
+	if len(entries) < 2 {
+		util.Fatalf("At least two entries must have a class, but %d do.",
+			len(entries))
+	}
+	util.Verbosef("%d of %d entries have a class.", len(entries), len(all))
+
+	results := make([]result, len(entries))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for qi := range jobs {
+				results[qi] = benchmark(entries, qi)
+			}
+		}()
+	}
+	for qi := range entries {
+		jobs <- qi
+	}
+	close(jobs)
+	wg.Wait()
+
+	var sumAuc, sumPrec float64
+	count := 0
+	for _, r := range results {
+		if !r.ok {
+			continue
+		}
+		if flagPerQuery {
+			fmt.Printf("%s %s %0.4f %0.4f\n", r.id, r.class, r.auc, r.prec)
+		}
+		sumAuc += r.auc
+		sumPrec += r.prec
+		count++
+	}
+	if count == 0 {
+		util.Fatalf("No query has both relevant and irrelevant entries.")
+	}
+	fmt.Printf("queries\t%d\n", count)
+	fmt.Printf("skipped\t%d\n", len(results)-count)
+	fmt.Printf("auc\t%0.4f\n", sumAuc/float64(count))
+	fmt.Printf("precision@%d\t%0.4f\n", flagK, sumPrec/float64(count))
+}
+
+// benchmark ranks every other entry with respect to the query entry `qi` and
+// computes its AUC and precision@k.
+func benchmark(entries []entry, qi int) result {
+	q := entries[qi]
+	r := result{id: q.Id, class: q.class}
+
+	neighbors := make([]neighbor, 0, len(entries)-1)
+	npos := 0
+	for i, e := range entries {
+		if i == qi {
+			continue
+		}
+		relevant := e.class == q.class
+		if relevant {
+			npos++
+		}
+		dist := math.Abs(util.CosineNorms(q.Bow, e.Bow, q.norm, e.norm))
+		neighbors = append(neighbors, neighbor{dist, relevant})
+	}
+	nneg := len(neighbors) - npos
+	if npos == 0 || nneg == 0 {
+		return r
+	}
+	sort.Stable(neighborsByDist(neighbors))
+
+	// The AUC is the fraction of (relevant, irrelevant) pairs where the
+	// relevant entry is closer to the query. Ties count as half.
+	pairs, negBefore := 0.0, 0
+	for start := 0; start < len(neighbors); {
+		end, pos, neg := start, 0, 0
+		for ; end < len(neighbors); end++ {
+			if neighbors[end].dist != neighbors[start].dist {
+				break
+			}
+			if neighbors[end].relevant {
+				pos++
+			} else {
+				neg++
+			}
+		}
+		pairs += float64(pos*(nneg-negBefore-neg)) + 0.5*float64(pos*neg)
+		negBefore += neg
+		start = end
+	}
+	r.auc = pairs / float64(npos*nneg)
+
+	k := flagK
+	if k > len(neighbors) {
+		k = len(neighbors)
+	}
+	hits := 0
+	for _, n := range neighbors[:k] {
+		if n.relevant {
+			hits++
+		}
+	}
+	r.prec = float64(hits) / float64(k)
+	r.ok = true
+	return r
+}
+
+// readClasses reads a file where each line has the form 'id class'.
+func readClasses(fpath string) map[string]string {
+	f := util.OpenFile(fpath)
+	defer f.Close()
+
+	classes := make(map[string]string)
+	for i, line := range util.ReadLines(f) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			util.FatalfCode(util.ExitParse,
+				"Expected 'id class' on line %d in '%s' but got "+
+					"'%s'.", i+1, fpath, line)
+		}
+		classes[fields[0]] = fields[1]
+	}
+	return classes
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// distances is a symmetric distance matrix stored in condensed form. That
+// is, only the entries above the diagonal are kept.
+type distances struct {
+	n     int
+	dists []float64
+}
+
+// newDistances computes the cosine distance between every pair of entries
+// with `threads` goroutines.
+func newDistances(entries []bow.Bowed, threads int) *distances {
+	n := len(entries)
+	d := &distances{n, make([]float64, n*(n-1)/2)}
+	if threads < 1 {
+		threads = 1
+	}
+
+	rows := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				for j := i + 1; j < n; j++ {
+					dist := math.Abs(entries[i].Bow.Cosine(entries[j].Bow))
+					d.set(i, j, dist)
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+	return d
+}
+
+func (d *distances) index(i, j int) int {
+	if i > j {
+		i, j = j, i
+	}
+	return i*d.n - i*(i+1)/2 + (j - i - 1)
+}
+
+func (d *distances) get(i, j int) float64 {
+	return d.dists[d.index(i, j)]
+}
+
+func (d *distances) set(i, j int, dist float64) {
+	d.dists[d.index(i, j)] = dist
+}
+
+// linkage computes the distance between a cluster k and the cluster formed
+// by merging clusters i and j, given the distances from k to i and from k to
+// j and the sizes of i and j. (These are the Lance-Williams updates.)
+type linkage func(dik, djk float64, ni, nj int) float64
+
+var linkages = map[string]linkage{
+	"single": func(dik, djk float64, ni, nj int) float64 {
+		return math.Min(dik, djk)
+	},
+	"complete": func(dik, djk float64, ni, nj int) float64 {
+		return math.Max(dik, djk)
+	},
+	"average": func(dik, djk float64, ni, nj int) float64 {
+		return (float64(ni)*dik + float64(nj)*djk) / float64(ni+nj)
+	},
+}
+
+// agglomerate repeatedly merges the two closest clusters (where every entry
+// starts in its own cluster) until the closest pair of clusters is further
+// apart than `threshold`. Each cluster returned is a list of entry indices,
+// and clusters are ordered by their smallest entry index.
+//
+// Note that `dists` is modified in place: it ends up holding the distances
+// between clusters.
+func agglomerate(dists *distances, link linkage, threshold float64) [][]int {
+	n := dists.n
+	members := make([][]int, n)
+	for i := range members {
+		members[i] = []int{i}
+	}
+	for {
+		mini, minj, min := -1, -1, math.Inf(1)
+		for i := 0; i < n; i++ {
+			if members[i] == nil {
+				continue
+			}
+			for j := i + 1; j < n; j++ {
+				if members[j] == nil {
+					continue
+				}
+				if d := dists.get(i, j); d < min {
+					mini, minj, min = i, j, d
+				}
+			}
+		}
+		if mini == -1 || min > threshold {
+			break
+		}
+
+		ni, nj := len(members[mini]), len(members[minj])
+		for k := 0; k < n; k++ {
+			if k == mini || k == minj || members[k] == nil {
+				continue
+			}
+			d := link(dists.get(mini, k), dists.get(minj, k), ni, nj)
+			dists.set(mini, k, d)
+		}
+		members[mini] = append(members[mini], members[minj]...)
+		members[minj] = nil
+	}
+
+	clusters := make([][]int, 0, n)
+	for i := range members {
+		if members[i] != nil {
+			clusters = append(clusters, members[i])
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,70 @@
+// Command bow-cluster clusters the entries of a BOW database by the cosine
+// distance between their BOW vectors using agglomerative clustering.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"runtime/pprof"
+
+	"github.com/ndaniels/tools/util"
+)
+
+var (
+	flagThreshold = 0.2
+	flagLinkage   = "average"
+)
+
+func init() {
+	flag.Float64Var(&flagThreshold, "threshold", flagThreshold,
+		"Clusters are merged only while the distance between them is less\n"+
+			"than or equal to this threshold.")
+	flag.StringVar(&flagLinkage, "linkage", flagLinkage,
+		"The distance between two clusters. Legal values are 'single'\n"+
+			"(minimum pairwise distance), 'complete' (maximum pairwise\n"+
+			"distance) and 'average' (mean pairwise distance).")
+
+	util.FlagUse("cpu", "cpuprof", "verbose")
+	util.FlagParse("bowdb-path out-clusters.csv",
+		"Clusters every entry in the BOW database given and writes each\n"+
+			"cluster as a line of entry ids in CSV format.")
+	util.AssertNArg(2)
+
+	if _, ok := linkages[flagLinkage]; !ok {
+		util.FatalfCode(util.ExitUsage, "Unknown linkage '%s'.", flagLinkage)
+	}
+}
+
+func main() {
+	if len(util.FlagCpuProf) > 0 {
+		f := util.CreateFile(util.FlagCpuProf)
+		pprof.StartCPUProfile(f)
+		defer f.Close()
+		defer pprof.StopCPUProfile()
+	}
+
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read BOW database entries")
+	util.Assert(db.Close())
+
+	util.Verbosef("Computing distances between %d entries...", len(entries))
+	dists := newDistances(entries, util.FlagCpu)
+
+	util.Verbosef("Clustering...")
+	groups := agglomerate(dists, linkages[flagLinkage], flagThreshold)
+
+	clusters := make([][]string, len(groups))
+	for i, group := range groups {
+		clusters[i] = make([]string, len(group))
+		for j, entryIndex := range group {
+			clusters[i][j] = entries[entryIndex].Id
+		}
+	}
+
+	out := util.CreateFile(util.Arg(1))
+	defer out.Close()
+
+	csvw := csv.NewWriter(out)
+	util.Assert(csvw.WriteAll(clusters))
+}
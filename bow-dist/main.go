@@ -1,19 +1,183 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
+	"strings"
+	"sync"
 
 	"github.com/ndaniels/tools/util"
 )
 
+var (
+	flagWeights = ""
+	flagPairs   = ""
+	flagRef     = ""
+	flagTop     = 0
+)
+
 func init() {
-	util.FlagParse("bow1 bow2", "")
-	util.AssertNArg(2)
+	flag.StringVar(&flagWeights, "weights", flagWeights,
+		"When set, both BOWs are weighted by the fragment weights in this\n"+
+			"file (as written by 'bow-weight -weights') before comparing.")
+	flag.StringVar(&flagPairs, "pairs", flagPairs,
+		"When set, each line of this file has the form 'bow1\\tbow2', and\n"+
+			"'bow1\\tbow2\\tdistance' is printed for each line. If a pair\n"+
+			"cannot be compared, then the distance is '-' and the error is\n"+
+			"printed in a fourth column. Each BOW file is read only once.\n"+
+			"No BOWs may be given as arguments when this is set.")
+	flag.StringVar(&flagRef, "ref", flagRef,
+		"When set, the distance from the BOW in this file to every BOW\n"+
+			"given as an argument is printed as 'file\tdistance', sorted\n"+
+			"by distance (smallest first). Directories are searched\n"+
+			"recursively for BOW files. Files that cannot be compared are\n"+
+			"skipped with a warning.")
+	flag.IntVar(&flagTop, "n", flagTop,
+		"When set with '-ref', only the K closest BOWs are printed.")
+
+	util.FlagUse("cpu")
+	util.FlagParse("bow1 bow2 | -ref ref-bow (bow | dir) ...",
+		"Outputs the cosine distance between two BOWs. It is an error if\n"+
+			"the BOWs were computed with different fragment libraries.")
+	if len(flagPairs) > 0 && len(flagRef) > 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The '-pairs' and '-ref' flags cannot both be set.")
+	}
+	if flagTop != 0 && (len(flagRef) == 0 || flagTop < 0) {
+		util.FatalfCode(util.ExitUsage,
+			"The '-n' flag must be positive and requires '-ref'.")
+	}
+	if len(flagPairs) > 0 {
+		util.AssertNArg(0)
+	} else if len(flagRef) > 0 {
+		util.AssertLeastNArg(1)
+	} else {
+		util.AssertNArg(2)
+	}
 }
 
 func main() {
-	b1 := util.BowRead(util.Arg(0))
-	b2 := util.BowRead(util.Arg(1))
-	fmt.Printf("%0.4f\n", math.Abs(b1.Bow.Cosine(b2.Bow)))
+	var weights util.BowWeights
+	if len(flagWeights) > 0 {
+		weights = util.ReadBowWeights(flagWeights)
+	}
+	if len(flagPairs) > 0 {
+		distPairs(flagPairs, weights)
+		return
+	}
+	if len(flagRef) > 0 {
+		distRef(flagRef, util.Args(), flagTop, weights)
+		return
+	}
+
+	b1 := util.BowReadAny(util.Arg(0))
+	b2 := util.BowReadAny(util.Arg(1))
+	dist, err := distance(b1, b2, weights)
+	util.Assert(err, "Cannot compare BOWs")
+	fmt.Printf("%0.4f\n", dist)
+}
+
+// distance returns the cosine distance between two BOWs, weighting each by
+// `weights` if it is not nil. Stored norms are used when the BOWs are not
+// weighted.
+func distance(b1, b2 util.BowFile, weights util.BowWeights) (float64, error) {
+	if err := b1.SameLibrary(b2); err != nil {
+		return 0, err
+	}
+	if weights != nil {
+		if len(weights) != len(b1.Bow.Freqs) {
+			return 0, fmt.Errorf("BOWs have %d fragments, but there are %d "+
+				"weights", len(b1.Bow.Freqs), len(weights))
+		}
+		b1.Bow, b2.Bow = weights.Apply(b1.Bow), weights.Apply(b2.Bow)
+		b1.Norm, b2.Norm = 0, 0
+	}
+	return math.Abs(b1.Cosine(b2)), nil
+}
+
+// cachedBow is a BOW file that is read at most once. Its norm is also
+// computed at most once.
+type cachedBow struct {
+	once sync.Once
+	bow  util.BowFile
+	err  error
+}
+
+// bowCache reads BOW files on demand and keeps them in memory.
+type bowCache struct {
+	sync.Mutex
+	bows map[string]*cachedBow
+}
+
+func (c *bowCache) get(path string) (util.BowFile, error) {
+	c.Lock()
+	cb, ok := c.bows[path]
+	if !ok {
+		cb = new(cachedBow)
+		c.bows[path] = cb
+	}
+	c.Unlock()
+
+	cb.once.Do(func() {
+		cb.bow, cb.err = util.BowOpen(path)
+		cb.bow.Norm = cb.bow.Magnitude()
+	})
+	return cb.bow, cb.err
+}
+
+// distPairs prints the distance between each pair of BOWs listed in the file
+// at `fpath`, in the same order as the pairs are listed.
+func distPairs(fpath string, weights util.BowWeights) {
+	f := util.OpenFile(fpath)
+	lines := util.ReadLines(f)
+	util.Assert(f.Close())
+
+	results := make([]string, len(lines))
+	cache := &bowCache{bows: make(map[string]*cachedBow)}
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = distPair(cache, weights, lines[i])
+			}
+		}()
+	}
+	for i := range lines {
+		if len(strings.TrimSpace(lines[i])) > 0 {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, line := range results {
+		if len(line) > 0 {
+			fmt.Println(line)
+		}
+	}
+}
+
+// distPair returns the output line for a single 'bow1\tbow2' line.
+func distPair(cache *bowCache, weights util.BowWeights, line string) string {
+	pair := strings.Split(line, "\t")
+	if len(pair) != 2 {
+		return fmt.Sprintf("%s\t-\texpected 'bow1\\tbow2'", line)
+	}
+	b1, err := cache.get(pair[0])
+	if err != nil {
+		return fmt.Sprintf("%s\t-\t%s", line, err)
+	}
+	b2, err := cache.get(pair[1])
+	if err != nil {
+		return fmt.Sprintf("%s\t-\t%s", line, err)
+	}
+	dist, err := distance(b1, b2, weights)
+	if err != nil {
+		return fmt.Sprintf("%s\t-\t%s", line, err)
+	}
+	return fmt.Sprintf("%s\t%0.4f", line, dist)
 }
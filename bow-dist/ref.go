@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ndaniels/tools/util"
+)
+
+// ranked is the distance from the reference BOW to the BOW in a file.
+type ranked struct {
+	file string
+	dist float64
+	err  error
+}
+
+type byDist []ranked
+
+func (rs byDist) Len() int      { return len(rs) }
+func (rs byDist) Swap(i, j int) { rs[i], rs[j] = rs[j], rs[i] }
+func (rs byDist) Less(i, j int) bool {
+	if rs[i].dist == rs[j].dist {
+		return rs[i].file < rs[j].file
+	}
+	return rs[i].dist < rs[j].dist
+}
+
+// distRef prints 'file\tdistance' for every BOW file in `args` (with
+// directories expanded), sorted by distance to the BOW in `refPath`. Only the
+// first `n` are printed if `n` is greater than zero. Files that cannot be
+// compared are skipped with a warning.
+func distRef(refPath string, args []string, n int, weights util.BowWeights) {
+	ref := util.BowReadAny(refPath)
+	if weights == nil {
+		ref.Norm = ref.Magnitude() // computed once for every comparison
+	}
+	files := util.AllFilesFromArgs(args)
+
+	results := make([]ranked, len(files))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = rank(ref, files[i], weights)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	ok := make([]ranked, 0, len(results))
+	for _, r := range results {
+		if !util.Warning(r.err, "Skipping '%s'", r.file) {
+			ok = append(ok, r)
+		}
+	}
+	sort.Sort(byDist(ok))
+	if n > 0 && n < len(ok) {
+		ok = ok[:n]
+	}
+	for _, r := range ok {
+		fmt.Printf("%s\t%0.4f\n", r.file, r.dist)
+	}
+}
+
+// rank returns the distance from `ref` to the BOW in the file given.
+func rank(ref util.BowFile, file string, weights util.BowWeights) ranked {
+	b, err := util.BowOpen(file)
+	if err != nil {
+		return ranked{file: file, err: err}
+	}
+	dist, err := distance(ref, b, weights)
+	return ranked{file, dist, err}
+}
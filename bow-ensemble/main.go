@@ -0,0 +1,62 @@
+// Command bow-ensemble computes a BOW for a chain from each of several
+// fragment libraries and writes their concatenation.
+package main
+
+import (
+	"strings"
+
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+func init() {
+	util.FlagUse("alphabet", "mask", "source", "altloc")
+	util.FlagParse("frag-lib,frag-lib,... chain pdb-file out-bow",
+		"Computes a BOW for the specified chain in the given PDB file with\n"+
+			"each of the comma separated fragment libraries, and writes the\n"+
+			"concatenation of the BOWs to 'out-bow'. The libraries must all\n"+
+			"be structure libraries or all be sequence libraries.\n\n"+
+			"The output records the name and size of each library, and can\n"+
+			"be compared with 'bow-dist' to other BOWs computed from the\n"+
+			"same libraries in the same order.")
+	util.AssertNArg(4)
+}
+
+func main() {
+	libPaths := strings.Split(util.Arg(0), ",")
+	chainId := util.Arg(1)
+	entry := util.PDBRead(util.Arg(2))
+
+	if len(chainId) != 1 {
+		util.Fatalf("Could not find chain with identifier '%s'.", chainId)
+	}
+	chain := entry.Chain(chainId[0])
+	if chain == nil || !chain.IsProtein() {
+		util.Fatalf("Could not find chain with identifier '%s'.", chainId)
+	}
+
+	libs := make([]fragbag.Library, len(libPaths))
+	bows := make([]bow.Bowed, len(libPaths))
+	for i, libPath := range libPaths {
+		libs[i] = util.Library(libPath)
+		if fragbag.IsStructure(libs[i]) != fragbag.IsStructure(libs[0]) {
+			util.Fatalf("Cannot mix structure and sequence libraries: '%s' "+
+				"and '%s'.", libPaths[0], libPath)
+		}
+
+		if fragbag.IsStructure(libs[i]) {
+			lib := libs[i].(fragbag.StructureLibrary)
+			bows[i] = bow.BowerFromChain(chain).StructureBow(lib)
+		} else {
+			lib := libs[i].(fragbag.SequenceLibrary)
+			s := util.ChainSequence(chain, util.SequenceSource(lib))
+			s = util.BowSequence(s)
+			bows[i] = bow.BowerFromSequence(s).SequenceBow(lib)
+		}
+	}
+
+	out := util.CreateFile(util.Arg(3))
+	util.BowFileWrite(out, util.NewEnsembleBowFile(libs, bows))
+	util.Assert(out.Close())
+}
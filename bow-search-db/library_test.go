@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// atomsLib is a structure library that saves its fragments as JSON.
+type atomsLib struct {
+	name  string
+	frags [][]structure.Coords
+}
+
+func (lib atomsLib) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(lib.frags)
+}
+
+func (lib atomsLib) Size() int                   { return len(lib.frags) }
+func (lib atomsLib) FragmentSize() int           { return len(lib.frags[0]) }
+func (lib atomsLib) String() string              { return lib.name }
+func (lib atomsLib) Name() string                { return lib.name }
+func (lib atomsLib) Tag() string                 { return "structure" }
+func (lib atomsLib) Fragment(i int) interface{}  { return lib.frags[i] }
+func (lib atomsLib) SubLibrary() fragbag.Library { return nil }
+
+func (lib atomsLib) BestStructureFragment([]structure.Coords) int {
+	return 0
+}
+
+func (lib atomsLib) Atoms(i int) []structure.Coords {
+	return lib.frags[i]
+}
+
+func TestSameLibrary(t *testing.T) {
+	frags := func(z float64) [][]structure.Coords {
+		return [][]structure.Coords{
+			{{X: 0}, {X: 3.8}, {X: 7.6}},
+			{{X: 0}, {Y: 3.8}, {Y: 7.6, Z: z}},
+		}
+	}
+	lib := atomsLib{"lib", frags(1)}
+	err := sameLibrary("q", "t", lib, atomsLib{"lib", frags(1)})
+	if err != nil {
+		t.Errorf("identical libraries: %s", err)
+	}
+
+	err = sameLibrary("q", "t", lib, atomsLib{"other", frags(1)})
+	if err == nil || !strings.Contains(err.Error(), "is not the same") {
+		t.Errorf("libraries with different names: got error %v", err)
+	}
+
+	// One coordinate of one fragment differs slightly.
+	err = sameLibrary("q", "t", lib, atomsLib{"lib", frags(1.001)})
+	if err == nil || !strings.Contains(err.Error(), "fragments differ") {
+		t.Errorf("subtly different libraries: got error %v", err)
+	}
+}
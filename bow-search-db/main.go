@@ -0,0 +1,179 @@
+// Command bow-search-db searches every entry of one BOW database against
+// another BOW database and reports the best hit for each query.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/TuftsBCB/io/fasta"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagFasta = ""
+
+func init() {
+	flag.StringVar(&flagFasta, "fasta", flagFasta,
+		"When set, a FASTA file is written to the directory given for each\n"+
+			"query, named by the query id, containing the sequence of the\n"+
+			"query followed by the sequence of its best hit. Sequences are\n"+
+			"found from the ids of entries in the same way as bower files\n"+
+			"(e.g., PDB_PATH must be set for PDB ids). Queries whose query or\n"+
+			"hit sequence cannot be found are reported and skipped.")
+}
+
+type hit struct {
+	id   string
+	dist float64
+}
+
+func main() {
+	util.FlagUse("cpu", "verbose", "progress-interval", "source")
+	util.FlagParse("query-bowdb target-bowdb out-tsv",
+		"For every entry in 'query-bowdb', find the entry in 'target-bowdb'\n"+
+			"with the smallest cosine distance. Each line of output has the\n"+
+			"form 'query-id\\tbest-hit-id\\tdistance', in the same order as\n"+
+			"the entries of 'query-bowdb'.\n\n"+
+			"Both databases must have been built with the same fragment "+
+			"library.")
+	util.AssertNArg(3)
+
+	dbQuery := util.OpenBowDB(util.Arg(0))
+	dbTarget := util.OpenBowDB(util.Arg(1))
+	libq, libt := dbQuery.Lib, dbTarget.Lib
+	util.Assert(sameLibrary(util.Arg(0), util.Arg(1), libq, libt))
+
+	queries, err := dbQuery.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	targets, err := dbTarget.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(1))
+	util.Assert(dbQuery.Close())
+	util.Assert(dbTarget.Close())
+	if len(targets) == 0 {
+		util.Fatalf("'%s' has no entries.", util.Arg(1))
+	}
+
+	qnorms, tnorms := norms(queries), norms(targets)
+	progress := util.NewProgress(len(queries))
+	hits := make([]hit, len(queries))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for qi := range jobs {
+				hits[qi] = bestHit(queries[qi], qnorms[qi], targets, tnorms)
+				progress.JobDone(nil)
+			}
+		}()
+	}
+	for qi := range queries {
+		jobs <- qi
+	}
+	close(jobs)
+	wg.Wait()
+	progress.Close()
+
+	out := util.CreateFile(util.Arg(2))
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	for qi, h := range hits {
+		fmt.Fprintf(w, "%s\t%s\t%0.4f\n", queries[qi].Id, h.id, h.dist)
+	}
+	util.Assert(w.Flush(), "Could not write to '%s'", util.Arg(2))
+
+	if len(flagFasta) > 0 {
+		writeFastas(flagFasta, libq, queries, hits)
+	}
+}
+
+// writeFastas writes the sequences of each query and its best hit to a FASTA
+// file in `dir`. Sequences are read from the source that util.SequenceSource
+// gives for `lib`.
+func writeFastas(
+	dir string,
+	lib fragbag.Library,
+	queries []bow.Bowed,
+	hits []hit,
+) {
+	util.Assert(os.MkdirAll(dir, 0777))
+	written := 0
+	for qi, h := range hits {
+		qseq, err := util.SequenceFromId(queries[qi].Id, lib)
+		if util.Warning(err, "Skipping FASTA for query '%s'", queries[qi].Id) {
+			continue
+		}
+		hseq, err := util.SequenceFromId(h.id, lib)
+		if util.Warning(err, "Skipping FASTA for query '%s'", queries[qi].Id) {
+			continue
+		}
+
+		fpath := filepath.Join(dir, util.SafeFileName(queries[qi].Id)+".fasta")
+		out := util.CreateFile(fpath)
+		w := fasta.NewWriter(out)
+		for _, s := range []seq.Sequence{qseq, hseq} {
+			util.Assert(w.Write(s), "Could not write to '%s'", fpath)
+		}
+		util.Assert(w.Flush(), "Could not write to '%s'", fpath)
+		util.Assert(out.Close(), "Could not write to '%s'", fpath)
+		written++
+	}
+	util.Verbosef("Wrote %d of %d FASTA files.", written, len(hits))
+}
+
+// sameLibrary returns an error if the fragment libraries of the databases
+// at `pathq` and `patht` differ. Libraries with the same name and size must
+// also have the same fingerprint (see util.LibraryFingerprint).
+func sameLibrary(pathq, patht string, libq, libt fragbag.Library) error {
+	if libq.Name() != libt.Name() || libq.Size() != libt.Size() ||
+		libq.FragmentSize() != libt.FragmentSize() {
+		return fmt.Errorf("The fragment library of '%s' (%s) is not the "+
+			"same as the fragment library of '%s' (%s).",
+			pathq, libq, patht, libt)
+	}
+	fpq, fpt := util.LibraryFingerprint(libq), util.LibraryFingerprint(libt)
+	if fpq != fpt {
+		return fmt.Errorf("The fragment library of '%s' has the same name "+
+			"as the fragment library of '%s', but their fragments differ "+
+			"(fingerprints %s and %s).", pathq, patht, fpq, fpt)
+	}
+	return nil
+}
+
+// norms returns the L2 norm of each BOW given, so that they are not computed
+// again for every comparison.
+func norms(bs []bow.Bowed) []float64 {
+	ns := make([]float64, len(bs))
+	for i := range bs {
+		ns[i] = bs[i].Bow.Magnitude()
+	}
+	return ns
+}
+
+// bestHit returns the target with the smallest cosine distance to the query,
+// given the norm of the query and of each target.
+func bestHit(
+	query bow.Bowed,
+	qnorm float64,
+	targets []bow.Bowed,
+	tnorms []float64,
+) hit {
+	best := hit{dist: math.Inf(1)}
+	for i, target := range targets {
+		d := math.Abs(util.CosineNorms(query.Bow, target.Bow, qnorm, tnorms[i]))
+		if d < best.dist {
+			best = hit{target.Id, d}
+		}
+	}
+	return best
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// randomBows returns `n` BOWs of the size given with random frequencies.
+func randomBows(rng *rand.Rand, n, size int) []bow.Bowed {
+	bs := make([]bow.Bowed, n)
+	for i := range bs {
+		b := bow.NewBow(size)
+		for j := range b.Freqs {
+			if rng.Intn(2) == 0 {
+				b.Freqs[j] = float32(rng.Intn(20))
+			}
+		}
+		bs[i] = bow.Bowed{Id: fmt.Sprintf("bow%d", i), Bow: b}
+	}
+	return bs
+}
+
+// bestHitRecompute is bestHit as it was before norms were cached.
+func bestHitRecompute(query bow.Bowed, targets []bow.Bowed) hit {
+	best := hit{dist: math.Inf(1)}
+	for _, target := range targets {
+		if d := math.Abs(query.Bow.Cosine(target.Bow)); d < best.dist {
+			best = hit{target.Id, d}
+		}
+	}
+	return best
+}
+
+func TestBestHitNorms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	queries := randomBows(rng, 50, 400)
+	targets := randomBows(rng, 500, 400)
+	qnorms, tnorms := norms(queries), norms(targets)
+	for qi, q := range queries {
+		got := bestHit(q, qnorms[qi], targets, tnorms)
+		if want := bestHitRecompute(q, targets); got != want {
+			t.Errorf("query %d: cached norms give %v, recomputed give %v",
+				qi, got, want)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	queries := randomBows(rng, 20, 400)
+	targets := randomBows(rng, 5000, 400)
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			qnorms, tnorms := norms(queries), norms(targets)
+			for qi, q := range queries {
+				bestHit(q, qnorms[qi], targets, tnorms)
+			}
+		}
+	})
+	b.Run("recomputed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, q := range queries {
+				bestHitRecompute(q, targets)
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/ndaniels/esfragbag/bowdb"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagWeights = ""
+
+func init() {
+	flag.StringVar(&flagWeights, "weights", flagWeights,
+		"When set, the computed weights are also written to this file, one\n"+
+			"weight per line. The file may be given to 'bow-dist -weights'.")
+
+	util.FlagParse("in-bowdb out-bowdb",
+		"Writes a copy of 'in-bowdb' to 'out-bowdb' where each BOW is\n"+
+			"weighted by the inverse document frequency of its fragments.\n"+
+			"The weight of fragment i is\n\n"+
+			"\tln((1 + N) / (1 + df(i))) + 1\n\n"+
+			"where N is the number of entries in the database and df(i) is\n"+
+			"the number of entries in which fragment i occurs.")
+	util.AssertNArg(2)
+}
+
+func main() {
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	weights := util.ComputeIDF(entries, db.Lib.Size())
+	if len(flagWeights) > 0 {
+		f := util.CreateFile(flagWeights)
+		util.Assert(util.WriteBowWeights(f, weights),
+			"Could not write weights to '%s'", flagWeights)
+		util.Assert(f.Close())
+	}
+
+	out, err := bowdb.Create(db.Lib, util.Arg(1))
+	util.Assert(err, "Could not create BOW database '%s'", util.Arg(1))
+	for _, entry := range entries {
+		entry.Bow = weights.Apply(entry.Bow)
+		out.Add(entry)
+	}
+	util.Assert(out.Close(), "Could not write BOW database '%s'", util.Arg(1))
+	util.Verbosef("Weighted %d entries.", len(entries))
+}
@@ -0,0 +1,170 @@
+// Command bow-windows computes a BOW for each window of residues sliding
+// across a chain.
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/esfragbag/bowdb"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagStep = 1
+
+func init() {
+	flag.IntVar(&flagStep, "step", flagStep,
+		"The number of residues between the starts of consecutive windows.")
+
+	util.FlagUse("altloc", "het-breaks")
+	util.FlagParse("frag-lib pdb-file chain size out-bowdb",
+		"Computes a BOW for every window of 'size' residues of a chain\n"+
+			"and writes them to a new BOW database 'out-bowdb'. If\n"+
+			"'out-bowdb' is '--', then a human readable version of each BOW\n"+
+			"is printed to stdout instead.\n\n"+
+			"Windows are ranges of the chain's sequence (e.g., SEQRES), so\n"+
+			"that disordered residues do not shift later windows. A window's\n"+
+			"BOW counts the best fragment of every fragment-sized region in\n"+
+			"the window where every residue has an alpha-carbon atom. Each\n"+
+			"BOW is labeled 'id/start-end' where 'id' is the PDB id code and\n"+
+			"chain, and [start, end) are sequence indices starting at 0.\n"+
+			"Windows without any such region are skipped. If the last window\n"+
+			"does not end at the end of the sequence, then one more window\n"+
+			"of the last 'size' residues is added, so that every residue is\n"+
+			"in some window. With '-het-breaks', regions that span a\n"+
+			"non-standard residue are not counted.")
+	util.AssertNArg(5)
+	if flagStep < 1 {
+		util.FatalfCode(util.ExitUsage, "The step must be at least 1.")
+	}
+}
+
+func main() {
+	lib := util.StructureLibrary(util.Arg(0))
+	entry := util.PDBRead(util.Arg(1))
+	chainId, size := util.Arg(2), util.ParseInt(util.Arg(3))
+	out := util.Arg(4)
+
+	if len(chainId) != 1 {
+		util.Fatalf("Could not find protein chain with id '%s'.", chainId)
+	}
+	chain := entry.Chain(chainId[0])
+	if chain == nil || !chain.IsProtein() {
+		util.Fatalf("Could not find protein chain with id '%s'.", chainId)
+	}
+	if size < lib.FragmentSize() {
+		util.FatalfCode(util.ExitUsage, "The window size (%d) must be at "+
+			"least the fragment size of '%s' (%d).",
+			size, lib.Name(), lib.FragmentSize())
+	}
+	util.Assert(util.CheckFragmentSize(lib, len(chain.Sequence)),
+		"Chain '%s' is too short", chainId)
+
+	bows := windowBows(lib, chain, size, flagStep)
+	if len(bows) == 0 {
+		util.Warnf("Chain '%s' has no windows of %d residues with fragments.",
+			chainId, size)
+	}
+	if out == "--" {
+		for _, b := range bows {
+			fmt.Printf("%s\t%s\n", b.Id, b.Bow)
+		}
+		return
+	}
+	db, err := bowdb.Create(lib, out)
+	util.Assert(err, "Could not create BOW database '%s'", out)
+	for _, b := range bows {
+		db.Add(b)
+	}
+	util.Assert(db.Close(), "Could not write BOW database '%s'", out)
+	util.Verbosef("Wrote %d window BOWs.", len(bows))
+}
+
+// windowBows returns a BOW for each window of `size` residues of the chain's
+// sequence, starting every `step` residues, plus a last window ending at the
+// end of the sequence. Windows without any fragment are omitted.
+func windowBows(
+	lib fragbag.StructureLibrary,
+	chain *pdb.Chain,
+	size, step int,
+) []bow.Bowed {
+	fsize := lib.FragmentSize()
+	n := len(chain.Sequence)
+
+	// The best fragment of each region is computed only once, since
+	// overlapping windows share regions. -1 means the region has a residue
+	// without an alpha-carbon atom, or spans a non-standard residue.
+	inSegs := regionsInSegments(chain, fsize)
+	best := make([]int, n-fsize+1)
+	for i := range best {
+		best[i] = -1
+		if !inSegs[i] {
+			continue
+		}
+		if atoms := chain.SequenceCaAtomSlice(i, i+fsize); atoms != nil {
+			best[i] = lib.BestStructureFragment(atoms)
+		}
+	}
+
+	id := fmt.Sprintf("%s%c", chain.Entry.IdCode, chain.Ident)
+	starts := make([]int, 0, n/step+2)
+	for start := 0; start+size <= n; start += step {
+		starts = append(starts, start)
+	}
+	if len(starts) > 0 && starts[len(starts)-1] != n-size {
+		starts = append(starts, n-size)
+	}
+
+	bows := make([]bow.Bowed, 0, len(starts))
+	for _, start := range starts {
+		b := bow.NewBow(lib.Size())
+		count := 0
+		for i := start; i+fsize <= start+size; i++ {
+			if best[i] >= 0 {
+				b.Freqs[best[i]]++
+				count++
+			}
+		}
+		if count == 0 {
+			util.Verbosef("Skipping window [%d, %d): it has no fragments.",
+				start, start+size)
+			continue
+		}
+		bows = append(bows, bow.Bowed{
+			Id:  fmt.Sprintf("%s/%d-%d", id, start, start+size),
+			Bow: b,
+		})
+	}
+	return bows
+}
+
+// regionsInSegments returns, for each fragment-sized region of the chain's
+// sequence, whether its alpha-carbon atoms are within one segment of
+// util.CaSegments. Sequence residues are matched to the atoms of the first
+// model by their coordinates. Regions with missing atoms are never within a
+// segment.
+func regionsInSegments(chain *pdb.Chain, fsize int) []bool {
+	model := chain.Models[0]
+	index := make(map[structure.Coords]int)
+	for i, atom := range model.CaAtoms() {
+		if _, ok := index[atom]; !ok {
+			index[atom] = i
+		}
+	}
+
+	segs := util.CaSegments(model)
+	seqAtoms := chain.SequenceCaAtoms()
+	in := make([]bool, len(chain.Sequence)-fsize+1)
+	for i := range in {
+		first, last := seqAtoms[i], seqAtoms[i+fsize-1]
+		if first == nil || last == nil {
+			continue
+		}
+		in[i] = util.InCaSegments(segs, index[*first], index[*last]+1)
+	}
+	return in
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/tools/util"
+)
+
+// writeAssignments writes the best fragment of every window of `atoms` as
+// tab-separated values, with one window per line. Windows are described by
+// inclusive alpha-carbon atom indices starting at 1. These are the same
+// fragments counted in the BOW of the chain, so windows that are not within
+// one of `segs` are skipped.
+func writeAssignments(
+	w io.Writer,
+	lib fragbag.StructureLibrary,
+	atoms []structure.Coords,
+	segs []util.CaSegment,
+) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "start\tend\tfragment\trmsd\n")
+
+	fsize := lib.FragmentSize()
+	for i := 0; i <= len(atoms)-fsize; i++ {
+		if !util.InCaSegments(segs, i, i+fsize) {
+			continue
+		}
+		region := atoms[i : i+fsize]
+		best := lib.BestStructureFragment(region)
+		rmsd := structure.RMSD(region, lib.Atoms(best))
+		fmt.Fprintf(bw, "%d\t%d\t%d\t%f\n", i+1, i+fsize, best, rmsd)
+	}
+	return bw.Flush()
+}
@@ -1,39 +1,131 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/ndaniels/esfragbag"
 	"github.com/ndaniels/esfragbag/bow"
 	"github.com/ndaniels/tools/util"
 )
 
+var (
+	flagText        = false
+	flagAssignments = false
+	flagAggregate   = util.AggregateChain
+)
+
 func init() {
-	util.FlagUse("cpu")
-	util.FlagParse("frag-lib-dir chain pdb-file out-bow",
-		"Computes and outputs a BOW file for the specified chain in the\n"+
-			"given PDB file. If 'out-bow' is '--', then a human readable\n"+
-			"version of the BOW will be printed to stdout instead.")
-	util.AssertNArg(4)
+	flag.BoolVar(&flagText, "text", flagText,
+		"When set, the BOW is written in a plain text format that can be\n"+
+			"read by 'bow-dist'. If 'out-bow' is '--', the text format is\n"+
+			"printed to stdout.")
+	flag.BoolVar(&flagAssignments, "assignments", flagAssignments,
+		"When set, the best fragment for every window of the chain is\n"+
+			"written as tab-separated values to 'out-bow.assignments.tsv'.\n"+
+			"Windows are given by alpha-carbon indices starting at 1.")
+	flag.StringVar(&flagAggregate, "aggregate", flagAggregate,
+		"When set to 'entry', the BOWs of every protein chain in the PDB\n"+
+			"file are summed into a single BOW labeled by the entry's id,\n"+
+			"and the 'chain' argument must be omitted. The default, 'chain',\n"+
+			"computes the BOW of a single chain.")
 }
 
 func main() {
+	util.FlagUse("cpu", "altloc", "het-breaks", "sparse", "norms")
+	util.FlagParse("frag-lib-dir (chain | -aggregate entry) pdb-file out-bow",
+		"Computes and outputs a BOW file for the specified chain in the\n"+
+			"given PDB file. If 'pdb-file' is '-', then the PDB file is read\n"+
+			"from stdin. If 'out-bow' is '--', then a human readable\n"+
+			"version of the BOW will be printed to stdout instead.")
+	switch flagAggregate {
+	case util.AggregateChain:
+		util.AssertNArg(4)
+	case util.AggregateEntry:
+		util.AssertNArg(3)
+		if flagAssignments {
+			util.FatalfCode(util.ExitUsage,
+				"The '-assignments' flag cannot be used with "+
+					"'-aggregate entry'.")
+		}
+	default:
+		util.FatalfCode(util.ExitUsage,
+			"Unknown aggregation '%s'. Legal values are 'chain' "+
+				"and 'entry'.", flagAggregate)
+	}
+	if flagAssignments && util.Arg(util.NArg()-1) == "--" {
+		util.FatalfCode(util.ExitUsage,
+			"The '-assignments' flag cannot be used when the BOW "+
+				"is printed to stdout.")
+	}
+
 	libPath := util.Arg(0)
-	chain := util.Arg(1)
-	pdbEntryPath := util.Arg(2)
-	bowOut := util.Arg(3)
+	pdbEntryPath := util.Arg(util.NArg() - 2)
+	bowOut := util.Arg(util.NArg() - 1)
 
 	lib := util.StructureLibrary(libPath)
 	entry := util.PDBRead(pdbEntryPath)
 
-	thechain := entry.Chain(chain[0])
-	if thechain == nil || !thechain.IsProtein() {
-		util.Fatalf("Could not find chain with identifier '%c'.", chain[0])
+	var thechain *pdb.Chain
+	var b bow.Bowed
+	if flagAggregate == util.AggregateEntry {
+		chainBows := make([]bow.Bowed, 0, len(entry.Chains))
+		for _, chain := range entry.Chains {
+			if !chain.IsProtein() {
+				continue
+			}
+			b, err := chainBow(lib, chain)
+			if util.Warning(err, "Skipping chain '%c'", chain.Ident) {
+				continue
+			}
+			chainBows = append(chainBows, b)
+		}
+		var err error
+		b, err = util.AggregateBows(entry.IdCode, chainBows)
+		util.Assert(err, "Could not aggregate BOWs of '%s'", pdbEntryPath)
+	} else {
+		chain := util.Arg(1)
+		thechain = entry.Chain(chain[0])
+		if thechain == nil || !thechain.IsProtein() {
+			util.Fatalf("Could not find chain with identifier '%c'.", chain[0])
+		}
+		var err error
+		b, err = chainBow(lib, thechain)
+		util.Assert(err, "Chain '%c' is too short", thechain.Ident)
 	}
-
-	bow := bow.BowerFromChain(thechain).StructureBow(lib)
-	if bowOut == "--" {
-		fmt.Println(bow)
+	if flagText {
+		if bowOut == "--" {
+			util.BowWriteText(os.Stdout, lib, b)
+		} else {
+			out := util.CreateFile(bowOut)
+			util.BowWriteText(out, lib, b)
+			util.Assert(out.Close())
+		}
+	} else if bowOut == "--" {
+		fmt.Println(b)
 	} else {
-		util.BowWrite(util.CreateFile(bowOut), bow)
+		util.BowWrite(util.CreateFile(bowOut), lib, b)
+	}
+	if flagAssignments {
+		fpath := bowOut + ".assignments.tsv"
+		out := util.CreateFile(fpath)
+		segs := util.CaSegments(thechain.Models[0])
+		util.Assert(writeAssignments(out, lib, thechain.CaAtoms(), segs),
+			"Could not write '%s'", fpath)
+		util.Assert(out.Close(), "Could not write '%s'", fpath)
+	}
+}
+
+// chainBow computes the BOW of a chain. An error is returned if the chain
+// is too short to contain a fragment of the library.
+func chainBow(
+	lib fragbag.StructureLibrary,
+	chain *pdb.Chain,
+) (bow.Bowed, error) {
+	if err := util.CheckFragmentSize(lib, len(chain.CaAtoms())); err != nil {
+		return bow.Bowed{}, err
 	}
+	return util.StructureBowerFromChain(chain).StructureBow(lib), nil
 }
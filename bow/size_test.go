@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// fourLib is a structure library of one fragment of four residues.
+type fourLib struct{}
+
+func (fourLib) Save(w io.Writer) error      { return nil }
+func (fourLib) Size() int                   { return 1 }
+func (fourLib) FragmentSize() int           { return 4 }
+func (fourLib) String() string              { return "four" }
+func (fourLib) Name() string                { return "four" }
+func (fourLib) Tag() string                 { return "structure" }
+func (fourLib) Fragment(i int) interface{}  { return nil }
+func (fourLib) SubLibrary() fragbag.Library { return nil }
+
+func (fourLib) BestStructureFragment([]structure.Coords) int { return 0 }
+func (fourLib) Atoms(i int) []structure.Coords               { return nil }
+
+// caChain returns a chain with `n` residues that each have an alpha-carbon.
+func caChain(n int) *pdb.Chain {
+	entry := &pdb.Entry{IdCode: "1abc"}
+	chain := &pdb.Chain{Entry: entry, Ident: 'A'}
+	model := &pdb.Model{Entry: entry, Chain: chain, Num: 1}
+	for i := 0; i < n; i++ {
+		model.Residues = append(model.Residues, &pdb.Residue{
+			Name:        'A',
+			SequenceNum: i + 1,
+			Atoms:       []pdb.Atom{{Name: "CA"}},
+		})
+	}
+	chain.Models = []*pdb.Model{model}
+	entry.Chains = []*pdb.Chain{chain}
+	return chain
+}
+
+func TestChainBowTooShort(t *testing.T) {
+	_, err := chainBow(fourLib{}, caChain(3))
+	want := "input has 3 residues, library 'four' requires at least 4"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+
+	b, err := chainBow(fourLib{}, caChain(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Bow.Freqs[0] != 2 {
+		t.Errorf("got %v fragments, want 2", b.Bow.Freqs[0])
+	}
+}
@@ -0,0 +1,124 @@
+// Command bowdb-diff checks that two BOW databases contain the same entries
+// with the same BOWs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var (
+	flagTolerance = 0.0
+	flagMax       = 10
+)
+
+func init() {
+	flag.Float64Var(&flagTolerance, "tolerance", flagTolerance,
+		"The largest absolute difference allowed between corresponding\n"+
+			"frequencies of two BOWs that are considered identical.")
+	flag.IntVar(&flagMax, "max", flagMax,
+		"The number of differences to report. When 0, every difference is\n"+
+			"reported.")
+
+	util.FlagParse("bowdb-a bowdb-b",
+		"Compares two BOW databases. Each entry id must appear in both\n"+
+			"databases, and the BOWs of corresponding entries must be\n"+
+			"identical (or within the tolerance given). Entries are compared\n"+
+			"in order of id, and differences are printed to stdout. The exit\n"+
+			"status is non-zero if any differences were found.")
+	util.AssertNArg(2)
+}
+
+func main() {
+	pathA, pathB := util.Arg(0), util.Arg(1)
+	a, fingerA := readSorted(pathA)
+	b, fingerB := readSorted(pathB)
+
+	diffs := 0
+	report := func(format string, v ...interface{}) {
+		diffs++
+		if flagMax <= 0 || diffs <= flagMax {
+			fmt.Printf(format+"\n", v...)
+		}
+	}
+	if fingerA != fingerB {
+		report("Fragment libraries differ: '%s' has fingerprint %s, but "+
+			"'%s' has fingerprint %s.", pathA, fingerA, pathB, fingerB)
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i].Id < b[j].Id):
+			report("%s: only in '%s'", a[i].Id, pathA)
+			i++
+		case i >= len(a) || b[j].Id < a[i].Id:
+			report("%s: only in '%s'", b[j].Id, pathB)
+			j++
+		default:
+			if err := compare(a[i].Bow, b[j].Bow); err != nil {
+				report("%s: %s", a[i].Id, err)
+			}
+			i++
+			j++
+		}
+	}
+
+	if diffs > 0 {
+		if flagMax > 0 && diffs > flagMax {
+			fmt.Printf("... and %d more differences.\n", diffs-flagMax)
+		}
+		util.Fatalf("Found %d differences between '%s' and '%s'.",
+			diffs, pathA, pathB)
+	}
+	util.Verbosef("Compared %d entries. No differences found.", len(a))
+}
+
+// readSorted reads every entry in the database at `path` sorted by id, along
+// with the fingerprint of the database's fragment library. Duplicate ids
+// are an error.
+func readSorted(path string) ([]bow.Bowed, string) {
+	db := util.OpenBowDB(path)
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", path)
+	fingerprint := util.LibraryFingerprint(db.Lib)
+	util.Assert(db.Close())
+
+	sort.Sort(entriesById(entries))
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Id == entries[i-1].Id {
+			util.Fatalf("The id '%s' appears more than once in '%s'.",
+				entries[i].Id, path)
+		}
+	}
+	return entries, fingerprint
+}
+
+// compare returns an error describing the first frequency that differs by
+// more than the tolerance between the two BOWs given.
+func compare(b1, b2 bow.Bow) error {
+	if len(b1.Freqs) != len(b2.Freqs) {
+		return fmt.Errorf("BOWs have %d and %d fragments",
+			len(b1.Freqs), len(b2.Freqs))
+	}
+	for i := range b1.Freqs {
+		f1, f2 := float64(b1.Freqs[i]), float64(b2.Freqs[i])
+		nan := math.IsNaN(f1) != math.IsNaN(f2)
+		if nan || math.Abs(f1-f2) > flagTolerance {
+			return fmt.Errorf("fragment %d has frequencies %f and %f",
+				i, f1, f2)
+		}
+	}
+	return nil
+}
+
+type entriesById []bow.Bowed
+
+func (es entriesById) Len() int           { return len(es) }
+func (es entriesById) Less(i, j int) bool { return es[i].Id < es[j].Id }
+func (es entriesById) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
@@ -0,0 +1,96 @@
+// Command bowdb-histogram prints the total frequency of each fragment over
+// every entry in a BOW database.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ndaniels/tools/util"
+)
+
+var flagCsv = false
+
+func init() {
+	flag.BoolVar(&flagCsv, "csv", flagCsv,
+		"When set, the histogram is written as CSV with a header row.")
+
+	util.FlagParse("bowdb-path",
+		"Sums the frequency of each fragment across every entry in the BOW\n"+
+			"database and prints 'fragment count fraction' for each fragment,\n"+
+			"sorted by count (largest first). The fraction is the count\n"+
+			"divided by the sum of all counts. Fragments with a count of zero\n"+
+			"are included at the end.")
+	util.AssertNArg(1)
+}
+
+type bin struct {
+	frag  int
+	count float64
+}
+
+type binsByCount []bin
+
+func (bs binsByCount) Len() int      { return len(bs) }
+func (bs binsByCount) Swap(i, j int) { bs[i], bs[j] = bs[j], bs[i] }
+func (bs binsByCount) Less(i, j int) bool {
+	if bs[i].count == bs[j].count {
+		return bs[i].frag < bs[j].frag
+	}
+	return bs[i].count > bs[j].count
+}
+
+func main() {
+	// bowdb can only read a database all at once, so memory grows with the
+	// number of entries rather than the size of the library. The entries
+	// cannot be streamed until bowdb can read them one at a time.
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	bins := make([]bin, db.Lib.Size())
+	for i := range bins {
+		bins[i].frag = i
+	}
+	total := 0.0
+	for _, entry := range entries {
+		if len(entry.Bow.Freqs) != len(bins) {
+			util.Fatalf("Entry '%s' has %d fragments, but the library has %d.",
+				entry.Id, len(entry.Bow.Freqs), len(bins))
+		}
+		for i, f := range entry.Bow.Freqs {
+			bins[i].count += float64(f)
+			total += float64(f)
+		}
+	}
+	sort.Sort(binsByCount(bins))
+
+	fraction := func(b bin) float64 {
+		if total == 0 {
+			return 0
+		}
+		return b.count / total
+	}
+	if flagCsv {
+		w := csv.NewWriter(os.Stdout)
+		util.Assert(w.Write([]string{"fragment", "count", "fraction"}))
+		for _, b := range bins {
+			util.Assert(w.Write([]string{
+				strconv.Itoa(b.frag),
+				strconv.FormatFloat(b.count, 'f', -1, 64),
+				strconv.FormatFloat(fraction(b), 'f', 6, 64),
+			}))
+		}
+		w.Flush()
+		util.Assert(w.Error())
+	} else {
+		for _, b := range bins {
+			fmt.Printf("%d %g %0.6f\n", b.frag, b.count, fraction(b))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Command bowdb-sample writes a random subset of a BOW database to a new
+// database.
+package main
+
+import (
+	"flag"
+	"sort"
+
+	"github.com/ndaniels/esfragbag/bowdb"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagNum = 100
+
+func init() {
+	flag.IntVar(&flagNum, "n", flagNum,
+		"The number of entries to sample. If the database has fewer\n"+
+			"entries, all of them are written.")
+
+	util.FlagUse("seed")
+	util.FlagParse("in-bowdb out-bowdb",
+		"Writes a uniform random sample of the entries of 'in-bowdb' to\n"+
+			"'out-bowdb', which uses the same fragment library. Entries are\n"+
+			"written in the same order as they appear in 'in-bowdb'. The\n"+
+			"same seed always selects the same entries.")
+	util.AssertNArg(2)
+	if flagNum < 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The number of entries to sample must not be negative.")
+	}
+}
+
+func main() {
+	// bowdb can only read a database all at once, so the whole database is
+	// held in memory. Sampling cannot stream the entries (as reservoir
+	// sampling would) until bowdb can read them one at a time.
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	sample := util.Rand.Perm(len(entries))
+	if len(sample) > flagNum {
+		sample = sample[:flagNum]
+	}
+	sort.Ints(sample)
+
+	out, err := bowdb.Create(db.Lib, util.Arg(1))
+	util.Assert(err, "Could not create BOW database '%s'", util.Arg(1))
+	for _, i := range sample {
+		out.Add(entries[i])
+	}
+	util.Assert(out.Close(), "Could not write BOW database '%s'", util.Arg(1))
+	util.Verbosef("Sampled %d of %d entries.", len(sample), len(entries))
+}
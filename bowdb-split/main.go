@@ -0,0 +1,71 @@
+// Command bowdb-split writes each entry of a BOW database to its own file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	path "path/filepath"
+
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagFormat = "gob"
+
+// writers maps each output format to a function that writes a BOW file.
+var writers = map[string]func(io.Writer, fragbag.Library, bow.Bowed){
+	"gob":  util.BowWrite,
+	"json": util.BowWriteJSON,
+	"text": util.BowWriteText,
+}
+
+func init() {
+	flag.StringVar(&flagFormat, "format", flagFormat,
+		"The format of each BOW file written. Legal values are gob, json\n"+
+			"and text. All formats can be read by 'bow-dist'.")
+
+	util.FlagUse("sparse", "norms")
+	util.FlagParse("bowdb-path out-dir",
+		"Writes every entry of the BOW database to 'out-dir' as a file\n"+
+			"named '{id}.bow'. Characters in ids other than letters, digits,\n"+
+			"'.', '-' and '_' are replaced with '_'. If two ids have the same\n"+
+			"file name, then '-2', '-3', etc. is added to the later ids.")
+	util.AssertNArg(2)
+
+	if _, ok := writers[flagFormat]; !ok {
+		util.FatalfCode(util.ExitUsage, "Unknown format '%s'.", flagFormat)
+	}
+}
+
+func main() {
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	outDir := util.Arg(1)
+	util.Assert(os.MkdirAll(outDir, 0777))
+
+	write := writers[flagFormat]
+	used := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := util.SafeFileName(entry.Id)
+		if used[name] {
+			for n := 2; used[name]; n++ {
+				name = fmt.Sprintf("%s-%d", util.SafeFileName(entry.Id), n)
+			}
+			util.Warnf("The file name for '%s' is already used. Writing "+
+				"'%s.bow' instead.", entry.Id, name)
+		}
+		used[name] = true
+
+		fpath := path.Join(outDir, name+".bow")
+		f := util.CreateFile(fpath)
+		write(f, db.Lib, entry)
+		util.Assert(f.Close(), "Could not write '%s'", fpath)
+	}
+	util.Verbosef("Wrote %d BOW files.", len(entries))
+}
@@ -0,0 +1,129 @@
+// Command bowdb-verify checks that the entries of a BOW database are
+// consistent with its fragment library.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagRecompute = 0
+
+func init() {
+	flag.IntVar(&flagRecompute, "recompute", flagRecompute,
+		"When set to N > 0, the BOWs of the first N entries are recomputed\n"+
+			"from their sources and compared with the stored BOWs. The\n"+
+			"source of each entry is found from its id in the same way as\n"+
+			"bower files (e.g., PDB_PATH must be set for PDB ids). Entries\n"+
+			"whose sources cannot be found are skipped with a warning.")
+
+	util.FlagUse("alphabet", "mask", "source", "altloc", "het-breaks")
+	util.FlagParse("bowdb-path",
+		"Verifies that every BOW in the database has the dimensionality of\n"+
+			"the database's fragment library and contains only finite,\n"+
+			"non-negative frequencies. Each problem is printed to stdout, and\n"+
+			"the exit status is non-zero if any problems were found.")
+	util.AssertNArg(1)
+}
+
+func main() {
+	db := util.OpenBowDB(util.Arg(0))
+	entries, err := db.ReadAll()
+	util.Assert(err, "Could not read entries from '%s'", util.Arg(0))
+	util.Assert(db.Close())
+
+	problems := 0
+	size := db.Lib.Size()
+	for _, entry := range entries {
+		if err := checkEntry(entry, size); err != nil {
+			fmt.Println(err)
+			problems++
+		}
+	}
+	for i := 0; i < flagRecompute && i < len(entries); i++ {
+		if err := recompute(db.Lib, entries[i]); err != nil {
+			fmt.Println(err)
+			problems++
+		}
+	}
+
+	util.Verbosef("Checked %d entries. Library fingerprint: %s",
+		len(entries), util.LibraryFingerprint(db.Lib))
+	if problems > 0 {
+		util.Fatalf("Found %d problems in '%s'.", problems, util.Arg(0))
+	}
+}
+
+func checkEntry(entry bow.Bowed, size int) error {
+	if len(entry.Bow.Freqs) != size {
+		return fmt.Errorf("%s: has %d fragments, but the library has %d",
+			entry.Id, len(entry.Bow.Freqs), size)
+	}
+	for i, f := range entry.Bow.Freqs {
+		f64 := float64(f)
+		if math.IsNaN(f64) || math.IsInf(f64, 0) || f64 < 0 {
+			return fmt.Errorf("%s: fragment %d has invalid frequency %f",
+				entry.Id, i, f64)
+		}
+	}
+	return nil
+}
+
+// recompute computes the BOW of the source of `entry` and returns an error
+// if it differs from the stored BOW. If the source cannot be found, a
+// warning is emitted and nil is returned.
+func recompute(lib fragbag.Library, entry bow.Bowed) error {
+	if !util.IsPDB(entry.Id) {
+		util.Warnf("Cannot find the source of '%s'.", entry.Id)
+		return nil
+	}
+	bowers := util.BowerOpen(entry.Id, lib, false)
+	defer func() {
+		for _ = range bowers {
+		}
+	}()
+	for b := range bowers {
+		if b.Err != nil {
+			util.Warnf("Cannot recompute '%s': %s", entry.Id, b.Err)
+			return nil
+		}
+
+		var fresh bow.Bowed
+		if fragbag.IsStructure(lib) {
+			fresh = b.Bower.(bow.StructureBower).StructureBow(
+				lib.(fragbag.StructureLibrary))
+		} else {
+			fresh = b.Bower.(bow.SequenceBower).SequenceBow(
+				lib.(fragbag.SequenceLibrary))
+		}
+		if fresh.Id != entry.Id {
+			continue
+		}
+		if !sameFreqs(fresh.Bow, entry.Bow) {
+			return fmt.Errorf("%s: stored BOW differs from recomputed BOW "+
+				"(cosine distance %0.4f)",
+				entry.Id, math.Abs(fresh.Bow.Cosine(entry.Bow)))
+		}
+		return nil
+	}
+	util.Warnf("Cannot recompute '%s': no BOW with that id in its source.",
+		entry.Id)
+	return nil
+}
+
+func sameFreqs(b1, b2 bow.Bow) bool {
+	if len(b1.Freqs) != len(b2.Freqs) {
+		return false
+	}
+	for i := range b1.Freqs {
+		if math.Abs(float64(b1.Freqs[i]-b2.Freqs[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
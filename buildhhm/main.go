@@ -4,6 +4,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	path "path/filepath"
+	"strings"
 
 	"github.com/TuftsBCB/apps/hhsuite"
 	"github.com/TuftsBCB/io/hmm"
@@ -16,26 +20,75 @@ var (
 
 func init() {
 	flag.BoolVar(&flagQuiet, "quiet", flagQuiet,
-		"When set, hhblits/hhmake output will be hidden.")
+		"When set, hhblits/hhmake output will be hidden.\n"+
+			"(Deprecated. Use '-log warn' instead.)")
 
-	util.FlagUse("seq-db")
-	util.FlagParse("in-fasta-file out-hhm-file", "")
-	util.AssertNArg(2)
+	util.FlagUse("seq-db", "progress-interval")
+	util.FlagParse("in-fasta-file out-hhm-file | "+
+		"in-fasta-file [in-fasta-file ...] out-dir",
+		"hhblits/hhmake output is shown when the log level is info or debug.\n"+
+			"If 'out-hhm-file' ends with '.gz', it is gzip compressed.\n\n"+
+			"If the last argument is a directory or more than two arguments\n"+
+			"are given, then an HHM is built for each FASTA file and written\n"+
+			"to 'out-dir' with the same base name and an '.hhm' extension.\n"+
+			"A progress bar is shown, so hhblits/hhmake output is only shown\n"+
+			"when the log level is debug. FASTA files that fail are reported\n"+
+			"and skipped.")
+	util.AssertLeastNArg(2)
+
+	if flagQuiet && util.FlagLogLevel > util.LogWarn {
+		util.FlagLogLevel = util.LogWarn
+	}
 }
 
 func main() {
-	inFasta := util.Arg(0)
-	outHHM := util.Arg(1)
+	last := util.Arg(util.NArg() - 1)
+	if util.NArg() == 2 && !util.IsDir(last) {
+		verbose := util.FlagLogLevel >= util.LogInfo
+		util.Assert(buildHHM(util.Arg(0), last, verbose))
+		return
+	}
+
+	util.Assert(os.MkdirAll(last, 0777))
+	fastas := flag.Args()[:util.NArg()-1]
+	progress := util.NewProgress(len(fastas))
+	for _, inFasta := range fastas {
+		base := path.Base(inFasta)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		outHHM := path.Join(last, base+".hhm")
+
+		progress.Verbosef("Building HHM for '%s'", inFasta)
+		verbose := util.FlagLogLevel >= util.LogDebug
+		progress.JobDone(buildHHM(inFasta, outHHM, verbose))
+	}
+	progress.Close()
+	util.Verbosef("%s", progress.Stats())
+}
 
+// buildHHM builds an HHM from the FASTA file `inFasta` and writes it to
+// `outHHM`. If `verbose` is true, then hhblits/hhmake output is shown.
+func buildHHM(inFasta, outHHM string, verbose bool) error {
 	hhblits := hhsuite.HHBlitsDefault
 	hhmake := hhsuite.HHMakePseudo
-	hhblits.Verbose = !flagQuiet
-	hhmake.Verbose = !flagQuiet
+	hhblits.Verbose = verbose
+	hhmake.Verbose = verbose
 
 	HHM, err := hhsuite.BuildHHM(
 		hhblits, hhmake, util.FlagSeqDB, inFasta)
-	util.Assert(err, "Error building HHM")
+	if err != nil {
+		return fmt.Errorf("Error building HHM from '%s': %s", inFasta, err)
+	}
 
-	util.Assert(hmm.WriteHHM(util.CreateFile(outHHM), HHM),
-		"Error writing HHM '%s'", outHHM)
+	out, err := util.CreateMaybeGz(outHHM)
+	if err != nil {
+		return err
+	}
+	if err := hmm.WriteHHM(out, HHM); err != nil {
+		out.Close()
+		return fmt.Errorf("Error writing HHM '%s': %s", outHHM, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("Error writing HHM '%s': %s", outHHM, err)
+	}
+	return nil
 }
@@ -0,0 +1,34 @@
+// Command chains lists the chain identifiers in PDB and PDBx/mmCIF files.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ndaniels/tools/util"
+)
+
+func init() {
+	util.FlagParse("pdb-or-cif-file ...",
+		"Output the chain identifiers in each PDB or mmCIF file given, one\n"+
+			"file per line. Each line uses the chain selection syntax\n"+
+			"(e.g., '1ctf.ent.gz:A,B') so that it may be given to other\n"+
+			"tools as is.")
+	util.AssertLeastNArg(1)
+}
+
+func main() {
+	for _, fpath := range util.Args() {
+		idents, err := util.ListChains(fpath)
+		if util.Warning(err) {
+			continue
+		}
+
+		chains := make([]string, len(idents))
+		for i := range idents {
+			chains[i] = string(idents[i])
+		}
+		base := strings.Split(fpath, ":")[0]
+		fmt.Printf("%s:%s\n", base, strings.Join(chains, ","))
+	}
+}
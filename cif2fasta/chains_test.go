@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdbx"
+)
+
+func TestParseChainIds(t *testing.T) {
+	tests := []struct {
+		list string
+		ids  []string
+	}{
+		{"", nil},
+		{"A", []string{"A"}},
+		{"A,B", []string{"A", "B"}},
+		{"AA, AB", []string{"AA", "AB"}},
+		{"AB,", []string{"AB"}},
+		{"AB", []string{"A", "B"}}, // one-character ids, as before
+	}
+	for _, test := range tests {
+		ids := parseChainIds(test.list)
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("parseChainIds(%q) = %q, want %q",
+				test.list, ids, test.ids)
+		}
+	}
+}
+
+const strandsCif = `data_1ABC
+#
+loop_
+_entity_poly.entity_id
+_entity_poly.type
+_entity_poly.pdbx_strand_id
+1 'polypeptide(L)' AA,AB
+2 'polypeptide(L)' B
+#
+`
+
+func TestMultiCharacterChains(t *testing.T) {
+	strands, err := readStrandIds(strings.NewReader(strandsCif))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[byte][]string{'1': {"AA", "AB"}, '2': {"B"}}
+	if !reflect.DeepEqual(strands, want) {
+		t.Fatalf("readStrandIds = %q, want %q", strands, want)
+	}
+
+	// The mmCIF reader keeps one chain per first character.
+	entry := &pdbx.Entry{Id: "1ABC"}
+	ent := &pdbx.Entity{Entry: entry, Id: '1'}
+	ent.Chains = map[byte]*pdbx.Chain{'A': {Entity: ent, Id: 'A'}}
+	chains := entityChains(ent, strands['1'])
+	if len(chains) != 2 {
+		t.Fatalf("got %d chains, want 2", len(chains))
+	}
+	if h := chainHeader(chains[1]); h != "1abcAB" {
+		t.Errorf("chainHeader = %q, want %q", h, "1abcAB")
+	}
+
+	chainIds = parseChainIds("AB,")
+	defer func() { chainIds = nil }()
+	if isChainUsable(chains[0]) || !isChainUsable(chains[1]) {
+		t.Errorf("'-chain AB,' should only select chain 'AB'")
+	}
+	chainIds = parseChainIds("AA,B")
+	if !isChainUsable(chains[0]) || isChainUsable(chains[1]) {
+		t.Errorf("'-chain AA,B' should only select chain 'AA'")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// readDescriptions reads the '_entity' category of a PDBx/mmCIF file and
+// returns the description (i.e., '_entity.pdbx_description') of each
+// entity, keyed by the entity identifier. Whitespace in each description
+// (including new lines) is collapsed to single spaces.
+func readDescriptions(r io.Reader) (map[byte]string, error) {
+	values, err := readEntityValues(r,
+		"_entity.", "_entity.id", "_entity.pdbx_description")
+	if err != nil {
+		return nil, err
+	}
+	descs := make(map[byte]string)
+	for id, text := range values {
+		desc := strings.Join(strings.Fields(text), " ")
+		if desc != "?" && desc != "." {
+			descs[id] = desc
+		}
+	}
+	return descs, nil
+}
+
+// readStrandIds reads the '_entity_poly' category of a PDBx/mmCIF file and
+// returns the full identifier of every chain of each polymer entity (i.e.,
+// the comma-separated list '_entity_poly.pdbx_strand_id'), in order, keyed
+// by the entity identifier.
+func readStrandIds(r io.Reader) (map[byte][]string, error) {
+	values, err := readEntityValues(r, "_entity_poly.",
+		"_entity_poly.entity_id", "_entity_poly.pdbx_strand_id")
+	if err != nil {
+		return nil, err
+	}
+	strands := make(map[byte][]string)
+	for id, list := range values {
+		for _, strand := range strings.Split(list, ",") {
+			strand = strings.TrimSpace(strand)
+			if len(strand) > 0 && strand != "?" && strand != "." {
+				strands[id] = append(strands[id], strand)
+			}
+		}
+	}
+	return strands, nil
+}
+
+// readEntityValues returns the value of the tag `valueTag` for each row of
+// a category of a PDBx/mmCIF file, keyed by the first character of the
+// entity identifier in `idTag`. The category may be a loop or a list of
+// tags. `prefix` is the prefix of every tag in the category (e.g.,
+// '_entity.').
+//
+// Only the block of lines containing the category is tokenized, where blocks
+// are separated by lines containing only '#' (as in every mmCIF file
+// distributed by the PDB).
+func readEntityValues(
+	r io.Reader,
+	prefix, idTag, valueTag string,
+) (map[byte]string, error) {
+	var block []string
+	found := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "#" {
+			if found {
+				break
+			}
+			block = block[:0]
+			continue
+		}
+		block = append(block, line)
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, prefix) {
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	values := make(map[byte]string)
+	if !found {
+		return values, nil
+	}
+
+	tokens := cifTokens(block)
+	var ids, texts []string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.bare && t.value == "loop_":
+			var tags []string
+			for i+1 < len(tokens) && tokens[i+1].isTag() {
+				i++
+				tags = append(tags, tokens[i].value)
+			}
+			idCol, valueCol := -1, -1
+			for j, tag := range tags {
+				switch tag {
+				case idTag:
+					idCol = j
+				case valueTag:
+					valueCol = j
+				}
+			}
+			var row []string
+			for i+1 < len(tokens) && !tokens[i+1].isTag() &&
+				!(tokens[i+1].bare && tokens[i+1].value == "loop_") {
+				i++
+				row = append(row, tokens[i].value)
+			}
+			if idCol < 0 || valueCol < 0 || len(tags) == 0 {
+				continue
+			}
+			for j := 0; j+len(tags) <= len(row); j += len(tags) {
+				ids = append(ids, row[j+idCol])
+				texts = append(texts, row[j+valueCol])
+			}
+		case t.isTag() && i+1 < len(tokens):
+			i++
+			switch t.value {
+			case idTag:
+				ids = append(ids, tokens[i].value)
+			case valueTag:
+				texts = append(texts, tokens[i].value)
+			}
+		}
+	}
+	for i := 0; i < len(ids) && i < len(texts); i++ {
+		if len(ids[i]) > 0 {
+			values[ids[i][0]] = texts[i]
+		}
+	}
+	return values, nil
+}
+
+// cifToken is a single value in a PDBx/mmCIF file. Values that were not
+// quoted are bare, which distinguishes tags and keywords from quoted text.
+type cifToken struct {
+	value string
+	bare  bool
+}
+
+func (t cifToken) isTag() bool {
+	return t.bare && strings.HasPrefix(t.value, "_")
+}
+
+// cifTokens splits lines of a PDBx/mmCIF file into tokens. Quoted values and
+// multi-line text fields (delimited by lines starting with ';') are single
+// tokens. Comments are dropped.
+func cifTokens(lines []string) []cifToken {
+	var tokens []cifToken
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, ";") {
+			text := []string{line[1:]}
+			for i++; i < len(lines) && !strings.HasPrefix(lines[i], ";"); i++ {
+				text = append(text, lines[i])
+			}
+			tokens = append(tokens, cifToken{value: strings.Join(text, "\n")})
+			continue
+		}
+		for j := 0; j < len(line); {
+			c := line[j]
+			switch {
+			case c == ' ' || c == '\t':
+				j++
+			case c == '#':
+				j = len(line)
+			case c == '\'' || c == '"':
+				// A quote only ends a value when followed by whitespace.
+				end := j + 1
+				for end < len(line) {
+					if line[end] == c &&
+						(end+1 == len(line) || line[end+1] == ' ' ||
+							line[end+1] == '\t') {
+						break
+					}
+					end++
+				}
+				tokens = append(tokens, cifToken{value: line[j+1 : end]})
+				j = end + 1
+			default:
+				end := j
+				for end < len(line) && line[end] != ' ' && line[end] != '\t' {
+					end++
+				}
+				tokens = append(tokens, cifToken{value: line[j:end], bare: true})
+				j = end
+			}
+		}
+	}
+	return tokens
+}
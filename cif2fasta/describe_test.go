@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdbx"
+)
+
+// describedCif names entity 1 in a multi-line text field and entity 2 in a
+// quoted value. Entity 3 has no description.
+const describedCif = `data_1ABC
+#
+loop_
+_entity.id
+_entity.type
+_entity.pdbx_description
+1 polymer
+;Hemoglobin alpha
+  chain
+;
+2 polymer 'Guide   RNA'
+3 polymer ?
+#
+`
+
+func TestDescribe(t *testing.T) {
+	descs, err := readDescriptions(strings.NewReader(describedCif))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[byte]string{'1': "Hemoglobin alpha chain", '2': "Guide RNA"}
+	if !reflect.DeepEqual(descs, want) {
+		t.Fatalf("readDescriptions = %q, want %q", descs, want)
+	}
+
+	entry := &pdbx.Entry{Id: "1ABC"}
+	tests := []struct {
+		entity   byte
+		chain    string
+		polyType string
+		header   string
+	}{
+		{'1', "A", "protein", "1abcA Hemoglobin alpha chain"},
+		{'2', "B", "rna", "1abcB Guide RNA [RNA]"},
+		{'3', "C", "protein", "1abcC"},
+	}
+	for _, test := range tests {
+		ent := &pdbx.Entity{Entry: entry, Id: test.entity}
+		chain := cifChain{&pdbx.Chain{Entity: ent}, test.chain}
+		header := fastaHeader(chain, descs, test.polyType)
+		if header != test.header {
+			t.Errorf("header of chain %s is %q, want %q",
+				test.chain, header, test.header)
+		}
+		if strings.ContainsAny(header, "\r\n") {
+			t.Errorf("header of chain %s has a new line", test.chain)
+		}
+	}
+
+	// Without '-describe', no descriptions are read.
+	ent := &pdbx.Entity{Entry: entry, Id: '1'}
+	chain := cifChain{&pdbx.Chain{Entity: ent}, "A"}
+	if header := fastaHeader(chain, nil, "protein"); header != "1abcA" {
+		t.Errorf("header without '-describe' is %q, want %q",
+			header, "1abcA")
+	}
+}
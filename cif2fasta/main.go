@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/TuftsBCB/io/fasta"
 	"github.com/TuftsBCB/io/pdbx"
@@ -19,96 +25,639 @@ var (
 	flagChain          = ""
 	flagSeparateChains = false
 	flagSplit          = ""
+	flagModel          = 1
+	flagType           = "protein"
+	flagNameTemplate   = "{pdbid}{chain}.fasta"
+	flagKeepModified   = false
+	flagTrimN          = 0
+	flagTrimC          = 0
+	flagMap            = ""
+	flagDescribe       = false
+	flagMinLen         = 0
+	flagLengths        = false
 )
 
 func init() {
 	flag.StringVar(&flagChain, "chain", flagChain,
-		"This may be set to one or more chain identifiers. Only amino acids "+
-			"belonging to a chain specified will be included.")
+		"This may be set to a comma-separated list of chain identifiers\n"+
+			"(e.g., 'A,B' or 'AA,AB'). Only amino acids belonging to a chain\n"+
+			"specified will be included. Each identifier is matched as a\n"+
+			"whole. For compatibility, a list without commas (e.g., 'AB') is\n"+
+			"a set of one-character identifiers.")
 	flag.StringVar(&flagSplit, "split", flagSplit,
 		"When set, each FASTA entry produced will be written to a file in the "+
 			"specified directory with the PDB id code and chain identifier as "+
 			"the name.")
+	flag.StringVar(&flagNameTemplate, "name-template", flagNameTemplate,
+		"The template used to name each file written with '-split'. The\n"+
+			"placeholders {pdbid} (lowercase), {PDBID} (uppercase), {chain}\n"+
+			"and {entity} are replaced with the PDB id code, chain identifier\n"+
+			"and entity identifier of each FASTA entry.")
+	flag.IntVar(&flagModel, "model", flagModel,
+		"The model (starting with 1) to use for entries with multiple\n"+
+			"models. Chains with coordinates but without this model are\n"+
+			"skipped, and it is an error if no chain has this model.")
+	flag.StringVar(&flagType, "type", flagType,
+		"The type of polymer to include. Legal values are protein, rna,\n"+
+			"dna, hybrid (DNA/RNA hybrids), other (any other polymer, e.g.,\n"+
+			"polysaccharides) and all. When set to all, the header of each\n"+
+			"sequence that is not a protein is labeled with its type (e.g.,\n"+
+			"' [RNA]' or ' [DNA/RNA]').")
+	flag.BoolVar(&flagKeepModified, "keep-modified", flagKeepModified,
+		"When set, modified amino acids (e.g., MSE) are written as the\n"+
+			"lowercase letter of their parent amino acid, or 'x' if the\n"+
+			"parent is not known, instead of as the uppercase letter.")
+	flag.IntVar(&flagTrimN, "trim-n", flagTrimN,
+		"The number of residues to remove from the N-terminus (start) of\n"+
+			"every sequence. Sequences with no residues left are skipped.")
+	flag.IntVar(&flagTrimC, "trim-c", flagTrimC,
+		"The number of residues to remove from the C-terminus (end) of\n"+
+			"every sequence. Sequences with no residues left are skipped.")
+	flag.StringVar(&flagMap, "map", flagMap,
+		"When set, a tab-separated table is written to the file given\n"+
+			"with one row per entity written. Each row has the PDB id code,\n"+
+			"the entity identifier, a comma-separated list of the chains\n"+
+			"written for the entity, its sequence length (after trimming)\n"+
+			"and its polymer type.")
 
-	util.FlagParse("in-pdb-file [out-fasta-file]", "")
+	flag.BoolVar(&flagDescribe, "describe", flagDescribe,
+		"When set, the description of each entity (e.g., the molecule\n"+
+			"name) is added to the header of its sequences after the id.")
+	flag.IntVar(&flagMinLen, "min-len", flagMinLen,
+		"Sequences with fewer residues than this are skipped. The length\n"+
+			"is checked before '-trim-n' and '-trim-c' are applied.")
+	flag.BoolVar(&flagLengths, "lengths", flagLengths,
+		"When set, no FASTA is written. Instead, a tab-separated table\n"+
+			"with the PDB id code, chain identifier and sequence length of\n"+
+			"each chain that would be written is printed (or written to\n"+
+			"'out-fasta-file' if given).")
+}
+
+// chainIds are the chain identifiers given by '-chain'.
+var chainIds []string
+
+// parseChainIds splits a comma-separated list of chain identifiers, where
+// each identifier may have several characters (e.g., 'AA,AB').
+//
+// For compatibility, a list without commas that has several characters
+// (e.g., 'AB') is a set of one-character identifiers, and a warning shows
+// how to select a single chain with that identifier instead (e.g., 'AB,').
+func parseChainIds(list string) []string {
+	list = strings.TrimSpace(list)
+	if len(list) > 1 && !strings.Contains(list, ",") {
+		util.Warnf("Reading '-chain %s' as the chains %s. Use '-chain %s,' "+
+			"to select the chain '%s'.", list,
+			strings.Join(strings.Split(list, ""), ", "), list, list)
+		return strings.Split(list, "")
+	}
+
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if len(id) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// cifChain is a chain of an entity along with its full identifier. The
+// mmCIF reader only keeps the first character of each chain identifier, so
+// the full identifiers are read separately (see readStrandIds).
+type cifChain struct {
+	*pdbx.Chain
+	id string
+}
+
+// entityChains returns the chains of the entity given, in the order of
+// `strands`, the full identifiers of its chains. Chains are matched with
+// the chains read by the mmCIF reader (which have their models) by the
+// first character of their identifiers. When `strands` is empty, the chains
+// read by the mmCIF reader are returned in order of identifier.
+func entityChains(ent *pdbx.Entity, strands []string) []cifChain {
+	chains := make([]cifChain, 0, len(ent.Chains))
+	if len(strands) == 0 {
+		ids := make([]int, 0, len(ent.Chains))
+		for id := range ent.Chains {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			chain := ent.Chains[byte(id)]
+			chains = append(chains, cifChain{chain, string(chain.Id)})
+		}
+		return chains
+	}
+	for _, id := range strands {
+		chain := ent.Chains[id[0]]
+		if chain == nil {
+			chain = &pdbx.Chain{Entity: ent, Id: id[0]}
+		}
+		chains = append(chains, cifChain{chain, id})
+	}
+	return chains
+}
+
+// record is a FASTA entry along with the chain it was produced from.
+type record struct {
+	chain cifChain
+	seq.Sequence
+}
+
+func main() {
+	util.FlagUse("cpu", "residue-map")
+	util.FlagParse("(in-pdb-file | in-dir) [out-fasta-file]",
+		"If 'in-dir' is a directory, every PDBx/mmCIF file in it is read\n"+
+			"(recursively). Files are parsed in parallel, but their entries\n"+
+			"are written in order of file path. Files that cannot be read\n"+
+			"are reported and skipped.")
 
 	if util.NArg() != 1 && util.NArg() != 2 {
 		util.Usage()
 	}
+	if _, ok := polymerLabels[flagType]; !ok && flagType != "all" {
+		util.FatalfCode(util.ExitUsage, "Unknown polymer type '%s'.", flagType)
+	}
+	util.Assert(checkNameTemplate(flagNameTemplate),
+		"Invalid name template '%s'", flagNameTemplate)
+	if flagModel < 1 {
+		util.FatalfCode(util.ExitUsage,
+			"Model numbers start at 1, but got %d.", flagModel)
+	}
+	if flagTrimN < 0 || flagTrimC < 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The '-trim-n' and '-trim-c' flags must not be negative.")
+	}
+	if flagLengths && len(flagSplit) > 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The '-lengths' and '-split' flags cannot both be set.")
+	}
+	chainIds = parseChainIds(flagChain)
+
+	inputs := []string{flag.Arg(0)}
+	dirMode := util.IsDir(flag.Arg(0))
+	if dirMode {
+		inputs = cifFiles(flag.Arg(0))
+		if len(inputs) == 0 {
+			util.FatalfCode(util.ExitIO,
+				"Could not find any PDBx/mmCIF files in '%s'.",
+				flag.Arg(0))
+		}
+	}
+
+	var fasOut io.WriteCloser
+	if flag.NArg() == 1 {
+		fasOut = os.Stdout
+	} else {
+		if len(flagSplit) > 0 {
+			util.FatalfCode(util.ExitUsage,
+				"The '--split' option is incompatible with a single "+
+					"output file.")
+		}
+		fasOut = util.CreateFileMaybeGz(util.Arg(1))
+	}
+
+	var fasEntries []record
+	var entityRows []entityRow
+	var w *fasta.Writer
+	var lengths *bufio.Writer
+	if flagLengths {
+		lengths = bufio.NewWriter(fasOut)
+		fmt.Fprintf(lengths, "pdbid\tchain\tlength\n")
+	} else if len(flagSplit) == 0 {
+		w = fasta.NewWriter(fasOut)
+	}
+	for result := range readAll(inputs) {
+		if result.err != nil {
+			if !dirMode {
+				util.Fatalf("%s", result.err)
+			}
+			util.Warnf("Skipping '%s': %s", result.fpath, result.err)
+			continue
+		}
+		entityRows = append(entityRows, result.rows...)
+		if lengths != nil {
+			for _, entry := range result.records {
+				fmt.Fprintf(lengths, "%s\t%s\t%d\n",
+					strings.ToLower(entry.chain.Entity.Entry.Id),
+					chainIdent(entry.chain), entry.Len())
+			}
+			continue
+		}
+		if w == nil {
+			fasEntries = append(fasEntries, result.records...)
+			continue
+		}
+		for _, entry := range result.records {
+			util.Assert(w.Write(entry.Sequence), "Could not write FASTA file")
+		}
+	}
+
+	if len(flagMap) > 0 {
+		out := util.CreateFile(flagMap)
+		util.Assert(writeEntityMap(out, entityRows),
+			"Could not write '%s'", flagMap)
+		util.Assert(out.Close(), "Could not write '%s'", flagMap)
+	}
+
+	if lengths != nil {
+		util.Assert(lengths.Flush(), "Could not write lengths")
+		util.Assert(fasOut.Close(), "Could not write lengths")
+	} else if w != nil {
+		util.Assert(w.Flush(), "Could not write FASTA file")
+		util.Assert(fasOut.Close(), "Could not write FASTA file")
+	} else {
+		writeSplit(flagSplit, fasEntries)
+	}
 }
 
-func main() {
-	var f io.Reader
-	var err error
+// writeSplit writes each entry to its own FASTA file in `dir`, named by
+// splitNames. Since every entry has a different file name, up to 'cpu'
+// files are written at once.
+func writeSplit(dir string, entries []record) {
+	names := splitNames(flagNameTemplate, entries)
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fp := path.Join(dir, names[i])
+				out := util.CreateFile(fp)
+
+				w := fasta.NewWriter(out)
+				util.Assert(w.Write(entries[i].Sequence),
+					"Could not write to '%s'", fp)
+				util.Assert(w.Flush(), "Could not write to '%s'", fp)
+				util.Assert(out.Close(), "Could not write to '%s'", fp)
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// fileResult is the FASTA entries and entity rows read from one input file.
+type fileResult struct {
+	fpath   string
+	records []record
+	rows    []entityRow
+	err     error
+}
+
+// readAll reads every input file given in parallel. The results are sent on
+// the channel returned in the same order as the inputs, so that the output
+// does not depend on which files finish parsing first.
+func readAll(inputs []string) <-chan fileResult {
+	pending := make([]chan fileResult, len(inputs))
+	for i := range pending {
+		pending[i] = make(chan fileResult, 1)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		for i := range inputs {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for i := 0; i < util.FlagCpu; i++ {
+		go func() {
+			for i := range jobs {
+				records, rows, err := readEntries(inputs[i])
+				pending[i] <- fileResult{inputs[i], records, rows, err}
+			}
+		}()
+	}
+
+	results := make(chan fileResult)
+	go func() {
+		for _, result := range pending {
+			results <- <-result
+		}
+		close(results)
+	}()
+	return results
+}
 
-	f = util.OpenFile(flag.Arg(0))
-	if strings.HasSuffix(flag.Arg(0), ".gz") {
+// cifFiles returns every PDBx/mmCIF file in `dir`, recursively, sorted by
+// path.
+func cifFiles(dir string) []string {
+	var fpaths []string
+	for _, fpath := range util.RecursiveFiles(dir) {
+		if util.IsCIF(fpath) {
+			fpaths = append(fpaths, fpath)
+		}
+	}
+	sort.Strings(fpaths)
+	return fpaths
+}
+
+// readEntries reads the FASTA entries of the PDBx/mmCIF file at `fpath`,
+// along with a row for the '-map' table for each entity that has an entry.
+func readEntries(fpath string) ([]record, []entityRow, error) {
+	fp, err := os.Open(fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fp.Close()
+
+	var f io.Reader = fp
+	if strings.HasSuffix(fpath, ".gz") {
 		f, err = gzip.NewReader(f)
-		util.Assert(err)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	cifEntry, err := pdbx.Read(f)
-	util.Assert(err, "Could not read PDBx/mmCIF file")
 
-	fasEntries := make([]seq.Sequence, 0, 5)
+	// The full chain identifiers, the residue names needed to find modified
+	// residues (or to apply '-residue-map') and the entity descriptions are
+	// read from extra passes over the file's contents.
+	needMonomers := flagKeepModified || util.FlagResidueMap != nil
+	var monomers map[byte][]string
+	var descs map[byte]string
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not read PDBx/mmCIF file: %s", err)
+	}
+	strands, err := readStrandIds(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not read PDBx/mmCIF file: %s", err)
+	}
+	if needMonomers {
+		monomers, err = readMonomers(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"Could not read PDBx/mmCIF file: %s", err)
+		}
+	}
+	if flagDescribe {
+		descs, err = readDescriptions(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"Could not read PDBx/mmCIF file: %s", err)
+		}
+	}
+	cifEntry, err := pdbx.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not read PDBx/mmCIF file: %s", err)
+	}
+
+	fasEntries := make([]record, 0, 5)
+	entityRows := make([]entityRow, 0, 5)
+	modelFound := false
 	for _, ent := range cifEntry.Entities {
-		for _, chain := range ent.Chains {
+		polyType := polymerType(ent)
+		if flagType != "all" && flagType != polyType {
+			continue
+		}
+		residues := ent.Seq
+		if needMonomers && polyType == "protein" {
+			if len(monomers[ent.Id]) != len(ent.Seq) {
+				util.Warnf("Could not find residue names for entity '%c' "+
+					"of '%s'. Residues will not be mapped or marked.",
+					ent.Id, fpath)
+			}
+			if util.FlagResidueMap != nil {
+				residues = mapResidues(residues, monomers[ent.Id])
+			}
+			if flagKeepModified {
+				residues = markModified(residues, monomers[ent.Id])
+			}
+		}
+		row := entityRow{entity: ent, polyType: polyType}
+		for _, chain := range entityChains(ent, strands[ent.Id]) {
 			if !isChainUsable(chain) || len(ent.Seq) == 0 {
 				continue
 			}
+			if len(chain.Models) > 0 {
+				if flagModel > len(chain.Models) {
+					continue
+				}
+				modelFound = true
+			}
 
-			fasEntry := seq.Sequence{
-				Name:     chainHeader(chain),
-				Residues: ent.Seq,
+			name := fastaHeader(chain, descs, polyType)
+			kept, ok := selectResidues(name, residues)
+			if !ok {
+				continue
 			}
-			fasEntries = append(fasEntries, fasEntry)
+			fasEntry := seq.Sequence{Name: name, Residues: kept}
+			fasEntries = append(fasEntries, record{chain, fasEntry})
+			row.chains = append(row.chains, chain)
+			row.length = len(fasEntry.Residues)
+		}
+		if len(row.chains) > 0 {
+			entityRows = append(entityRows, row)
 		}
 	}
+	if !modelFound && flagModel > 1 {
+		return nil, nil, fmt.Errorf("Model %d does not exist in '%s'.",
+			flagModel, fpath)
+	}
 	if len(fasEntries) == 0 {
-		util.Fatalf("Could not find any chains with amino acids.")
+		return nil, nil, fmt.Errorf("Could not find any chains of type '%s'.",
+			flagType)
+	}
+	return fasEntries, entityRows, nil
+}
+
+var (
+	namePlaceholder  = regexp.MustCompile(`{[^{}]*}`)
+	namePlaceholders = map[string]func(chain cifChain) string{
+		"{pdbid}": func(chain cifChain) string {
+			return strings.ToLower(chain.Entity.Entry.Id)
+		},
+		"{PDBID}": func(chain cifChain) string {
+			return strings.ToUpper(chain.Entity.Entry.Id)
+		},
+		"{chain}": func(chain cifChain) string {
+			return chainIdent(chain)
+		},
+		"{entity}": func(chain cifChain) string {
+			return string(chain.Entity.Id)
+		},
 	}
+)
 
-	var fasOut io.Writer
-	if flag.NArg() == 1 {
-		fasOut = os.Stdout
-	} else {
-		if len(flagSplit) > 0 {
-			util.Fatalf("The '--split' option is incompatible with a single " +
-				"output file.")
+// checkNameTemplate returns an error if the template given contains an
+// unknown placeholder.
+func checkNameTemplate(tpl string) error {
+	for _, ph := range namePlaceholder.FindAllString(tpl, -1) {
+		if _, ok := namePlaceholders[ph]; !ok {
+			return fmt.Errorf("Unknown placeholder '%s'", ph)
 		}
-		fasOut = util.CreateFile(util.Arg(1))
 	}
+	return nil
+}
 
-	if len(flagSplit) == 0 {
-		util.Assert(fasta.NewWriter(fasOut).WriteAll(fasEntries),
-			"Could not write FASTA file '%s'", fasOut)
-	} else {
-		for _, entry := range fasEntries {
-			fp := path.Join(flagSplit, fmt.Sprintf("%s.fasta", entry.Name))
-			out := util.CreateFile(fp)
+// splitName fills in the placeholders of the template given for a chain.
+func splitName(tpl string, chain cifChain) string {
+	return namePlaceholder.ReplaceAllStringFunc(tpl, func(ph string) string {
+		return namePlaceholders[ph](chain)
+	})
+}
+
+// selectResidues returns the residues of the sequence named `name` to write.
+// The sequence is skipped (and false is returned) if it has fewer residues
+// than '-min-len', or if no residues are left after it is trimmed.
+func selectResidues(name string, residues []seq.Residue) ([]seq.Residue, bool) {
+	if len(residues) < flagMinLen {
+		util.Verbosef("Skipping '%s': it has %d residues, which is "+
+			"fewer than %d.", name, len(residues), flagMinLen)
+		return nil, false
+	}
+	trimmed := trim(residues)
+	if len(trimmed) == 0 {
+		util.Warnf("Skipping '%s': no residues are left after "+
+			"trimming %d residues.", name, len(residues))
+		return nil, false
+	}
+	return trimmed, true
+}
+
+// trim removes the number of residues given by '-trim-n' and '-trim-c' from
+// the start and end of the residues given, respectively.
+func trim(residues []seq.Residue) []seq.Residue {
+	if flagTrimN+flagTrimC >= len(residues) {
+		return nil
+	}
+	return residues[flagTrimN : len(residues)-flagTrimC]
+}
+
+// splitNames returns the file name of each entry written with '-split'.
+// Entries whose names collide (e.g., chains with a blank identifier and
+// chains named 'A') are disambiguated by adding '-2', '-3', etc. before the
+// extension of every name after the first, so that no two entries are ever
+// written to the same file.
+func splitNames(tpl string, entries []record) []string {
+	names := make([]string, len(entries))
+	used := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		names[i] = splitName(tpl, entry.chain)
+		used[names[i]] = true
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			continue
+		}
+
+		base, ext := name, ""
+		if dot := strings.Index(name, "."); dot > -1 {
+			base, ext = name[:dot], name[dot:]
+		}
+		for n := 2; ; n++ {
+			uniq := fmt.Sprintf("%s-%d%s", base, n, ext)
+			if !used[uniq] {
+				util.Warnf("Both '%s' and another entry are named '%s'. "+
+					"Writing '%s' instead.", entries[i].Name, name, uniq)
+				names[i] = uniq
+				used[uniq], seen[uniq] = true, true
+				break
+			}
+		}
+	}
+	return names
+}
 
-			w := fasta.NewWriter(out)
-			util.Assert(w.Write(entry), "Could not write to '%s'", fp)
-			util.Assert(w.Flush(), "Could not write to '%s'", fp)
+// entityRow is a row of the table written with '-map': an entity and the
+// chains that were written for it.
+type entityRow struct {
+	entity   *pdbx.Entity
+	polyType string
+	chains   []cifChain
+	length   int
+}
+
+// writeEntityMap writes each entity row as tab-separated values.
+func writeEntityMap(w io.Writer, rows []entityRow) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "pdbid\tentity\tchains\tlength\ttype\n")
+	for _, row := range rows {
+		chains := make([]string, len(row.chains))
+		for i, chain := range row.chains {
+			chains[i] = chainIdent(chain)
 		}
+		fmt.Fprintf(bw, "%s\t%c\t%s\t%d\t%s\n",
+			strings.ToLower(row.entity.Entry.Id), row.entity.Id,
+			strings.Join(chains, ","), row.length, row.polyType)
+	}
+	return bw.Flush()
+}
+
+// fastaHeader returns the FASTA header of a chain whose entity has the
+// polymer type given. The entity's description in `descs`, if any, follows
+// the id.
+func fastaHeader(
+	chain cifChain,
+	descs map[byte]string,
+	polyType string,
+) string {
+	header := chainHeader(chain)
+	if desc, ok := descs[chain.Entity.Id]; ok {
+		header += " " + desc
 	}
+	return header + polymerLabels[polyType]
+}
+
+func chainHeader(chain cifChain) string {
+	return fmt.Sprintf("%s%s",
+		strings.ToLower(chain.Entity.Entry.Id), chainIdent(chain))
 }
 
-func chainHeader(chain *pdbx.Chain) string {
-	ident := chain.Id
-	if ident == ' ' {
-		ident = 'A'
+// chainIdent returns the full identifier of the chain given, where a blank
+// identifier is treated as 'A'.
+func chainIdent(chain cifChain) string {
+	if len(strings.TrimSpace(chain.id)) == 0 {
+		return "A"
 	}
-	return fmt.Sprintf("%s%c", strings.ToLower(chain.Entity.Entry.Id), ident)
+	return chain.id
 }
 
-func isChainUsable(chain *pdbx.Chain) bool {
-	if len(flagChain) == 0 {
+func isChainUsable(chain cifChain) bool {
+	if len(chainIds) == 0 {
 		return true
 	}
-	for i := 0; i < len(flagChain); i++ {
-		if chain.Id == flagChain[i] {
+	for _, id := range chainIds {
+		if chain.id == id {
 			return true
 		}
 	}
 	return false
 }
+
+// polymerLabels maps each polymer type to the label appended to FASTA headers
+// of that type.
+var polymerLabels = map[string]string{
+	"protein": "",
+	"rna":     " [RNA]",
+	"dna":     " [DNA]",
+	"hybrid":  " [DNA/RNA]",
+	"other":   " [OTHER]",
+}
+
+// polymerType classifies an entity by the polymer type declared in its
+// metadata (i.e., '_entity_poly.type'). Entities without a declared type are
+// assumed to be proteins.
+func polymerType(ent *pdbx.Entity) string {
+	t := strings.ToLower(ent.Type)
+	switch {
+	case len(t) == 0 || strings.HasPrefix(t, "polypeptide"):
+		return "protein"
+	case strings.Contains(t, "polydeoxyribonucleotide") &&
+		strings.Contains(t, "polyribonucleotide"):
+		return "hybrid"
+	case strings.HasPrefix(t, "polydeoxyribonucleotide"):
+		return "dna"
+	case strings.HasPrefix(t, "polyribonucleotide"):
+		return "rna"
+	}
+	return "other"
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// standardResidues is the set of three letter codes of the standard amino
+// acids (including unknown residues).
+var standardResidues = map[string]bool{
+	"ALA": true, "ARG": true, "ASN": true, "ASP": true, "CYS": true,
+	"GLN": true, "GLU": true, "GLY": true, "HIS": true, "ILE": true,
+	"LEU": true, "LYS": true, "MET": true, "PHE": true, "PRO": true,
+	"SER": true, "THR": true, "TRP": true, "TYR": true, "VAL": true,
+	"UNK": true,
+}
+
+// markModified returns a copy of the entity sequence `residues` where each
+// modified residue is lowercased. `monomers` are the three letter codes of
+// the residues in the same order. The parent of each modified residue is
+// found with '-residue-map' (or the built-in map), and modified residues
+// whose parent is unknown are written as 'x'.
+//
+// If the number of monomers differs from the number of residues, then the
+// residues are returned unchanged.
+func markModified(residues []seq.Residue, monomers []string) []seq.Residue {
+	if len(monomers) != len(residues) {
+		return residues
+	}
+	marked := make([]seq.Residue, len(residues))
+	for i, mon := range monomers {
+		parent, ok := residueMap()[mon]
+		switch {
+		case standardResidues[mon]:
+			marked[i] = residues[i]
+		case ok && parent >= 'A' && parent <= 'Z':
+			marked[i] = parent - 'A' + 'a'
+		default:
+			marked[i] = 'x'
+		}
+	}
+	return marked
+}
+
+// mapResidues returns the one letter codes of `monomers`, the three letter
+// codes of the residues of an entity, using '-residue-map'. If the number of
+// monomers differs from the number of residues, then the residues are
+// returned unchanged.
+func mapResidues(residues []seq.Residue, monomers []string) []seq.Residue {
+	if len(monomers) != len(residues) {
+		return residues
+	}
+	mapped := make([]seq.Residue, len(monomers))
+	for i, mon := range monomers {
+		mapped[i] = util.FlagResidueMap.Residue(mon)
+	}
+	return mapped
+}
+
+// residueMap returns the residue map given by '-residue-map', or the
+// built-in map if the flag is not set.
+func residueMap() util.ResidueMap {
+	if util.FlagResidueMap != nil {
+		return util.FlagResidueMap
+	}
+	return util.DefaultResidueMap
+}
+
+// readMonomers reads the '_entity_poly_seq' loop of a PDBx/mmCIF file and
+// returns the three letter code of each residue of each entity, keyed by
+// the entity identifier.
+func readMonomers(r io.Reader) (map[byte][]string, error) {
+	monomers := make(map[byte][]string)
+	scanner := bufio.NewScanner(r)
+
+	var columns []string
+	inLoop, inData := false, false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "loop_":
+			inLoop, inData, columns = true, false, nil
+			continue
+		case inLoop && !inData && strings.HasPrefix(line, "_"):
+			columns = append(columns, line)
+			continue
+		case inLoop && !inData:
+			inData = true
+		}
+		if !inData || len(columns) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(columns[0], "_entity_poly_seq.") {
+			inLoop, inData = false, false
+			continue
+		}
+		if len(line) == 0 || line[0] == '#' || line[0] == '_' {
+			inLoop, inData = false, false
+			continue
+		}
+
+		entCol, monCol := -1, -1
+		for i, col := range columns {
+			switch col {
+			case "_entity_poly_seq.entity_id":
+				entCol = i
+			case "_entity_poly_seq.mon_id":
+				monCol = i
+			}
+		}
+		fields := strings.Fields(line)
+		if entCol < 0 || monCol < 0 || len(fields) != len(columns) {
+			continue
+		}
+		entId := fields[entCol][0]
+		monomers[entId] = append(monomers[entId], fields[monCol])
+	}
+	return monomers, scanner.Err()
+}
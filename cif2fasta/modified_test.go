@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+const monomersCif = `data_1ABC
+#
+loop_
+_entity_poly_seq.entity_id
+_entity_poly_seq.num
+_entity_poly_seq.mon_id
+_entity_poly_seq.hetero
+1 1 MET n
+1 2 MSE n
+1 3 ALA n
+1 4 XYZ n
+1 5 GLY n
+#
+`
+
+func TestResidueMapChangesSequence(t *testing.T) {
+	monomers, err := readMonomers(strings.NewReader(monomersCif))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mons := monomers['1']
+	residues := []seq.Residue("MMAXG")
+
+	dir, err := ioutil.TempDir("", "residuemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "residues")
+	custom := "# unusual residues\nxyz k\nMSE X\n"
+	if err := ioutil.WriteFile(fpath, []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { util.FlagResidueMap = nil }()
+
+	tests := []struct {
+		name           string
+		rm             util.ResidueMap
+		mapped, marked string
+	}{
+		{"built-in", util.DefaultResidueMap, "MMAXG", "MmAxG"},
+		{"custom", util.LoadResidueMap(fpath), "MXAKG", "MxAkG"},
+	}
+	for _, test := range tests {
+		util.FlagResidueMap = test.rm
+		mapped := fmt.Sprintf("%s", mapResidues(residues, mons))
+		if mapped != test.mapped {
+			t.Errorf("%s: mapped sequence %q, want %q",
+				test.name, mapped, test.mapped)
+		}
+		marked := fmt.Sprintf("%s", markModified(residues, mons))
+		if marked != test.marked {
+			t.Errorf("%s: marked sequence %q, want %q",
+				test.name, marked, test.marked)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/ndaniels/tools/util"
+)
+
+var aminoAcids = []string{
+	"ALA", "ARG", "ASN", "ASP", "CYS", "GLN", "GLU", "GLY", "HIS", "ILE",
+	"LEU", "LYS", "MET", "PHE", "PRO", "SER", "THR", "TRP", "TYR", "VAL",
+}
+
+// randomCif returns a PDBx/mmCIF file with the id given and a single protein
+// entity of `n` random residues in chains A and B, each with an alpha-carbon
+// atom for every residue.
+func randomCif(rng *rand.Rand, id string, n int) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "data_%s\n#\n_entry.id %s\n#\n", id, id)
+	fmt.Fprintf(buf, "loop_\n_entity_poly.entity_id\n_entity_poly.type\n"+
+		"_entity_poly.pdbx_strand_id\n1 'polypeptide(L)' A,B\n#\n")
+
+	residues := make([]string, n)
+	fmt.Fprintf(buf, "loop_\n_entity_poly_seq.entity_id\n"+
+		"_entity_poly_seq.num\n_entity_poly_seq.mon_id\n")
+	for i := range residues {
+		residues[i] = aminoAcids[rng.Intn(len(aminoAcids))]
+		fmt.Fprintf(buf, "1 %d %s\n", i+1, residues[i])
+	}
+	fmt.Fprintf(buf, "#\nloop_\n_atom_site.group_PDB\n_atom_site.id\n"+
+		"_atom_site.label_atom_id\n_atom_site.label_comp_id\n"+
+		"_atom_site.label_asym_id\n_atom_site.label_entity_id\n"+
+		"_atom_site.label_seq_id\n_atom_site.Cartn_x\n_atom_site.Cartn_y\n"+
+		"_atom_site.Cartn_z\n_atom_site.pdbx_PDB_model_num\n")
+	atom := 1
+	for _, chain := range []string{"A", "B"} {
+		for i, res := range residues {
+			fmt.Fprintf(buf, "ATOM %d CA %s %s 1 %d %.3f %.3f %.3f 1\n",
+				atom, res, chain, i+1,
+				rng.Float64()*50, rng.Float64()*50, rng.Float64()*50)
+			atom++
+		}
+	}
+	fmt.Fprintf(buf, "#\n")
+	return buf.Bytes()
+}
+
+// writeRandomCifs writes `n` random PDBx/mmCIF files to `dir` and returns
+// their paths, sorted.
+func writeRandomCifs(rng *rand.Rand, dir string, n, residues int) []string {
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("%d%03d", 1+i/1000, i%1000)
+		fpath := filepath.Join(dir, id+".cif")
+		err := ioutil.WriteFile(fpath, randomCif(rng, id, residues), 0644)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return cifFiles(dir)
+}
+
+// collect reads every input with `workers` parsers and returns the name of
+// every FASTA entry in the order they are received.
+func collect(inputs []string, workers int) ([]string, error) {
+	defer func(cpu int) { util.FlagCpu = cpu }(util.FlagCpu)
+	util.FlagCpu = workers
+
+	var names []string
+	for result := range readAll(inputs) {
+		if result.err != nil {
+			return nil, result.err
+		}
+		for _, rec := range result.records {
+			names = append(names, rec.Name)
+		}
+	}
+	return names, nil
+}
+
+func TestReadAllOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cif2fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputs := writeRandomCifs(rand.New(rand.NewSource(1)), dir, 20, 50)
+	serial, err := collect(inputs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(serial) != 2*len(inputs) {
+		t.Fatalf("got %d entries, want %d", len(serial), 2*len(inputs))
+	}
+	parallel, err := collect(inputs, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parallel, serial) {
+		t.Errorf("parallel entries differ from serial entries")
+	}
+}
+
+func benchmarkReadAll(b *testing.B, workers int) {
+	dir, err := ioutil.TempDir("", "cif2fasta")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputs := writeRandomCifs(rand.New(rand.NewSource(1)), dir, 64, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := collect(inputs, workers); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadAllSerial(b *testing.B) {
+	benchmarkReadAll(b, 1)
+}
+
+func BenchmarkReadAllParallel(b *testing.B) {
+	benchmarkReadAll(b, runtime.NumCPU())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdbx"
+)
+
+// mixedCif has a protein entity (chain A) and an RNA entity (chain B).
+const mixedCif = `data_1ABC
+#
+_entry.id 1ABC
+#
+loop_
+_entity_poly.entity_id
+_entity_poly.type
+_entity_poly.pdbx_strand_id
+1 'polypeptide(L)' A
+2 polyribonucleotide B
+#
+loop_
+_entity_poly_seq.entity_id
+_entity_poly_seq.num
+_entity_poly_seq.mon_id
+1 1 MET
+1 2 LYS
+1 3 VAL
+2 1 G
+2 2 C
+2 3 U
+2 4 A
+#
+`
+
+func TestPolymerType(t *testing.T) {
+	types := map[string]string{
+		"":                        "protein",
+		"polypeptide(L)":          "protein",
+		"polyribonucleotide":      "rna",
+		"polydeoxyribonucleotide": "dna",
+		"polysaccharide(D)":       "other",
+		"other":                   "other",
+		"polydeoxyribonucleotide/polyribonucleotide hybrid": "hybrid",
+	}
+	for declared, want := range types {
+		ent := &pdbx.Entity{Type: declared}
+		if got := polymerType(ent); got != want {
+			t.Errorf("polymerType(%q) = %q, want %q", declared, got, want)
+		}
+		if _, ok := polymerLabels[want]; !ok {
+			t.Errorf("polymer type %q has no label", want)
+		}
+	}
+}
+
+func TestMixedEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cif2fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "1abc.cif")
+	if err := ioutil.WriteFile(fpath, []byte(mixedCif), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(polyType string) { flagType = polyType }(flagType)
+	tests := []struct {
+		polyType string
+		entries  []string
+	}{
+		{"protein", []string{"1abcA MKV"}},
+		{"rna", []string{"1abcB [RNA] GCUA"}},
+		{"all", []string{"1abcA MKV", "1abcB [RNA] GCUA"}},
+		{"dna", nil},
+	}
+	for _, test := range tests {
+		flagType = test.polyType
+		records, _, err := readEntries(fpath)
+		if test.entries == nil {
+			if err == nil {
+				t.Errorf("-type %s: expected an error", test.polyType)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-type %s: %s", test.polyType, err)
+			continue
+		}
+		var entries []string
+		for _, rec := range records {
+			entries = append(entries,
+				rec.Name+" "+string(residueBytes(rec.Residues)))
+		}
+		// Entities are read into a map, so their order is not fixed.
+		sort.Strings(entries)
+		if !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("-type %s: got %q, want %q",
+				test.polyType, entries, test.entries)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdbx"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// readDir returns the contents of every file in `dir`, keyed by name.
+func readDir(t *testing.T, dir string) map[string]string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(infos))
+	for _, info := range infos {
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[info.Name()] = string(data)
+	}
+	return files
+}
+
+// Run with -race to check that parallel writes do not share files.
+func TestWriteSplitCollisions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Chains with a blank identifier are named like chain 'A'.
+	entry := &pdbx.Entry{Id: "1ABC"}
+	ent := &pdbx.Entity{Entry: entry, Id: '1'}
+	var entries []record
+	for i, id := range []string{"A", " ", "", "A", "B"} {
+		chain := cifChain{&pdbx.Chain{Entity: ent}, id}
+		name := string(rune('a' + i))
+		entries = append(entries, record{chain,
+			seq.NewSequenceString(name, "MKTAYIAKQR"[i:])})
+	}
+
+	defer func(cpu int) { util.FlagCpu = cpu }(util.FlagCpu)
+	var runs []map[string]string
+	for _, cpu := range []int{1, 4} {
+		util.FlagCpu = cpu
+		out := filepath.Join(dir, "cpu"+string(rune('0'+cpu)))
+		if err := os.Mkdir(out, 0777); err != nil {
+			t.Fatal(err)
+		}
+		writeSplit(out, entries)
+		runs = append(runs, readDir(t, out))
+	}
+	if len(runs[0]) != len(entries) {
+		t.Errorf("wrote %d files for %d entries", len(runs[0]), len(entries))
+	}
+	if !reflect.DeepEqual(runs[0], runs[1]) {
+		t.Errorf("parallel files differ from serial files:\n%v\n%v",
+			runs[1], runs[0])
+	}
+}
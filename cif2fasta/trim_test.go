@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+)
+
+func TestSelectResidues(t *testing.T) {
+	defer func() { flagMinLen, flagTrimN, flagTrimC = 0, 0, 0 }()
+
+	residues := seq.NewSequenceString("", "MKTAYIAKQR").Residues
+	tests := []struct {
+		minLen, trimN, trimC int
+		want                 string // empty if skipped
+	}{
+		{0, 0, 0, "MKTAYIAKQR"},
+		{0, 2, 3, "TAYIA"},
+		{10, 2, 3, "TAYIA"}, // -min-len applies before trimming
+		{11, 0, 0, ""},
+		{0, 5, 5, ""},
+	}
+	for _, test := range tests {
+		flagMinLen, flagTrimN, flagTrimC = test.minLen, test.trimN, test.trimC
+		kept, ok := selectResidues("test", residues)
+		got := string(residueBytes(kept))
+		if ok != (len(test.want) > 0) || got != test.want {
+			t.Errorf("-min-len %d -trim-n %d -trim-c %d: got %q (%v), "+
+				"want %q", test.minLen, test.trimN, test.trimC,
+				got, ok, test.want)
+		}
+	}
+}
+
+func residueBytes(rs []seq.Residue) []byte {
+	bs := make([]byte, len(rs))
+	for i, r := range rs {
+		bs[i] = byte(r)
+	}
+	return bs
+}
@@ -6,5 +6,15 @@ In most cases, command line tools are typically very small and only
 representative of an interface to interact with an existing library. In more
 exceptional circumstances where performance is needed, a command line tool
 may use concurrency in an attempt to decrease execution time.
+
+Every tool uses the same exit codes when it fails, so that scripts can react
+to the kind of failure:
+
+	0  success
+	1  any other failure (e.g., problems found by a validation tool)
+	2  invalid arguments or flags
+	3  a file could not be found, opened or created
+	4  an input file is malformed
+	5  an internal error (i.e., a bug)
 */
 package tools
@@ -1,21 +1,67 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	path "path/filepath"
+	"sort"
 
 	"github.com/TuftsBCB/io/fasta"
 	"github.com/ndaniels/tools/util"
 )
 
+var flagManifest = false
+
 func init() {
-	util.FlagParse("fasta-file",
+	flag.BoolVar(&flagManifest, "manifest", flagManifest,
+		"When set, the argument is a directory of FASTA shards (e.g., as\n"+
+			"written by 'fasta-split'). The number of sequences in each\n"+
+			"shard is printed as 'shard<TAB>count', in order of file name,\n"+
+			"followed by a 'total<TAB>count' line.")
+
+	util.FlagParse("fasta-file | -manifest shard-dir",
 		"Quickly count the number of sequences in a fasta file.")
 	util.AssertNArg(1)
 }
 
 func main() {
+	if flagManifest {
+		manifest(util.Arg(0))
+		return
+	}
 	rfasta := util.OpenFasta(util.Arg(0))
 	count, err := fasta.QuickSequenceCount(rfasta)
 	util.Assert(err)
 	fmt.Println(count)
 }
+
+// manifest prints the number of sequences in every FASTA file in `dir`,
+// along with the total.
+func manifest(dir string) {
+	if !util.IsDir(dir) {
+		util.FatalfCode(util.ExitIO, "'%s' is not a directory.", dir)
+	}
+	shards := make([]string, 0)
+	for _, fpath := range util.RecursiveFiles(dir) {
+		if util.IsFasta(fpath) {
+			shards = append(shards, fpath)
+		}
+	}
+	sort.Strings(shards)
+
+	total := 0
+	for _, shard := range shards {
+		f := util.OpenMaybeGz(shard)
+		count, err := fasta.QuickSequenceCount(f)
+		util.Assert(err, "Could not count sequences in '%s'", shard)
+		util.Assert(f.Close())
+
+		rel, err := path.Rel(dir, shard)
+		if err != nil {
+			rel = shard
+		}
+		fmt.Printf("%s\t%d\n", rel, count)
+		total += count
+	}
+	fmt.Printf("total\t%d\n", total)
+}
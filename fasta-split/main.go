@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"io"
 	"os"
 	path "path/filepath"
@@ -10,7 +11,12 @@ import (
 	"github.com/ndaniels/tools/util"
 )
 
+var flagGzip = false
+
 func init() {
+	flag.BoolVar(&flagGzip, "gzip", flagGzip,
+		"When set, each file is gzip compressed and named '{name}.fasta.gz'.")
+
 	util.FlagParse("fasta-file out-dir",
 		"Split a single FASTA file into a set of files for each sequence.")
 	util.AssertNArg(2)
@@ -21,6 +27,11 @@ func main() {
 	dir := util.Arg(1)
 	util.Assert(os.MkdirAll(dir, 0777))
 
+	ext := ".fasta"
+	if flagGzip {
+		ext += ".gz"
+	}
+
 	fr := fasta.NewReader(rfasta)
 	for {
 		s, err := fr.Read()
@@ -32,7 +43,7 @@ func main() {
 		}
 
 		s.Name = strings.Fields(s.Name)[0]
-		fw := util.CreateFile(path.Join(dir, s.Name+".fasta"))
+		fw := util.CreateFileMaybeGz(path.Join(dir, s.Name+ext))
 		w := fasta.NewWriter(fw)
 		util.Assert(w.Write(s))
 		util.Assert(w.Flush())
@@ -0,0 +1,57 @@
+package main
+
+import "github.com/TuftsBCB/seq"
+
+// codonTables maps NCBI translation table identifiers to the amino acid of
+// each of the 64 codons. Codons are ordered as in NCBI's tables, with the
+// bases of each position ordered T, C, A, G. Stop codons are '*'.
+var codonTables = map[int]string{
+	1:  "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	2:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSS**VVVVAAAADDEEGGGG",
+	3:  "FFLLSSSSYY**CCWWTTTTPPPPHHQQRRRRIIMMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	4:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	5:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSSSSVVVVAAAADDEEGGGG",
+	6:  "FFLLSSSSYYQQCC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	11: "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+}
+
+// baseIndex returns the position of a nucleotide in the order T, C, A, G, or
+// -1 if the nucleotide is ambiguous or unknown. 'U' is treated as 'T'.
+func baseIndex(r seq.Residue) int {
+	switch r {
+	case 'T', 't', 'U', 'u':
+		return 0
+	case 'C', 'c':
+		return 1
+	case 'A', 'a':
+		return 2
+	case 'G', 'g':
+		return 3
+	}
+	return -1
+}
+
+// translate converts the nucleotides given to amino acids using the codon
+// table given. Any trailing nucleotides that do not form a full codon are
+// ignored. Codons containing an ambiguous nucleotide are translated as 'X'.
+//
+// If '-read-through' is not set, translation stops at the first stop codon,
+// and the stop codon is not included.
+func translate(table string, nucs []seq.Residue) []seq.Residue {
+	aas := make([]seq.Residue, 0, len(nucs)/3)
+	for i := 0; i+3 <= len(nucs); i += 3 {
+		b1, b2, b3 := baseIndex(nucs[i]), baseIndex(nucs[i+1]),
+			baseIndex(nucs[i+2])
+		if b1 < 0 || b2 < 0 || b3 < 0 {
+			aas = append(aas, 'X')
+			continue
+		}
+
+		aa := seq.Residue(table[16*b1+4*b2+b3])
+		if aa == '*' && !flagReadThrough {
+			break
+		}
+		aas = append(aas, aa)
+	}
+	return aas
+}
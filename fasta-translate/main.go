@@ -0,0 +1,72 @@
+// Command fasta-translate translates each nucleotide sequence in a FASTA
+// file to a protein sequence.
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/TuftsBCB/io/fasta"
+	"github.com/ndaniels/tools/util"
+)
+
+var (
+	flagFrame       = 1
+	flagTable       = 1
+	flagReadThrough = false
+)
+
+func init() {
+	flag.IntVar(&flagFrame, "frame", flagFrame,
+		"The reading frame to translate. Legal values are 1, 2 and 3, where\n"+
+			"frame N starts translating at the Nth nucleotide.")
+	flag.IntVar(&flagTable, "table", flagTable,
+		"The NCBI genetic code (translation table) identifier. Supported\n"+
+			"tables are 1, 2, 3, 4, 5, 6 and 11.")
+	flag.BoolVar(&flagReadThrough, "read-through", flagReadThrough,
+		"When set, stop codons are translated as '*' and translation\n"+
+			"continues. Otherwise, translation stops at the first stop codon.")
+
+	util.FlagParse("in-fasta out-fasta",
+		"Translate each nucleotide sequence in 'in-fasta' to a protein\n"+
+			"sequence and write them to 'out-fasta'. Codons with ambiguous\n"+
+			"nucleotides are translated as 'X'.")
+	util.AssertNArg(2)
+
+	if flagFrame < 1 || flagFrame > 3 {
+		util.FatalfCode(util.ExitUsage,
+			"The frame must be 1, 2 or 3, but got %d.", flagFrame)
+	}
+	if _, ok := codonTables[flagTable]; !ok {
+		util.FatalfCode(util.ExitUsage,
+			"Unsupported translation table %d.", flagTable)
+	}
+}
+
+func main() {
+	table := codonTables[flagTable]
+	in := util.OpenFasta(util.Arg(0))
+	out := util.CreateFile(util.Arg(1))
+	defer out.Close()
+
+	r := fasta.NewReader(in)
+	w := fasta.NewWriter(out)
+	for {
+		s, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		util.Assert(err, "Could not read '%s'", util.Arg(0))
+
+		s.Residues = translate(table, s.Residues[min(flagFrame-1, s.Len()):])
+		util.Assert(w.Write(s), "Could not write '%s'", util.Arg(1))
+	}
+	util.Assert(w.Flush(), "Could not write '%s'", util.Arg(1))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TuftsBCB/hhfrag"
+)
+
+func TestCoverage(t *testing.T) {
+	frags := hhfrag.Fragments{{}}
+	fmap := &hhfrag.FragmentMap{
+		Name: "query",
+		Segments: []hhfrag.MapSegment{
+			{Start: 0, End: 5, Frags: frags},
+			{Start: 3, End: 8, Frags: frags},
+			{Start: 6, End: 10},
+		},
+	}
+
+	// The last segment has no fragments, so the C-terminal tail of the
+	// query is not covered.
+	covered, err := coverage(fmap, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if covered != 8 {
+		t.Errorf("got %d covered residues, want 8", covered)
+	}
+
+	if _, err := coverage(fmap, 9); err == nil {
+		t.Errorf("segment [6, 10) should not be within 9 residues")
+	}
+}
@@ -1,17 +1,91 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+
+	"github.com/TuftsBCB/hhfrag"
+	"github.com/TuftsBCB/io/fasta"
+	"github.com/ndaniels/esfragbag"
 	"github.com/ndaniels/tools/util"
 )
 
+var flagCoverage = ""
+
 func init() {
-	util.FlagUse("cpu")
-	util.FlagParse("frag-lib-dir fmap-file out-bow", "")
-	util.AssertNArg(3)
+	flag.StringVar(&flagCoverage, "coverage", flagCoverage,
+		"When set to the FASTA file of the map's query, the fraction of\n"+
+			"query residues covered by at least one fragment is printed to\n"+
+			"stdout in the format 'name covered/total ratio'. The query is\n"+
+			"needed because fragment maps do not record its length.")
 }
 
 func main() {
+	util.FlagUse("cpu", "sparse", "norms")
+	util.FlagParse("frag-lib-dir fmap-file out-bow", "")
+	util.AssertNArg(3)
+
 	lib := util.StructureLibrary(util.Arg(0))
 	fmap := util.FmapRead(util.Arg(1))
-	util.BowWrite(util.CreateFile(util.Arg(2)), fmap.StructureBow(lib))
+	util.Assert(checkSegments(lib, fmap),
+		"Fragment map '%s' is too short", util.Arg(1))
+	util.BowWrite(util.CreateFile(util.Arg(2)), lib, fmap.StructureBow(lib))
+
+	if len(flagCoverage) > 0 {
+		total := queryLength(flagCoverage)
+		covered, err := coverage(fmap, total)
+		util.AssertCode(util.ExitParse, err,
+			"Fragment map '%s' does not match query '%s'",
+			util.Arg(1), flagCoverage)
+		ratio := 0.0
+		if total > 0 {
+			ratio = float64(covered) / float64(total)
+		}
+		fmt.Printf("%s %d/%d %0.4f\n", fmap.Name, covered, total, ratio)
+	}
+}
+
+// queryLength returns the number of residues of the first sequence in the
+// FASTA file at `fpath`.
+func queryLength(fpath string) int {
+	s, err := fasta.NewReader(util.OpenFasta(fpath)).Read()
+	util.AssertCode(util.ExitParse, err, "Could not read '%s'", fpath)
+	return s.Len()
+}
+
+// coverage returns the number of residues of a query with `total` residues
+// that are in at least one segment with a fragment. Segments are half-open
+// intervals of residue positions starting at 0. An error is returned if a
+// segment is not within the query.
+func coverage(fmap *hhfrag.FragmentMap, total int) (int, error) {
+	covered := 0
+	hit := make([]bool, total)
+	for _, seg := range fmap.Segments {
+		if seg.Start < 0 || seg.End > total {
+			return 0, fmt.Errorf("segment [%d, %d) is not within the "+
+				"query's %d residues", seg.Start, seg.End, total)
+		}
+		if len(seg.Frags) == 0 {
+			continue
+		}
+		for i := seg.Start; i < seg.End; i++ {
+			if !hit[i] {
+				hit[i] = true
+				covered++
+			}
+		}
+	}
+	return covered, nil
+}
+
+// checkSegments returns an error if every segment of the map is too short to
+// contain a fragment of the library.
+func checkSegments(lib fragbag.Library, fmap *hhfrag.FragmentMap) error {
+	longest := 0
+	for _, seg := range fmap.Segments {
+		if seg.End-seg.Start > longest {
+			longest = seg.End - seg.Start
+		}
+	}
+	return util.CheckFragmentSize(lib, longest)
 }
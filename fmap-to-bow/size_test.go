@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/TuftsBCB/hhfrag"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// fourLib is a structure library of one fragment of four residues.
+type fourLib struct{}
+
+func (fourLib) Save(w io.Writer) error      { return nil }
+func (fourLib) Size() int                   { return 1 }
+func (fourLib) FragmentSize() int           { return 4 }
+func (fourLib) String() string              { return "four" }
+func (fourLib) Name() string                { return "four" }
+func (fourLib) Tag() string                 { return "structure" }
+func (fourLib) Fragment(i int) interface{}  { return nil }
+func (fourLib) SubLibrary() fragbag.Library { return nil }
+
+func (fourLib) BestStructureFragment([]structure.Coords) int { return 0 }
+func (fourLib) Atoms(i int) []structure.Coords               { return nil }
+
+func TestCheckSegmentsTooShort(t *testing.T) {
+	fmap := &hhfrag.FragmentMap{
+		Name:     "short",
+		Segments: []hhfrag.MapSegment{{Start: 0, End: 3}, {Start: 8, End: 11}},
+	}
+	err := checkSegments(fourLib{}, fmap)
+	want := "input has 3 residues, library 'four' requires at least 4"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+
+	fmap.Segments = append(fmap.Segments, hhfrag.MapSegment{Start: 2, End: 6})
+	if err := checkSegments(fourLib{}, fmap); err != nil {
+		t.Errorf("a segment of 4 residues is long enough, got %s", err)
+	}
+}
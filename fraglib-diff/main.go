@@ -0,0 +1,103 @@
+// Command fraglib-diff compares two structure fragment libraries by pairing
+// each fragment with its nearest counterpart in the other library.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagThreshold = 1.0
+
+func init() {
+	flag.Float64Var(&flagThreshold, "threshold", flagThreshold,
+		"Fragments whose nearest counterpart in the other library has an\n"+
+			"RMSD greater than this are reported as unmatched.")
+
+	util.FlagParse("fraglib-a fraglib-b",
+		"For every fragment in each library, finds the fragment in the\n"+
+			"other library with the smallest RMSD. A summary of the\n"+
+			"distribution of these best-match RMSDs is printed for each\n"+
+			"direction, followed by every unmatched fragment in the form\n"+
+			"'library fragment rmsd nearest-fragment'.\n\n"+
+			"Both libraries must be structure libraries with the same\n"+
+			"fragment size.")
+	util.AssertNArg(2)
+}
+
+// match is the nearest fragment in another library to some fragment.
+type match struct {
+	frag    int
+	nearest int
+	rmsd    float64
+}
+
+func main() {
+	libA := util.StructureLibrary(util.Arg(0))
+	libB := util.StructureLibrary(util.Arg(1))
+	if libA.FragmentSize() != libB.FragmentSize() {
+		util.Fatalf("The fragment size of '%s' (%d) is not the same as the "+
+			"fragment size of '%s' (%d).", util.Arg(0), libA.FragmentSize(),
+			util.Arg(1), libB.FragmentSize())
+	}
+
+	aToB, bToA := nearest(libA, libB), nearest(libB, libA)
+	summarize(fmt.Sprintf("%s -> %s", libA.Name(), libB.Name()), aToB)
+	summarize(fmt.Sprintf("%s -> %s", libB.Name(), libA.Name()), bToA)
+	unmatched(libA.Name(), aToB)
+	unmatched(libB.Name(), bToA)
+}
+
+// nearest returns the nearest fragment in `to` for each fragment in `from`.
+func nearest(from, to fragbag.StructureLibrary) []match {
+	matches := make([]match, from.Size())
+	for i := range matches {
+		matches[i] = match{frag: i, nearest: -1, rmsd: math.Inf(1)}
+		atoms := from.Atoms(i)
+		for j := 0; j < to.Size(); j++ {
+			rmsd := structure.RMSD(atoms, to.Atoms(j))
+			if rmsd < matches[i].rmsd {
+				matches[i].nearest, matches[i].rmsd = j, rmsd
+			}
+		}
+	}
+	return matches
+}
+
+// summarize prints the minimum, quartiles, maximum and mean of the RMSDs of
+// the matches given.
+func summarize(label string, matches []match) {
+	if len(matches) == 0 {
+		fmt.Printf("%s: no fragments\n", label)
+		return
+	}
+	rmsds := make([]float64, len(matches))
+	sum := 0.0
+	for i, m := range matches {
+		rmsds[i] = m.rmsd
+		sum += m.rmsd
+	}
+	sort.Float64s(rmsds)
+
+	quantile := func(q float64) float64 {
+		return rmsds[int(q*float64(len(rmsds)-1))]
+	}
+	fmt.Printf("%s: min %0.4f q1 %0.4f median %0.4f q3 %0.4f max %0.4f "+
+		"mean %0.4f\n", label, rmsds[0], quantile(0.25), quantile(0.5),
+		quantile(0.75), rmsds[len(rmsds)-1], sum/float64(len(rmsds)))
+}
+
+// unmatched prints every match with an RMSD greater than the threshold.
+func unmatched(name string, matches []match) {
+	for _, m := range matches {
+		if m.rmsd > flagThreshold {
+			fmt.Printf("%s %d %0.4f %d\n", name, m.frag, m.rmsd, m.nearest)
+		}
+	}
+}
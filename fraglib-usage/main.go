@@ -0,0 +1,117 @@
+// Command fraglib-usage counts how often each fragment of a structure library
+// is the best fragment for the windows of a set of chains.
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/tools/util"
+)
+
+func init() {
+	util.FlagUse("cpu", "altloc", "het-breaks")
+	util.FlagParse("frag-lib chain-list",
+		"Finds the best fragment for every window of every chain listed in\n"+
+			"'chain-list' (as 'bestfrag' does) and prints 'fragment count\n"+
+			"fraction' for each fragment, sorted by count (largest first).\n"+
+			"The fraction is the count divided by the number of windows.\n"+
+			"Fragments that are never chosen are included at the end.\n\n"+
+			"Each line of 'chain-list' names a PDB file or chain with the\n"+
+			"same syntax accepted for bower files (e.g., '1ctfA' or\n"+
+			"'1ctf.ent.gz:A'). Every protein chain is used when no chain is\n"+
+			"given. Blank lines and lines starting with '#' are skipped,\n"+
+			"and the list may be gzip compressed.")
+	util.AssertNArg(2)
+}
+
+type usage struct {
+	frag  int
+	count int
+}
+
+type usageByCount []usage
+
+func (us usageByCount) Len() int      { return len(us) }
+func (us usageByCount) Swap(i, j int) { us[i], us[j] = us[j], us[i] }
+func (us usageByCount) Less(i, j int) bool {
+	if us[i].count == us[j].count {
+		return us[i].frag < us[j].frag
+	}
+	return us[i].count > us[j].count
+}
+
+func main() {
+	lib := util.StructureLibrary(util.Arg(0))
+	names := util.ReadLinesFile(util.Arg(1))
+
+	// Each goroutine has its own counts, which are summed at the end.
+	counts := make([][]int, util.FlagCpu)
+	progress := util.NewProgress(len(names))
+	jobs := make(chan string)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		counts[i] = make([]int, lib.Size())
+		wg.Add(1)
+		go func(counts []int) {
+			defer wg.Done()
+			for name := range jobs {
+				_, chains, err := util.PDBOpen(name, 0)
+				if err == nil {
+					for _, chain := range chains {
+						if chain.IsProtein() {
+							countChain(lib, chain, counts)
+						}
+					}
+				}
+				progress.JobDone(err)
+			}
+		}(counts[i])
+	}
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+	progress.Close()
+
+	usages := make([]usage, lib.Size())
+	total := 0
+	for frag := range usages {
+		usages[frag].frag = frag
+		for _, cs := range counts {
+			usages[frag].count += cs[frag]
+		}
+		total += usages[frag].count
+	}
+	sort.Sort(usageByCount(usages))
+
+	unused := 0
+	for _, u := range usages {
+		fraction := 0.0
+		if total > 0 {
+			fraction = float64(u.count) / float64(total)
+		}
+		if u.count == 0 {
+			unused++
+		}
+		fmt.Printf("%d %d %0.6f\n", u.frag, u.count, fraction)
+	}
+	util.Verbosef("Counted %d windows. %d of %d fragments were never chosen.",
+		total, unused, lib.Size())
+}
+
+// countChain adds the best fragment of every window of the chain given to
+// `counts`.
+func countChain(lib fragbag.StructureLibrary, chain *pdb.Chain, counts []int) {
+	atoms := chain.CaAtoms()
+	fsize := lib.FragmentSize()
+	for _, seg := range util.CaSegments(chain.Models[0]) {
+		for i := seg.Start; i+fsize <= seg.End; i++ {
+			counts[lib.BestStructureFragment(atoms[i:i+fsize])]++
+		}
+	}
+}
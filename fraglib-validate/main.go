@@ -0,0 +1,99 @@
+// Command fraglib-validate checks that a fragment library loads and is
+// internally consistent.
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/tools/util"
+)
+
+func main() {
+	util.FlagParse("fraglib",
+		"Opens a fragment library and checks that it has at least one\n"+
+			"fragment, that its fragment size is positive and that every\n"+
+			"fragment is present. For structure libraries, every fragment\n"+
+			"must also have exactly the declared number of atoms, each with\n"+
+			"finite coordinates. For sequence libraries, every fragment's\n"+
+			"profile must have exactly the declared number of columns. Each\n"+
+			"problem is printed to stdout, and the exit status is non-zero\n"+
+			"if any problems were found.")
+	util.AssertNArg(1)
+
+	lib := util.Library(util.Arg(0))
+	problems := validate(lib)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		util.Fatalf("Found %d problems in '%s'.", len(problems), util.Arg(0))
+	}
+	util.Verbosef("'%s' (%s) has %d fragments of size %d. Fingerprint: %s",
+		util.Arg(0), lib.Name(), lib.Size(), lib.FragmentSize(),
+		util.LibraryFingerprint(lib))
+}
+
+// profile is implemented by the fragments of sequence libraries. Its length
+// is the number of columns of the fragment's profile.
+type profile interface {
+	Len() int
+}
+
+// validate returns a description of each problem found in the library.
+func validate(lib fragbag.Library) []string {
+	var problems []string
+	report := func(format string, v ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, v...))
+	}
+	if lib.Size() <= 0 {
+		report("library has %d fragments", lib.Size())
+	}
+	if lib.FragmentSize() <= 0 {
+		report("library has a fragment size of %d", lib.FragmentSize())
+	}
+	for i := 0; i < lib.Size(); i++ {
+		frag := lib.Fragment(i)
+		if frag == nil {
+			report("fragment %d: missing", i)
+			continue
+		}
+		if p, ok := frag.(profile); ok && fragbag.IsSequence(lib) {
+			if p.Len() != lib.FragmentSize() {
+				report("fragment %d: has %d profile columns, but the "+
+					"fragment size is %d", i, p.Len(), lib.FragmentSize())
+			}
+		}
+	}
+	if slib, ok := lib.(fragbag.StructureLibrary); ok {
+		for i := 0; i < slib.Size(); i++ {
+			checkAtoms(i, slib.Atoms(i), slib.FragmentSize(), report)
+		}
+	}
+	return problems
+}
+
+// checkAtoms reports a problem if the atoms of fragment `i` of a structure
+// library do not number `size` or have coordinates that are not finite.
+func checkAtoms(
+	i int,
+	atoms []structure.Coords,
+	size int,
+	report func(string, ...interface{}),
+) {
+	if len(atoms) != size {
+		report("fragment %d: has %d atoms, but the fragment size is %d",
+			i, len(atoms), size)
+	}
+	for j, atom := range atoms {
+		for _, c := range []float64{atom.X, atom.Y, atom.Z} {
+			if math.IsNaN(c) || math.IsInf(c, 0) {
+				report("fragment %d: atom %d has invalid coordinates "+
+					"(%f, %f, %f)", i, j+1, atom.X, atom.Y, atom.Z)
+				break
+			}
+		}
+	}
+}
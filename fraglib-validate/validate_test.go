@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// jsonLib is a library in the layout of a fragment library's JSON: a name
+// and a list of numbered fragments. As in fragment libraries, the fragment
+// size is the size of the first fragment.
+type jsonLib struct {
+	Ident     string
+	Fragments []*jsonFrag
+}
+
+type jsonFrag struct {
+	FragNumber int
+	Atoms      []structure.Coords
+	Emissions  [][]float64
+}
+
+func (lib *jsonLib) Save(w io.Writer) error      { return nil }
+func (lib *jsonLib) Size() int                   { return len(lib.Fragments) }
+func (lib *jsonLib) String() string              { return lib.Ident }
+func (lib *jsonLib) Name() string                { return lib.Ident }
+func (lib *jsonLib) Tag() string                 { return "" }
+func (lib *jsonLib) SubLibrary() fragbag.Library { return nil }
+
+func (lib *jsonLib) FragmentSize() int {
+	if len(lib.Fragments) == 0 || lib.Fragments[0] == nil {
+		return 0
+	}
+	f := lib.Fragments[0]
+	return len(f.Atoms) + len(f.Emissions)
+}
+
+func (lib *jsonLib) Fragment(i int) interface{} {
+	if lib.Fragments[i] == nil {
+		return nil
+	}
+	return lib.Fragments[i]
+}
+
+// structLib is a structure library read from JSON.
+type structLib struct{ *jsonLib }
+
+func (lib structLib) BestStructureFragment([]structure.Coords) int {
+	return 0
+}
+
+func (lib structLib) Atoms(i int) []structure.Coords {
+	if lib.Fragments[i] == nil {
+		return nil
+	}
+	return lib.Fragments[i].Atoms
+}
+
+// seqLib is a sequence library read from JSON. Its fragments are profiles.
+type seqLib struct{ *jsonLib }
+
+func (lib seqLib) BestSequenceFragment(seq.Sequence) int { return 0 }
+
+func (f *jsonFrag) Len() int { return len(f.Emissions) }
+
+func readLib(t *testing.T, data string) *jsonLib {
+	var lib jsonLib
+	if err := json.Unmarshal([]byte(data), &lib); err != nil {
+		t.Fatalf("%s: %s", data, err)
+	}
+	return &lib
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence bool
+		json     string
+		problems []string
+	}{
+		{
+			"good", false,
+			`{"Ident": "good", "Fragments": [
+				{"FragNumber": 0, "Atoms": [{"X": 1}, {"Y": 2}]},
+				{"FragNumber": 1, "Atoms": [{"Z": 3}, {"X": 4}]}]}`,
+			nil,
+		},
+		{
+			"empty", false,
+			`{"Ident": "empty", "Fragments": []}`,
+			[]string{"library has 0 fragments",
+				"library has a fragment size of 0"},
+		},
+		{
+			"short", false,
+			`{"Ident": "short", "Fragments": [
+				{"FragNumber": 0, "Atoms": [{"X": 1}, {"Y": 2}]},
+				{"FragNumber": 1, "Atoms": [{"Z": 3}]}]}`,
+			[]string{"fragment 1: has 1 atoms, but the fragment size is 2"},
+		},
+		{
+			"missing", false,
+			`{"Ident": "missing", "Fragments": [
+				{"FragNumber": 0, "Atoms": [{"X": 1}]}, null]}`,
+			[]string{"fragment 1: missing",
+				"fragment 1: has 0 atoms, but the fragment size is 1"},
+		},
+		{
+			"profile", true,
+			`{"Ident": "profile", "Fragments": [
+				{"FragNumber": 0, "Emissions": [[0.5, 0.5], [1, 0]]},
+				{"FragNumber": 1, "Emissions": [[0.5, 0.5], [1, 0], [0, 1]]}]}`,
+			[]string{"fragment 1: has 3 profile columns, " +
+				"but the fragment size is 2"},
+		},
+	}
+	for _, test := range tests {
+		var lib fragbag.Library = structLib{readLib(t, test.json)}
+		if test.sequence {
+			lib = seqLib{readLib(t, test.json)}
+		}
+		if got := validate(lib); !reflect.DeepEqual(got, test.problems) {
+			t.Errorf("%s: got problems %q, want %q",
+				test.name, got, test.problems)
+		}
+	}
+
+	// JSON cannot encode NaN or infinite coordinates.
+	lib := readLib(t, `{"Ident": "nan", "Fragments": [
+		{"FragNumber": 0, "Atoms": [{"X": 1}, {"Y": 2}]}]}`)
+	lib.Fragments[0].Atoms[1].Y = math.Inf(1)
+	want := []string{"fragment 0: atom 2 has invalid coordinates " +
+		"(0.000000, +Inf, 0.000000)"}
+	if got := validate(structLib{lib}); !reflect.DeepEqual(got, want) {
+		t.Errorf("nan: got problems %q, want %q", got, want)
+	}
+}
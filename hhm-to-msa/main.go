@@ -0,0 +1,59 @@
+// Command hhm-to-msa recovers the alignment stored in an HHM file.
+package main
+
+import (
+	"flag"
+
+	"github.com/TuftsBCB/io/hmm"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagOutFmt = ""
+
+func init() {
+	flag.StringVar(&flagOutFmt, "outfmt", flagOutFmt,
+		"Force the format of the output file. Legal values are fasta, "+
+			"stockholm, a2m and a3m.")
+
+	util.FlagParse("hhm-file out-msa",
+		"Writes the sequences stored in an HHM file as an MSA to 'out-msa'.\n"+
+			"The format of 'out-msa' is detected from its extension (e.g.,\n"+
+			"'.a3m'), but may be forced with the 'outfmt' flag. The HHM file\n"+
+			"may be gzip compressed.\n\n"+
+			"HHM files only retain the sequences of the alignment that\n"+
+			"hhmake was told to keep. If there are none, then only the\n"+
+			"consensus sequence (with one residue per match state) is\n"+
+			"written.")
+	util.AssertNArg(2)
+}
+
+func main() {
+	hhmFile, outFile := util.Arg(0), util.Arg(1)
+	outFmt := util.MSAFormatFromFile(outFile, flagOutFmt)
+
+	fhhm := util.OpenMaybeGz(hhmFile)
+	qhhm, err := hmm.ReadHHM(fhhm)
+	util.Assert(err, "Could not read HHM '%s'", hhmFile)
+	util.Assert(fhhm.Close())
+
+	msa := qhhm.MSA
+	if len(msa.Entries) == 0 {
+		cons := qhhm.Secondary.Consensus
+		if cons == nil || cons.Len() == 0 {
+			util.Fatalf("The HHM '%s' has no alignment or consensus sequence.",
+				hhmFile)
+		}
+		util.Warnf("The HHM '%s' has no alignment. Only its consensus "+
+			"sequence will be written.", hhmFile)
+
+		consensus := *cons
+		consensus.Name = qhhm.Meta.Name + " consensus"
+		msa = seq.NewMSA()
+		msa.AddFasta(consensus)
+	}
+
+	out := util.CreateFile(outFile)
+	util.Assert(outFmt.Write(out, msa), "Could not write '%s'", outFile)
+	util.Assert(out.Close())
+}
@@ -0,0 +1,97 @@
+// Command make-fmap computes a fragment map for every FASTA file in a
+// directory and writes each one to an output directory.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ndaniels/tools/util"
+)
+
+func init() {
+	util.FlagUse("cpu", "seq-db", "pdb-hhm-db", "blits", "verbose",
+		"hhfrag-min", "hhfrag-max", "hhfrag-inc", "progress-interval")
+	util.FlagParse("fasta-dir out-dir",
+		"Computes a fragment map for every FASTA file in 'fasta-dir' and\n"+
+			"writes each to 'out-dir' as '{name}.fmap', where '{name}' is the\n"+
+			"FASTA file name without its extension. At most 'cpu' maps are\n"+
+			"computed at once. FASTA files whose map would overwrite that\n"+
+			"of an earlier file (e.g., 'a/x.fasta' and 'b/x.fasta') are\n"+
+			"reported and skipped.")
+	util.AssertNArg(2)
+}
+
+func main() {
+	fastaDir := util.Arg(0)
+	outDir := util.Arg(1)
+
+	util.AssertIsDir(fastaDir)
+	util.Assert(os.MkdirAll(outDir, 0777))
+
+	fastas := make([]string, 0, 100)
+	outs := make([]string, 0, 100)
+	seen := make(map[string]string)
+	for _, fpath := range util.RecursiveFiles(fastaDir) {
+		if !util.IsFasta(fpath) {
+			continue
+		}
+		out := fmapPath(outDir, fpath)
+		if prev, ok := seen[out]; ok {
+			util.Warnf("Skipping '%s': its map '%s' would overwrite the "+
+				"map of '%s'.", fpath, out, prev)
+			continue
+		}
+		seen[out] = fpath
+		fastas = append(fastas, fpath)
+		outs = append(outs, out)
+	}
+
+	progress := util.NewProgress(len(fastas))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				progress.JobDone(makeFmap(fastas[i], outs[i]))
+			}
+		}()
+	}
+	for i := range fastas {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	progress.Close()
+	log.Printf("%s", progress.Stats())
+}
+
+// fmapPath returns the path of the fragment map of `fasta` in `outDir`.
+func fmapPath(outDir, fasta string) string {
+	name := path.Base(fasta)
+	name = strings.TrimSuffix(name, ".gz")
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return path.Join(outDir, fmt.Sprintf("%s.fmap", name))
+}
+
+// makeFmap computes the fragment map of `fasta` and writes it to `out`.
+func makeFmap(fasta, out string) error {
+	fmap, err := util.HHfragConf.MapFromFasta(
+		util.FlagPdbHhmDB, util.FlagSeqDB, fasta)
+	if err != nil {
+		return fmt.Errorf("Could not generate map from '%s': %s", fasta, err)
+	}
+
+	f := util.CreateFile(out)
+	util.FmapWrite(f, fmap)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("Could not write '%s': %s", out, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ndaniels/tools/util"
+)
+
+// readClasses reads a tab-separated file mapping ids to classes (e.g., a
+// SCOP or CATH fold). Blank lines and lines starting with '#' are skipped.
+func readClasses(fpath string) map[string]string {
+	f := util.OpenMaybeGz(fpath)
+	defer f.Close()
+
+	classes := make(map[string]string, 10000)
+	for i, line := range util.ReadLines(f) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			util.FatalfCode(util.ExitParse,
+				"Expected 'id<TAB>class' on line %d of '%s' but "+
+					"got '%s'.", i+1, fpath, line)
+		}
+		classes[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return classes
+}
+
+// classifyClusters prepends the majority class of its members and the
+// purity of that class to each cluster. Purity is the fraction of all members
+// (including those without a class) that are in the majority class. Ties are
+// broken in favor of the alphabetically smallest class. Clusters without any
+// classified members get a class of '-'.
+func classifyClusters(cs clusters, classes map[string]string) clusters {
+	missing := 0
+	classified := make(clusters, len(cs))
+	for i, cluster := range cs {
+		counts := make(map[string]int)
+		for _, label := range cluster {
+			class, ok := classes[label]
+			if !ok {
+				util.Verbosef("'%s' is not in the classification map.", label)
+				missing++
+				continue
+			}
+			counts[class]++
+		}
+
+		majority, purity := "-", 0.0
+		if len(counts) > 0 {
+			names := make([]string, 0, len(counts))
+			for class := range counts {
+				names = append(names, class)
+			}
+			sort.Strings(names)
+			majority = names[0]
+			for _, class := range names[1:] {
+				if counts[class] > counts[majority] {
+					majority = class
+				}
+			}
+			purity = float64(counts[majority]) / float64(len(cluster))
+		}
+		row := []string{majority, fmt.Sprintf("%f", purity)}
+		classified[i] = append(row, cluster...)
+	}
+	if missing > 0 {
+		util.Warnf("%d cluster members are not in the classification map.",
+			missing)
+	}
+	return classified
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/io/newick"
+)
+
+// testDists is a distance store of the pairs given. Missing pairs are far,
+// except that a missing distance between a label and itself is 0.
+type testDists map[[2]string]float64
+
+func (d testDists) Dist(label1, label2 string) float64 {
+	if label2 < label1 {
+		label1, label2 = label2, label1
+	}
+	if dist, ok := d[[2]string{label1, label2}]; ok {
+		return dist
+	}
+	if label1 == label2 {
+		return 0
+	}
+	return 100
+}
+
+func leaves(labels ...string) []newick.Tree {
+	trees := make([]newick.Tree, len(labels))
+	for i, label := range labels {
+		trees[i] = newick.Tree{Label: label}
+	}
+	return trees
+}
+
+func TestClusterCriteria(t *testing.T) {
+	tests := []struct {
+		name      string
+		dists     testDists
+		tree      newick.Tree
+		threshold clusters
+		diameter  clusters
+	}{
+		{
+			// The criteria agree when no distance is at the bound.
+			name:  "agree",
+			dists: testDists{{"a", "b"}: 1, {"c", "d"}: 3},
+			tree: newick.Tree{Children: []newick.Tree{
+				{Children: leaves("a", "b")},
+				{Children: leaves("c", "d")},
+			}},
+			threshold: clusters{{"a", "b"}, {"c"}, {"d"}},
+			diameter:  clusters{{"a", "b"}, {"c"}, {"d"}},
+		},
+		{
+			// A distance equal to the bound is within the threshold, but
+			// splits the subtree by diameter.
+			name:  "at-bound",
+			dists: testDists{{"a", "b"}: 2, {"a", "c"}: 1, {"b", "c"}: 1},
+			tree: newick.Tree{Children: []newick.Tree{
+				{Children: leaves("a", "c")},
+				{Label: "b"},
+			}},
+			threshold: clusters{{"a", "c", "b"}},
+			diameter:  clusters{{"a", "c"}, {"b"}},
+		},
+		{
+			// 'x' is far from itself (e.g., its self-alignment is poor),
+			// which only matters to the threshold.
+			name:  "self",
+			dists: testDists{{"x", "x"}: 5, {"x", "y"}: 1},
+			tree: newick.Tree{Children: []newick.Tree{
+				{Children: leaves("x", "y")},
+			}},
+			threshold: clusters{{"x"}, {"y"}},
+			diameter:  clusters{{"x", "y"}},
+		},
+	}
+	for _, test := range tests {
+		got := treeClusters(withinThreshold(2, test.dists), &test.tree)
+		if !reflect.DeepEqual(got, test.threshold) {
+			t.Errorf("%s: threshold clusters are %q, want %q",
+				test.name, got, test.threshold)
+		}
+		got = treeClusters(underDiameter(2, test.dists), &test.tree)
+		if !reflect.DeepEqual(got, test.diameter) {
+			t.Errorf("%s: diameter clusters are %q, want %q",
+				test.name, got, test.diameter)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+
+	"github.com/ndaniels/tools/util"
+)
+
+// diskDists is a distance store kept in an open addressing hash table on
+// disk, so that distance sets too large for memory can still be clustered.
+// Each lookup costs at least one read from the file, so the in-memory store
+// should be preferred whenever the distances fit in RAM.
+//
+// The file starts with a header of `diskDistsMagic` and the number of slots.
+// Each slot is 24 bytes: a 64 bit hash of the pair of labels (zero when the
+// slot is empty), the distance as a 64 bit float and the file offset of the
+// pair's key. The keys follow the slots. Each is a 32 bit length followed by
+// the two labels in order, separated by a zero byte. Keys are compared on
+// every hash match, so pairs whose hashes collide keep their own distances.
+type diskDists struct {
+	f      *os.File
+	nslots uint64
+
+	// end is the offset at which the next key is written.
+	end int64
+
+	// hash computes the hash of a key. It never returns zero.
+	hash func(key []byte) uint64
+}
+
+const (
+	diskDistsMagic      = "MBDISTS2"
+	diskDistsHeaderSize = int64(len(diskDistsMagic) + 8)
+	diskDistsSlotSize   = 24
+)
+
+var diskOrder = binary.LittleEndian
+
+// isDiskDists returns true if the file at `fpath` is a disk distance store.
+func isDiskDists(fpath string) bool {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(diskDistsMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return string(magic) == diskDistsMagic
+}
+
+// openDiskDists opens a disk distance store written by writeDiskDists.
+func openDiskDists(fpath string) (*diskDists, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, diskDistsHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Could not read header of '%s': %s", fpath, err)
+	}
+	if string(header[:len(diskDistsMagic)]) != diskDistsMagic {
+		f.Close()
+		return nil, fmt.Errorf("'%s' is not a distance store", fpath)
+	}
+	d := &diskDists{
+		f:      f,
+		nslots: diskOrder.Uint64(header[len(diskDistsMagic):]),
+		hash:   keyHash,
+	}
+	if d.nslots == 0 {
+		f.Close()
+		return nil, fmt.Errorf("Distance store '%s' has no slots", fpath)
+	}
+	return d, nil
+}
+
+// createDiskDists creates an empty disk distance store at `fpath` with room
+// for `count` distances.
+func createDiskDists(fpath string, count uint64) (*diskDists, error) {
+	// Keep the load factor at or below one half so probe sequences are short.
+	nslots := 2*count + 1
+	f, err := os.Create(fpath)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, diskDistsHeaderSize)
+	copy(header, diskDistsMagic)
+	diskOrder.PutUint64(header[len(diskDistsMagic):], nslots)
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	size := diskDistsHeaderSize + int64(nslots)*diskDistsSlotSize
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &diskDists{f: f, nslots: nslots, end: size, hash: keyHash}, nil
+}
+
+// writeDiskDists streams the alignment distances in `dir` into a new disk
+// distance store at `fpath`. The alignment files are read twice: once to
+// count the distances (which sizes the table) and once to store them.
+func writeDiskDists(dir, fpath string) {
+	count := uint64(0)
+	readAlignmentPairs(dir, func(p pair) { count++ })
+
+	d, err := createDiskDists(fpath, count)
+	util.Assert(err, "Could not create distance store '%s'", fpath)
+	readAlignmentPairs(dir, func(p pair) {
+		util.Assert(d.put(p), "Could not write distance store '%s'", fpath)
+	})
+	util.Assert(d.Close())
+}
+
+func (d *diskDists) Dist(label1, label2 string) float64 {
+	_, dist, _, err := d.find(pairKey(label1, label2))
+	util.Assert(err, "Could not read distance store")
+	return dist
+}
+
+// put stores the distance of a pair. If the pair is already in the store,
+// then its distance is replaced.
+func (d *diskDists) put(p pair) error {
+	key := pairKey(p.key[0], p.key[1])
+	idx, _, found, err := d.find(key)
+	if err != nil {
+		return err
+	}
+
+	slot := make([]byte, diskDistsSlotSize)
+	if found {
+		if _, err := d.f.ReadAt(slot, d.slotOffset(idx)); err != nil {
+			return err
+		}
+	} else {
+		record := make([]byte, 4+len(key))
+		diskOrder.PutUint32(record, uint32(len(key)))
+		copy(record[4:], key)
+		if _, err := d.f.WriteAt(record, d.end); err != nil {
+			return err
+		}
+		diskOrder.PutUint64(slot, d.hash(key))
+		diskOrder.PutUint64(slot[16:], uint64(d.end))
+		d.end += int64(len(record))
+	}
+	diskOrder.PutUint64(slot[8:], math.Float64bits(p.dist))
+	_, err = d.f.WriteAt(slot, d.slotOffset(idx))
+	return err
+}
+
+// find linearly probes for the slot with the key given. It returns the index
+// of either the slot holding the key or the first empty slot. The distance
+// is zero when the key is not found.
+func (d *diskDists) find(key []byte) (uint64, float64, bool, error) {
+	hash := d.hash(key)
+	slot := make([]byte, diskDistsSlotSize)
+	for i := uint64(0); i < d.nslots; i++ {
+		idx := (hash + i) % d.nslots
+		if _, err := d.f.ReadAt(slot, d.slotOffset(idx)); err != nil {
+			return 0, 0, false, err
+		}
+		switch diskOrder.Uint64(slot) {
+		case 0:
+			return idx, 0, false, nil
+		case hash:
+			same, err := d.keyAt(int64(diskOrder.Uint64(slot[16:])), key)
+			if err != nil {
+				return 0, 0, false, err
+			}
+			if same {
+				dist := math.Float64frombits(diskOrder.Uint64(slot[8:]))
+				return idx, dist, true, nil
+			}
+		}
+	}
+	return 0, 0, false, fmt.Errorf("distance store is full")
+}
+
+// keyAt returns true if the key stored at `offset` is `key`.
+func (d *diskDists) keyAt(offset int64, key []byte) (bool, error) {
+	size := make([]byte, 4)
+	if _, err := d.f.ReadAt(size, offset); err != nil {
+		return false, err
+	}
+	if int(diskOrder.Uint32(size)) != len(key) {
+		return false, nil
+	}
+	stored := make([]byte, len(key))
+	if _, err := d.f.ReadAt(stored, offset+4); err != nil {
+		return false, err
+	}
+	return bytes.Equal(stored, key), nil
+}
+
+func (d *diskDists) slotOffset(idx uint64) int64 {
+	return diskDistsHeaderSize + int64(idx)*diskDistsSlotSize
+}
+
+func (d *diskDists) Close() error {
+	return d.f.Close()
+}
+
+// pairKey returns the key of an unordered pair of labels.
+func pairKey(label1, label2 string) []byte {
+	if label2 < label1 {
+		label1, label2 = label2, label1
+	}
+	key := make([]byte, 0, len(label1)+1+len(label2))
+	key = append(key, label1...)
+	key = append(key, 0)
+	return append(key, label2...)
+}
+
+// keyHash returns a non-zero hash of a key.
+func keyHash(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	if sum := h.Sum64(); sum != 0 {
+		return sum
+	}
+	return 1
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/intern"
+
+	"github.com/TuftsBCB/io/newick"
+)
+
+func tempStore(t testing.TB, count uint64) (*diskDists, func()) {
+	dir, err := ioutil.TempDir("", "diskdists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := createDiskDists(filepath.Join(dir, "dists"), count)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return d, func() {
+		d.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestDiskDists(t *testing.T) {
+	pairs := []pair{
+		{[2]string{"a", "b"}, 1},
+		{[2]string{"a", "c"}, 2},
+		{[2]string{"b", "c"}, 3},
+		{[2]string{"b", "a"}, 4}, // replaces the distance of (a, b)
+	}
+	want := map[[2]string]float64{
+		{"a", "b"}: 4, {"c", "a"}: 2, {"b", "c"}: 3, {"a", "d"}: 0,
+	}
+	hashes := map[string]func([]byte) uint64{
+		"fnv":     keyHash,
+		"collide": func([]byte) uint64 { return 7 },
+	}
+	for name, hash := range hashes {
+		d, clean := tempStore(t, uint64(len(pairs)))
+		d.hash = hash
+		for _, p := range pairs {
+			if err := d.put(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for labels, dist := range want {
+			if got := d.Dist(labels[0], labels[1]); got != dist {
+				t.Errorf("%s: distance of %q is %f, want %f",
+					name, labels, got, dist)
+			}
+		}
+		clean()
+	}
+}
+
+// benchTree returns a balanced binary tree with the labels given as leaves.
+func benchTree(labels []string) newick.Tree {
+	if len(labels) == 1 {
+		return newick.Tree{Label: labels[0]}
+	}
+	mid := len(labels) / 2
+	return newick.Tree{Children: []newick.Tree{
+		benchTree(labels[:mid]), benchTree(labels[mid:]),
+	}}
+}
+
+// benchPairs returns random distances between every pair of `n` labels.
+func benchPairs(n int) ([]string, []pair) {
+	rng := rand.New(rand.NewSource(1))
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("d%06d", i)
+	}
+	pairs := make([]pair, 0, n*(n-1)/2)
+	for i := range labels {
+		for j := i + 1; j < n; j++ {
+			pairs = append(pairs,
+				pair{[2]string{labels[i], labels[j]}, rng.Float64()})
+		}
+	}
+	return labels, pairs
+}
+
+func benchmarkCluster(b *testing.B, dists distStore, labels []string) {
+	tree := benchTree(labels)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		treeClusters(withinThreshold(0.5, dists), &tree)
+	}
+}
+
+func BenchmarkClusterMem(b *testing.B) {
+	labels, pairs := benchPairs(300)
+	table := intern.NewTable(len(labels))
+	for _, p := range pairs {
+		table.Set(table.Atom(p.key[0]), table.Atom(p.key[1]), p.dist)
+	}
+	benchmarkCluster(b, memDists{table}, labels)
+}
+
+func BenchmarkClusterDisk(b *testing.B) {
+	labels, pairs := benchPairs(300)
+	d, clean := tempStore(b, uint64(len(pairs)))
+	defer clean()
+	for _, p := range pairs {
+		if err := d.put(p); err != nil {
+			b.Fatal(err)
+		}
+	}
+	benchmarkCluster(b, d, labels)
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/csv"
 	"log"
-	path "path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,8 +18,33 @@ type pair struct {
 	dist float64
 }
 
+// distStore is a source of distances between pairs of labels in a tree.
+type distStore interface {
+	Dist(label1, label2 string) float64
+}
+
+// memDists is an in-memory distance store backed by an intern table.
+type memDists struct {
+	*intern.Table
+}
+
+func (d memDists) Dist(label1, label2 string) float64 {
+	return d.Get(d.Atom(label1), d.Atom(label2))
+}
+
 func readAlignmentDists(dir string) *intern.Table {
 	dists := intern.NewTable(11000)
+	readAlignmentPairs(dir, func(p pair) {
+		a1, a2 := dists.Atom(p.key[0]), dists.Atom(p.key[1])
+		dists.Set(a1, a2, p.dist)
+	})
+	return dists
+}
+
+// readAlignmentPairs reads every alignment file in `dir` in parallel and
+// calls `add` with each distance found. `add` is only called from a single
+// goroutine.
+func readAlignmentPairs(dir string, add func(p pair)) {
 	threads := util.FlagCpu
 	addDists := make(chan []pair)
 	alignFile := make(chan string)
@@ -29,8 +53,7 @@ func readAlignmentDists(dir string) *intern.Table {
 	go func() {
 		for fileDists := range addDists {
 			for _, pair := range fileDists {
-				a1, a2 := dists.Atom(pair.key[0]), dists.Atom(pair.key[1])
-				dists.Set(a1, a2, pair.dist)
+				add(pair)
 			}
 		}
 		done <- struct{}{}
@@ -68,23 +91,21 @@ func readAlignmentDists(dir string) *intern.Table {
 		}()
 	}
 
-	for _, fpath := range util.RecursiveFiles(dir) {
-		if strings.HasPrefix(path.Base(fpath), ".") {
-			continue
-		}
+	opts := util.WalkOptions{SkipHidden: true}
+	for _, fpath := range util.RecursiveFilesFiltered(dir, opts) {
 		alignFile <- fpath
 	}
 	close(alignFile)
 	wg.Wait()
 	close(addDists)
 	<-done
-	return dists
 }
 
 func recordToDist(record []string) pair {
 	namePieces := strings.SplitN(record[0], ".ent_", 2)
 	if len(namePieces) != 2 {
-		util.Fatalf("Invalid alignment pair: '%s'.", record[0])
+		util.FatalfCode(util.ExitParse,
+			"Invalid alignment pair: '%s'.", record[0])
 	}
 	p1, p2 := namePieces[0], namePieces[1]
 	p2 = p2[0 : len(p2)-5]
@@ -13,30 +13,57 @@ import (
 )
 
 var (
-	flagThreshold = 0.097702
-	flagGobIt     = ""
+	flagThreshold   = 0.097702
+	flagMaxDiameter = 0.0
+	flagGobIt       = ""
+	flagDiskIt      = ""
+	flagClassify    = ""
 )
 
 func init() {
 	flag.Float64Var(&flagThreshold, "threshold", flagThreshold,
 		"The threshold at which to cut the tree.")
+	flag.Float64Var(&flagMaxDiameter, "max-diameter", flagMaxDiameter,
+		"When set, a subtree is cut into a cluster only when its diameter\n"+
+			"(the largest distance between two different labels in it) is\n"+
+			"strictly less than the bound given, and '-threshold' is\n"+
+			"ignored. This differs from '-threshold' in two ways: a\n"+
+			"distance equal to the bound splits the subtree, and the\n"+
+			"distance of a label with itself is never considered.")
 	flag.StringVar(&flagGobIt, "gobit", flagGobIt,
 		"If set, alignment distances will be cached to the file given, "+
 			"then mattbench-cluster will quit.")
+	flag.StringVar(&flagDiskIt, "diskit", flagDiskIt,
+		"If set, alignment distances will be written to a disk backed\n"+
+			"store in the file given, then mattbench-cluster will quit.\n"+
+			"Passing this file in place of the alignment directory reads\n"+
+			"distances from disk as they are needed, which is slower than\n"+
+			"the default in-memory distances but can handle distance sets\n"+
+			"that do not fit in memory.")
+	flag.StringVar(&flagClassify, "classify", flagClassify,
+		"If set, each cluster is labeled with the majority class of its\n"+
+			"members using the tab-separated 'id class' map in the file\n"+
+			"given (e.g., SCOP or CATH folds). The class and its purity are\n"+
+			"written as the first two columns of each cluster.")
+}
 
+func main() {
 	util.FlagUse("cpu", "cpuprof", "verbose")
 	util.FlagParse(
-		"(astral-alignment-dir | alignment-distances-gob) dendrogram-tree "+
+		"(astral-alignment-dir | alignment-distances-gob | "+
+			"alignment-distances-disk) dendrogram-tree "+
 			"out-clusters.csv",
 		"Where `dendrogram-tree` is a file in Newick tree format.")
-	if len(flagGobIt) > 0 {
+	if len(flagGobIt) > 0 || len(flagDiskIt) > 0 {
 		util.AssertNArg(1)
 	} else {
 		util.AssertNArg(3)
 	}
-}
+	if flagMaxDiameter < 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The maximum diameter must not be negative.")
+	}
 
-func main() {
 	if len(util.FlagCpuProf) > 0 {
 		f := util.CreateFile(util.FlagCpuProf)
 		pprof.StartCPUProfile(f)
@@ -50,35 +77,93 @@ func main() {
 		util.Assert(enc.Encode(dists), "Could not GOB encode distances")
 		return
 	}
+	if len(flagDiskIt) > 0 {
+		writeDiskDists(util.Arg(0), flagDiskIt)
+		return
+	}
 
-	var dists *intern.Table
+	var dists distStore
 	if util.IsDir(util.Arg(0)) {
-		dists = readAlignmentDists(util.Arg(0))
+		dists = memDists{readAlignmentDists(util.Arg(0))}
+	} else if isDiskDists(util.Arg(0)) {
+		disk, err := openDiskDists(util.Arg(0))
+		util.Assert(err, "Could not open distances")
+		defer disk.Close()
+		dists = disk
 	} else {
+		var table *intern.Table
 		dec := gob.NewDecoder(util.OpenFile(util.Arg(0)))
-		util.Assert(dec.Decode(&dists), "Could not GOB decode distances")
+		util.Assert(dec.Decode(&table), "Could not GOB decode distances")
+		dists = memDists{table}
 	}
 
 	treeFile := util.Arg(1)
 	outPath := util.Arg(2)
 
-	treeReader := newick.NewReader(util.OpenFile(treeFile))
+	treef := util.OpenMaybeGz(treeFile)
+	treeReader := newick.NewReader(treef)
 	tree, err := treeReader.ReadTree()
 	util.Assert(err, "Could not read newick tree")
+	util.Assert(treef.Close())
 
 	csvw := csv.NewWriter(util.CreateFile(outPath))
-	clusters := treeClusters(flagThreshold, dists, tree)
+	cut := withinThreshold(flagThreshold, dists)
+	if flagMaxDiameter > 0 {
+		cut = underDiameter(flagMaxDiameter, dists)
+	}
+	clusters := treeClusters(cut, tree)
+	if len(flagClassify) > 0 {
+		clusters = classifyClusters(clusters, readClasses(flagClassify))
+	}
 	util.Assert(csvw.WriteAll(clusters))
 }
 
 // clusters corresponds to a set of lists of all labels in a subtree.
 type clusters [][]string
 
-func treeClusters(
-	threshold float64,
-	dists *intern.Table,
-	tree *newick.Tree,
-) clusters {
+// cutter returns true when the labels of a subtree should be cut into a
+// single cluster.
+type cutter func(labels []string) bool
+
+// withinThreshold returns a cutter that accepts a subtree when every pair of
+// its labels, including each label paired with itself, is within
+// `threshold`.
+func withinThreshold(threshold float64, dists distStore) cutter {
+	return func(labels []string) bool {
+		for _, l1 := range labels {
+			for _, l2 := range labels {
+				if dists.Dist(l1, l2) > threshold {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+// underDiameter returns a cutter that accepts a subtree when its diameter,
+// the largest distance between two different labels, is strictly less than
+// `bound`. A subtree with a single label always has a diameter of 0.
+func underDiameter(bound float64, dists distStore) cutter {
+	return func(labels []string) bool {
+		for i := range labels {
+			for j := i + 1; j < len(labels); j++ {
+				if labels[i] == labels[j] {
+					continue
+				}
+				if dists.Dist(labels[i], labels[j]) >= bound {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+// treeClusters cuts `tree` into clusters from the root down. Each subtree
+// accepted by `cut` becomes a cluster of all of its labels, and the children
+// of every other subtree are visited in turn.
+func treeClusters(cut cutter, tree *newick.Tree) clusters {
 	if len(tree.Children) == 0 {
 		if len(tree.Label) > 0 {
 			return clusters{[]string{tree.Label}}
@@ -86,35 +171,21 @@ func treeClusters(
 		return nil
 	}
 
-	// Compare all pairs in this tree. If all are within the threshold given,
-	// then add this subtree as a cluster and move on. Otherwise, dig deeper.
-	within := forNode(tree, func(node1 *newick.Tree) bool {
-		if len(node1.Label) == 0 {
-			return true
+	// Check the labels of this tree. If they can be cut, then add this
+	// subtree as a cluster and move on. Otherwise, dig deeper.
+	labels := make([]string, 0, 10)
+	forNode(tree, func(node *newick.Tree) bool {
+		if len(node.Label) > 0 {
+			labels = append(labels, node.Label)
 		}
-		a1 := dists.Atom(node1.Label)
-		return forNode(tree, func(node2 *newick.Tree) bool {
-			if len(node2.Label) == 0 {
-				return true
-			}
-			return dists.Get(a1, dists.Atom(node2.Label)) <= threshold
-		})
+		return true
 	})
-	if within {
-		cluster := make([]string, 0, 10)
-		forNode(tree, func(node *newick.Tree) bool {
-			if len(node.Label) == 0 {
-				return true
-			}
-			cluster = append(cluster, node.Label)
-			return true
-		})
-		return clusters{cluster}
+	if cut(labels) {
+		return clusters{labels}
 	}
 	clusters := make(clusters, 0, len(tree.Children))
 	for i := range tree.Children {
-		clusters = append(
-			clusters, treeClusters(threshold, dists, &tree.Children[i])...)
+		clusters = append(clusters, treeClusters(cut, &tree.Children[i])...)
 	}
 	return clusters
 }
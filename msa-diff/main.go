@@ -0,0 +1,194 @@
+// Command msa-diff compares how the sequences of two MSAs are aligned.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagInFmt = ""
+
+func init() {
+	flag.StringVar(&flagInFmt, "infmt", flagInFmt,
+		"Force the format of both input files. Legal values are fasta, "+
+			"stockholm, a2m and a3m.")
+
+	util.FlagParse("msa-a msa-b",
+		"Compares the alignment of every sequence in both 'msa-a' and\n"+
+			"'msa-b', matched by name. Residues are assigned to the match\n"+
+			"columns of each MSA (upper case residues and '-' gaps), and\n"+
+			"residues in insertions (lower case) are assigned to no column.\n"+
+			"\n"+
+			"For each sequence in both MSAs, in the order of 'msa-a', a line\n"+
+			"'name<TAB>n' is printed, where n is the number of match columns\n"+
+			"whose residue (or gap) differs between the two MSAs. Sequences\n"+
+			"only in 'msa-a' are printed as 'name<TAB>removed', and those\n"+
+			"only in 'msa-b' as 'name<TAB>added'. Finally, the number of\n"+
+			"shifted columns (match columns that differ for at least one\n"+
+			"sequence) and the number of added and removed sequences are\n"+
+			"printed on lines starting with '#'.\n"+
+			"\n"+
+			"The formats are detected from the extensions of the files, but\n"+
+			"may be forced with the 'infmt' flag.")
+	util.AssertNArg(2)
+}
+
+func main() {
+	a, b := readMSA(util.Arg(0)), readMSA(util.Arg(1))
+	rowsA, rowsB := byName(util.Arg(0), a), byName(util.Arg(1), b)
+
+	ncols := matchColumns(a)
+	if nb := matchColumns(b); nb > ncols {
+		ncols = nb
+	}
+	shifted := make([]bool, ncols)
+
+	shared, added, removed, mismatched := 0, 0, 0, 0
+	for _, name := range names(a) {
+		rowA := rowsA[name]
+		rowB, ok := rowsB[name]
+		if !ok {
+			fmt.Printf("%s\tremoved\n", name)
+			removed++
+			continue
+		}
+		if !sameResidues(rowA, rowB) {
+			util.Warnf("Skipping '%s': its residues differ between '%s' "+
+				"and '%s'.", name, util.Arg(0), util.Arg(1))
+			mismatched++
+			continue
+		}
+		shared++
+
+		colsA, colsB := assignments(rowA, ncols), assignments(rowB, ncols)
+		differ := 0
+		for c := range colsA {
+			if colsA[c] != colsB[c] {
+				shifted[c] = true
+				differ++
+			}
+		}
+		fmt.Printf("%s\t%d\n", name, differ)
+	}
+	for _, name := range names(b) {
+		if _, ok := rowsA[name]; !ok {
+			fmt.Printf("%s\tadded\n", name)
+			added++
+		}
+	}
+
+	nshifted := 0
+	for _, s := range shifted {
+		if s {
+			nshifted++
+		}
+	}
+	fmt.Printf("# sequences: %d shared, %d added, %d removed, "+
+		"%d with different residues\n", shared, added, removed, mismatched)
+	fmt.Printf("# shifted columns: %d of %d\n", nshifted, ncols)
+}
+
+func readMSA(fpath string) seq.MSA {
+	format := util.MSAFormatFromFile(fpath, flagInFmt)
+	f := util.OpenFile(fpath)
+	defer f.Close()
+
+	m, err := format.Read(f)
+	util.AssertCode(util.ExitParse, err, "Could not read MSA '%s'", fpath)
+	return m
+}
+
+// byName maps the name of every row to the row. Only the first row with a
+// particular name is kept.
+func byName(fpath string, m seq.MSA) map[string]seq.Sequence {
+	rows := make(map[string]seq.Sequence, len(m.Entries))
+	for _, row := range m.Entries {
+		if _, ok := rows[row.Name]; ok {
+			util.Warnf("Ignoring duplicate sequence '%s' in '%s'.",
+				row.Name, fpath)
+			continue
+		}
+		rows[row.Name] = row
+	}
+	return rows
+}
+
+// names returns the name of every row without duplicates, in order.
+func names(m seq.MSA) []string {
+	seen := make(map[string]bool, len(m.Entries))
+	ns := make([]string, 0, len(m.Entries))
+	for _, row := range m.Entries {
+		if !seen[row.Name] {
+			seen[row.Name] = true
+			ns = append(ns, row.Name)
+		}
+	}
+	return ns
+}
+
+// matchColumns returns the number of match columns in the MSA.
+func matchColumns(m seq.MSA) int {
+	if len(m.Entries) == 0 {
+		return 0
+	}
+	n := 0
+	for _, r := range m.Entries[0].Residues {
+		if isMatch(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// assignments returns the index (starting at 0, and ignoring gaps) of the
+// residue in each of the first `ncols` match columns of the row given, or -1
+// if the column has a gap or the row has fewer columns.
+func assignments(row seq.Sequence, ncols int) []int {
+	cols := make([]int, ncols)
+	for c := range cols {
+		cols[c] = -1
+	}
+	c, residue := 0, 0
+	for _, r := range row.Residues {
+		if isMatch(r) && c < ncols {
+			if !isGap(r) {
+				cols[c] = residue
+			}
+			c++
+		}
+		if !isGap(r) {
+			residue++
+		}
+	}
+	return cols
+}
+
+// sameResidues returns true if the two rows have the same residues, ignoring
+// gaps and case.
+func sameResidues(row1, row2 seq.Sequence) bool {
+	return bytes.Equal(ungapped(row1), ungapped(row2))
+}
+
+func ungapped(row seq.Sequence) []byte {
+	residues := make([]byte, 0, len(row.Residues))
+	for _, r := range row.Residues {
+		if !isGap(r) {
+			residues = append(residues, byte(r))
+		}
+	}
+	return bytes.ToUpper(residues)
+}
+
+// isMatch returns true if the residue given is in a match column. Lower case
+// residues and '.' gaps are insertions.
+func isMatch(r seq.Residue) bool {
+	return r == '-' || (r >= 'A' && r <= 'Z')
+}
+
+func isGap(r seq.Residue) bool {
+	return r == '-' || r == '.'
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+)
+
+func TestDegap(t *testing.T) {
+	row := func(name, residues string) seq.Sequence {
+		return seq.NewSequenceString(name, residues)
+	}
+	// Columns 0, 4 and 6 only have gaps in the rows selected.
+	rows := []seq.Sequence{
+		row("a", "-MK-.V-"),
+		row("b", ".-KL-V-"),
+		row("c", "-M.-.A."),
+	}
+	want := []string{"MK-V", "-KLV", "M.-A"}
+
+	degapped := degap(rows)
+	if len(degapped) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(degapped), len(want))
+	}
+	for i, w := range want {
+		got := degapped[i]
+		if got.Name != rows[i].Name {
+			t.Errorf("row %d name = %q, want %q", i, got.Name, rows[i].Name)
+		}
+		if s := fmt.Sprintf("%s", got.Residues); s != w {
+			t.Errorf("row %d = %q, want %q", i, s, w)
+		}
+		if got.Len() != len(w) {
+			t.Errorf("row %d length = %d, want %d", i, got.Len(), len(w))
+		}
+	}
+	if s := fmt.Sprintf("%s", rows[0].Residues); s != "-MK-.V-" {
+		t.Errorf("degap modified its input: %q", s)
+	}
+
+	// Without all-gap columns, rows are unchanged.
+	again := degap(degapped)
+	for i, w := range want {
+		if s := fmt.Sprintf("%s", again[i].Residues); s != w {
+			t.Errorf("degapping twice changed row %d to %q", i, s)
+		}
+	}
+}
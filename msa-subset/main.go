@@ -0,0 +1,112 @@
+// Command msa-subset writes the rows of an MSA whose names match a regular
+// expression.
+package main
+
+import (
+	"flag"
+	"regexp"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+var (
+	flagInFmt      = ""
+	flagOutFmt     = ""
+	flagNameRegexp = ""
+	flagDegap      = false
+)
+
+func init() {
+	flag.StringVar(&flagInFmt, "infmt", flagInFmt,
+		"Force the format of the input file. Legal values are fasta, "+
+			"stockholm, a2m and a3m.")
+	flag.StringVar(&flagOutFmt, "outfmt", flagOutFmt,
+		"Force the format of the output file. Legal values are fasta, "+
+			"stockholm, a2m and a3m.")
+	flag.StringVar(&flagNameRegexp, "name-regexp", flagNameRegexp,
+		"Required. Only rows whose names match this regular expression\n"+
+			"are written. The expression may match any part of a name.")
+	flag.BoolVar(&flagDegap, "degap", flagDegap,
+		"When set, columns that only have gaps ('-' or '.') in the rows\n"+
+			"selected are removed.")
+}
+
+func main() {
+	util.FlagParse("in-msa out-msa",
+		"Writes the rows of 'in-msa' whose names match '-name-regexp' to\n"+
+			"'out-msa', in the same order. The formats are detected from the\n"+
+			"extensions of the files, but may be forced with the 'infmt' and\n"+
+			"'outfmt' flags.")
+	util.AssertNArg(2)
+
+	if len(flagNameRegexp) == 0 {
+		util.FatalfCode(util.ExitUsage, "The '-name-regexp' flag must be set.")
+	}
+
+	in, out := util.Arg(0), util.Arg(1)
+	re, err := regexp.Compile(flagNameRegexp)
+	util.Assert(err, "Invalid regular expression '%s'", flagNameRegexp)
+	inFmt := util.MSAFormatFromFile(in, flagInFmt)
+	outFmt := util.MSAFormatFromFile(out, flagOutFmt)
+
+	inf := util.OpenFile(in)
+	msa, err := inFmt.Read(inf)
+	util.Assert(err, "Could not read MSA '%s'", in)
+	util.Assert(inf.Close())
+
+	var rows []seq.Sequence
+	for _, row := range msa.Entries {
+		if re.MatchString(row.Name) {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		util.Fatalf("No rows of '%s' have a name matching '%s'.",
+			in, flagNameRegexp)
+	}
+	if flagDegap {
+		rows = degap(rows)
+	}
+	util.Verbosef("Selected %d of %d rows.", len(rows), len(msa.Entries))
+
+	subset := seq.NewMSA()
+	for _, row := range rows {
+		subset.Add(row)
+	}
+	outf := util.CreateFile(out)
+	util.Assert(outFmt.Write(outf, subset), "Could not write '%s'", out)
+	util.Assert(outf.Close())
+}
+
+// degap returns copies of the rows given without the columns that only have
+// gaps in every row. The order of residues in each row is unchanged. Rows
+// are in A2M form, so they all have the same length.
+func degap(rows []seq.Sequence) []seq.Sequence {
+	ncols := 0
+	for _, row := range rows {
+		if row.Len() > ncols {
+			ncols = row.Len()
+		}
+	}
+	keep := make([]bool, ncols)
+	for _, row := range rows {
+		for c, r := range row.Residues {
+			if r != '-' && r != '.' {
+				keep[c] = true
+			}
+		}
+	}
+
+	degapped := make([]seq.Sequence, len(rows))
+	for i, row := range rows {
+		residues := make([]seq.Residue, 0, row.Len())
+		for c, r := range row.Residues {
+			if keep[c] {
+				residues = append(residues, r)
+			}
+		}
+		degapped[i] = seq.Sequence{Name: row.Name, Residues: residues}
+	}
+	return degapped
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// insertsA3M has insertion columns after the second match column ('lk' in
+// 's1') and after the last match column ('m' in 's2').
+const insertsA3M = `>q
+ACDEF
+>s1
+AClkDEF
+>s2
+A-DEFm
+`
+
+const insertsA2M = `>q
+AC..DEF.
+>s1
+AClkDEF.
+>s2
+A-..DEFm
+`
+
+func TestConvertA3MRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msaconvert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.a3m")
+	mid := filepath.Join(dir, "mid.a2m")
+	out := filepath.Join(dir, "out.a3m")
+	if err := ioutil.WriteFile(in, []byte(insertsA3M), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct{ in, out, format string }{
+		{in, mid, "a2m"},
+		{mid, out, "a3m"},
+	} {
+		outFmt := util.MSAFormatFromFile(step.out, "")
+		w := writer(outFmt)
+		if err := convert(step.in, step.out, outFmt, w); err != nil {
+			t.Fatalf("converting to %s: %s", step.format, err)
+		}
+	}
+
+	for _, want := range []struct{ fpath, contents string }{
+		{mid, insertsA2M},
+		{out, insertsA3M},
+	} {
+		got, err := ioutil.ReadFile(want.fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want.contents {
+			t.Errorf("%s is\n%s\nwant\n%s", filepath.Base(want.fpath),
+				got, want.contents)
+		}
+	}
+}
+
+const annotatedStockholm = `# STOCKHOLM 1.0
+#=GF ID   toy
+#=GF DE   A toy family  with spacing
+#=GF CC
+#=GS s1 AC  P12345.1
+#=GS s2 DE  second sequence
+q  ACDEF
+s1 AC-EF
+s2 A-DEF
+//
+`
+
+func TestConvertStockholmAnnotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msaconvert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.sto")
+	out := filepath.Join(dir, "out.sto")
+	err = ioutil.WriteFile(in, []byte(annotatedStockholm), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outFmt := util.MSAFormatFromFile(out, "")
+	if err := convert(in, out, outFmt, writer(outFmt)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := util.StockholmMeta{
+		GF: []util.StockholmGF{
+			{Feature: "ID", Text: "toy"},
+			{Feature: "DE", Text: "A toy family  with spacing"},
+			{Feature: "CC"},
+		},
+		GS: []util.StockholmGS{
+			{Name: "s1", Feature: "AC", Text: "P12345.1"},
+			{Name: "s2", Feature: "DE", Text: "second sequence"},
+		},
+	}
+	for _, fpath := range []string{in, out} {
+		got, err := readMeta(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("annotations of %s are\n%+v\nwant\n%+v",
+				filepath.Base(fpath), got, want)
+		}
+	}
+
+	inMSA, err := readMSA(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outMSA, err := readMSA(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outMSA.Entries) != len(inMSA.Entries) {
+		t.Fatalf("%d rows written, want %d",
+			len(outMSA.Entries), len(inMSA.Entries))
+	}
+	for i := range inMSA.Entries {
+		got, want := outMSA.GetFasta(i), inMSA.GetFasta(i)
+		if got.Name != want.Name ||
+			string(residueBytes(got.Residues)) !=
+				string(residueBytes(want.Residues)) {
+			t.Errorf("row %d is %s %s, want %s %s", i,
+				got.Name, residueBytes(got.Residues),
+				want.Name, residueBytes(want.Residues))
+		}
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	ins := []string{"a/x.a3m", "b/x.a3m", "b/y.sto", "x.a2m"}
+	outs, errs := outputPaths(ins, "out", util.MSAFormats["fasta"])
+	wantOuts := []string{"out/x.fasta", "out/x.fasta", "out/y.fasta",
+		"out/x.fasta"}
+	for i := range ins {
+		if outs[i] != wantOuts[i] {
+			t.Errorf("output of '%s' is '%s', want '%s'",
+				ins[i], outs[i], wantOuts[i])
+		}
+		if collides := i == 1 || i == 3; (errs[i] != nil) != collides {
+			t.Errorf("error for '%s' is %v, want a collision: %v",
+				ins[i], errs[i], collides)
+		}
+	}
+
+	noExts := util.MSAFormat{Name: "plain"}
+	outs, _ = outputPaths([]string{"x.a3m"}, "out", noExts)
+	if outs[0] != "out/x.plain" {
+		t.Errorf("output without extensions is '%s', want 'out/x.plain'",
+			outs[0])
+	}
+}
+
+func TestCheckWidths(t *testing.T) {
+	m := testMSA("a ACDEF", "b ACD", "c AkCD")
+	err := checkWidths(&m, true, false)
+	if err == nil || !strings.Contains(err.Error(), "'b' has 3 columns") ||
+		!strings.Contains(err.Error(), "'a' has 5 columns") {
+		t.Errorf("ragged rows without -pad: got error %v", err)
+	}
+
+	if err := checkWidths(&m, true, true); err != nil {
+		t.Fatalf("ragged rows with -pad: %s", err)
+	}
+	for i, want := range []string{"ACDEF", "ACD--", "AkCD--"} {
+		if got := string(residueBytes(m.Entries[i].Residues)); got != want {
+			t.Errorf("padded row %d is %q, want %q", i, got, want)
+		}
+	}
+
+	long := testMSA("a ACD", "b ACDEF")
+	if err := checkWidths(&long, false, true); err == nil {
+		t.Errorf("a row longer than the first row should be an error " +
+			"even with -pad")
+	}
+}
+
+func residueBytes(rs []seq.Residue) []byte {
+	bs := make([]byte, len(rs))
+	for i, r := range rs {
+		bs[i] = byte(r)
+	}
+	return bs
+}
@@ -2,39 +2,26 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io"
-	"path"
+	"os"
+	path "path/filepath"
+	"strings"
+	"sync"
 
-	"github.com/TuftsBCB/io/msa"
 	"github.com/TuftsBCB/seq"
 	"github.com/ndaniels/tools/util"
 )
 
-type (
-	msaReader func(io.Reader) (seq.MSA, error)
-	msaWriter func(io.Writer, seq.MSA) error
-	msaIO     struct {
-		r msaReader
-		w msaWriter
-	}
-)
-
 var (
-	flagInFmt  = ""
-	flagOutFmt = ""
-
-	extToFmt = map[string]string{
-		"fasta": "fasta", "fa": "fasta", "fas": "fasta", "ali": "fasta",
-		"sto": "stockholm",
-		"a2m": "a2m",
-		"a3m": "a3m",
-	}
-	fmtToIO = map[string]msaIO{
-		"fasta":     msaIO{msa.ReadFasta, msa.WriteFasta},
-		"stockholm": msaIO{msa.ReadStockholm, msa.WriteStockholm},
-		"a2m":       msaIO{msa.Read, msa.WriteA2M},
-		"a3m":       msaIO{msa.Read, msa.WriteA3M},
-	}
+	flagInFmt     = ""
+	flagOutFmt    = ""
+	flagConsensus = false
+	flagPad       = false
+	flagStats     = false
+	flagCsv       = false
+	flagValidate  = false
+	flagStream    = false
 )
 
 func init() {
@@ -44,48 +31,361 @@ func init() {
 	flag.StringVar(&flagOutFmt, "outfmt", flagOutFmt,
 		"Force the format of the output file. Legal values are fasta, "+
 			"stockholm, a2m and a3m.")
+	flag.BoolVar(&flagConsensus, "annotate-consensus", flagConsensus,
+		"When set and the output format is stockholm, the most common\n"+
+			"residue in each column is written as a '#=GC RF' annotation.\n"+
+			"Columns with only gaps are annotated with a '.'.")
+	flag.BoolVar(&flagPad, "pad", flagPad,
+		"When set, rows of the input MSA that are shorter than the first\n"+
+			"row are padded on the right with gaps. Otherwise, rows with\n"+
+			"differing lengths are an error.")
+	flag.BoolVar(&flagStats, "stats", flagStats,
+		"When set, no conversion is done. Instead, the number of\n"+
+			"sequences, number of columns, fraction of gaps and mean column\n"+
+			"entropy of each 'in-msa' given are printed to stdout.")
+	flag.BoolVar(&flagCsv, "csv", flagCsv,
+		"When set with '-stats', the statistics are printed as CSV with\n"+
+			"one row per MSA.")
+	flag.BoolVar(&flagValidate, "validate", flagValidate,
+		"When set, no conversion is done. Instead, each 'in-msa' given is\n"+
+			"checked for rows with differing numbers of columns, residues\n"+
+			"that are illegal in its format and duplicate sequence names.\n"+
+			"Problems are printed to stdout, and the exit status is non-zero\n"+
+			"if any are found.")
 
-	util.FlagParse("in-msa out-msa",
+	flag.BoolVar(&flagStream, "stream", flagStream,
+		"When set, a2m and a3m inputs are read and converted one sequence\n"+
+			"at a time, so that very large MSAs do not have to fit in memory.\n"+
+			"Only conversions to a3m or fasta are supported, since they do\n"+
+			"not depend on other rows. Insertions are removed from fasta\n"+
+			"output. The '-annotate-consensus' flag cannot be used.")
+}
+
+func main() {
+	util.FlagUse("cpu", "progress-interval")
+	util.FlagParse(
+		"in-msa out-msa | in-msa [in-msa ...] out-dir | "+
+			"(-stats | -validate) in-msa [in-msa ...]",
 		"Convert the format of an MSA file from 'in-msa' to 'out-msa'.\n"+
 			"The formats are auto detected from the file's extension, but\n"+
-			"they may be forced with the 'infmt' and 'outfmt' flags.")
-	util.AssertNArg(2)
+			"they may be forced with the 'infmt' and 'outfmt' flags.\n\n"+
+			"If the last argument is a directory or more than two arguments\n"+
+			"are given, then every input MSA is converted to the format given\n"+
+			"by 'outfmt' and written to 'out-dir' with the same base name and\n"+
+			"the extension of the new format. Inputs that cannot be\n"+
+			"converted, or whose output would overwrite that of an earlier\n"+
+			"input (e.g., 'a/x.a3m' and 'b/x.a3m'), are reported and skipped.")
+	if flagCsv && !flagStats {
+		util.Warnf("The '-csv' flag is ignored without '-stats'.")
+	}
+	if flagStats && flagValidate {
+		util.FatalfCode(util.ExitUsage,
+			"The '-stats' and '-validate' flags cannot both be set.")
+	}
+	if flagStream && (flagStats || flagValidate || flagConsensus) {
+		util.FatalfCode(util.ExitUsage,
+			"The '-stream' flag cannot be used with '-stats', "+
+				"'-validate' or '-annotate-consensus'.")
+	}
+	if flagStats || flagValidate {
+		util.AssertLeastNArg(1)
+	} else {
+		util.AssertLeastNArg(2)
+	}
+
+	if flagStats {
+		printStats(flag.Args())
+		return
+	}
+	if flagValidate {
+		validateAll(flag.Args())
+		return
+	}
+
+	last := util.Arg(util.NArg() - 1)
+	if util.NArg() == 2 && !util.IsDir(last) {
+		in, out := util.Arg(0), util.Arg(1)
+		outFmt := util.MSAFormatFromFile(out, flagOutFmt)
+		if flagStream {
+			checkStream(util.MSAFormatFromFile(in, flagInFmt), outFmt)
+			util.Assert(convertStream(in, out, outFmt))
+			return
+		}
+		util.Assert(convert(in, out, outFmt, writer(outFmt)))
+		return
+	}
+
+	if len(flagOutFmt) == 0 {
+		util.FatalfCode(util.ExitUsage,
+			"The '-outfmt' flag must be set when converting to "+
+				"a directory.")
+	}
+	outFmt := util.MSAFormatFromFile("", flagOutFmt)
+	util.Assert(os.MkdirAll(last, 0777))
+	convertAll(flag.Args()[:util.NArg()-1], last, outFmt)
 }
 
-func main() {
-	in, out := util.Arg(0), util.Arg(1)
-	r, w := ioFromFile(in, flagInFmt).r, ioFromFile(out, flagOutFmt).w
-	inf := util.OpenFile(in)
-	defer inf.Close()
+// convertAll converts each input MSA to the format given in parallel. Each
+// output file is written to `outDir` (see outputPaths). Inputs whose output
+// file would overwrite that of an earlier input are reported and skipped.
+func convertAll(ins []string, outDir string, outFmt util.MSAFormat) {
+	var w msaWriter
+	if flagStream {
+		for _, in := range ins {
+			checkStream(util.MSAFormatFromFile(in, flagInFmt), outFmt)
+		}
+	} else {
+		w = writer(outFmt)
+	}
+	outs, errs := outputPaths(ins, outDir, outFmt)
+	jobs := make(chan int)
+	progress := util.NewProgress(len(ins))
+	wg := new(sync.WaitGroup)
+	for i := 0; i < util.FlagCpu; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if flagStream {
+					progress.JobDone(convertStream(ins[i], outs[i], outFmt))
+				} else {
+					progress.JobDone(convert(ins[i], outs[i], outFmt, w))
+				}
+			}
+		}()
+	}
+	for i := range ins {
+		if errs[i] != nil {
+			progress.JobDone(errs[i])
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	progress.Close()
+}
 
-	msa, err := r(inf)
-	util.Assert(err, "Error parsing '%s'", in)
+// outputPaths returns the path of the output file of each input MSA: the
+// base name of the input in `outDir`, with its extension replaced by the
+// first extension of the output format (or the format's name if it has no
+// extensions). If an output path is the same as that of an earlier input,
+// then an error is returned for that input instead.
+func outputPaths(
+	ins []string,
+	outDir string,
+	outFmt util.MSAFormat,
+) ([]string, []error) {
+	ext := outFmt.Name
+	if len(outFmt.Exts) > 0 {
+		ext = outFmt.Exts[0]
+	}
+
+	outs := make([]string, len(ins))
+	errs := make([]error, len(ins))
+	seen := make(map[string]string, len(ins))
+	for i, in := range ins {
+		base := path.Base(in)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		outs[i] = path.Join(outDir, base+"."+ext)
+		if prev, ok := seen[outs[i]]; ok {
+			errs[i] = fmt.Errorf("Skipping '%s': its output '%s' would "+
+				"overwrite the output of '%s'.", in, outs[i], prev)
+			continue
+		}
+		seen[outs[i]] = in
+	}
+	return outs, errs
+}
 
-	outf := util.CreateFile(out)
+// msaWriter writes an MSA along with the Stockholm annotations of its input.
+// Only the stockholm output format keeps the annotations.
+type msaWriter func(w io.Writer, m seq.MSA, meta util.StockholmMeta) error
+
+// writer returns the writer for the output format given, which adds any
+// annotations requested by flags.
+func writer(outFmt util.MSAFormat) msaWriter {
+	if outFmt.Name == "stockholm" {
+		return func(w io.Writer, m seq.MSA, meta util.StockholmMeta) error {
+			var gcs []util.StockholmGC
+			if flagConsensus {
+				rf := util.StockholmGC{Feature: "RF", Annotation: consensus(m)}
+				gcs = append(gcs, rf)
+			}
+			return util.WriteStockholmMeta(w, m, meta, gcs)
+		}
+	}
+	if flagConsensus {
+		util.Warnf("The '-annotate-consensus' flag is ignored for the "+
+			"'%s' output format.", outFmt.Name)
+	}
+	return func(w io.Writer, m seq.MSA, meta util.StockholmMeta) error {
+		return outFmt.Write(w, m)
+	}
+}
+
+// convert reads the MSA at `in` and writes it to `out` with `w`. A warning is
+// emitted if Stockholm annotations of `in` are dropped by the output format.
+func convert(in, out string, outFmt util.MSAFormat, w msaWriter) error {
+	msa, err := readMSA(in)
+	if err != nil {
+		return err
+	}
+	meta, err := readMeta(in)
+	if err != nil {
+		return err
+	}
+	if meta.Len() > 0 && outFmt.Name != "stockholm" {
+		util.Warnf("The %d '#=GF' and '#=GS' annotations of '%s' are "+
+			"dropped in %s output.", meta.Len(), in, outFmt.Name)
+	}
+
+	outf, err := os.Create(out)
+	if err != nil {
+		return err
+	}
 	defer outf.Close()
-	util.Assert(w(outf, msa), "Error writing '%s'", out)
+	if err := w(outf, msa, meta); err != nil {
+		return fmt.Errorf("Error writing '%s': %s", out, err)
+	}
+	return nil
 }
 
-func ioFromFile(fpath, force string) msaIO {
-	var fmt string
-	if len(force) > 0 {
-		fmt = force
-	} else {
-		var ok bool
-		ext := path.Ext(fpath)
-		if len(ext) > 0 {
-			ext = ext[1:]
+// readMeta reads the '#=GF' and '#=GS' annotations of `in` if it is in
+// Stockholm format. Otherwise, there are no annotations.
+func readMeta(in string) (util.StockholmMeta, error) {
+	inFmt, err := util.MSAFormatDetect(in, flagInFmt)
+	if err != nil || inFmt.Name != "stockholm" {
+		return util.StockholmMeta{}, nil
+	}
+	inf, err := os.Open(in)
+	if err != nil {
+		return util.StockholmMeta{}, err
+	}
+	defer inf.Close()
+
+	meta, err := util.ReadStockholmMeta(inf)
+	if err != nil {
+		return util.StockholmMeta{}, fmt.Errorf("Error parsing '%s': %s",
+			in, err)
+	}
+	return meta, nil
+}
+
+// readMSA reads the MSA at `in` in the format given by the 'infmt' flag or
+// detected from its extension. The widths of its rows are checked.
+func readMSA(in string) (seq.MSA, error) {
+	msa, inFmt, err := readRawMSA(in)
+	if err != nil {
+		return seq.MSA{}, err
+	}
+	if err := checkWidths(&msa, hasInserts(inFmt), flagPad); err != nil {
+		return seq.MSA{}, fmt.Errorf("Invalid MSA '%s': %s", in, err)
+	}
+	return msa, nil
+}
+
+// readRawMSA is like readMSA, except the widths of rows are not checked. The
+// format of the MSA is also returned.
+func readRawMSA(in string) (seq.MSA, util.MSAFormat, error) {
+	inFmt, err := util.MSAFormatDetect(in, flagInFmt)
+	if err != nil {
+		return seq.MSA{}, inFmt, fmt.Errorf("Error reading '%s': %s", in, err)
+	}
+	inf, err := os.Open(in)
+	if err != nil {
+		return seq.MSA{}, inFmt, err
+	}
+	defer inf.Close()
+
+	// Both a2m and a3m files are read into A2M form, where insertions in
+	// one row are padded with '.' in every other row. WriteA2M keeps this
+	// padding and WriteA3M removes it, so insertion columns survive a
+	// conversion in either direction (i.e., a3m -> a2m -> a3m is idempotent).
+	msa, err := inFmt.Read(inf)
+	if err != nil {
+		return seq.MSA{}, inFmt, fmt.Errorf("Error parsing '%s': %s", in, err)
+	}
+	return msa, inFmt, nil
+}
+
+// hasInserts returns true if the format given uses lowercase letters and '.'
+// for insertions that are not part of the alignment's columns.
+func hasInserts(format util.MSAFormat) bool {
+	return format.Name == "a2m" || format.Name == "a3m"
+}
+
+// rowWidth returns the number of alignment columns in the row given. If
+// `inserts` is true, then lowercase letters and '.' are not counted.
+func rowWidth(row seq.Sequence, inserts bool) int {
+	if !inserts {
+		return row.Len()
+	}
+	return util.A3MMatchColumns(row)
+}
+
+// checkWidths returns an error if any row of the MSA has a different number
+// of columns than the first row. If `pad` is true, then rows shorter than the
+// first row are padded on the right with gaps instead.
+func checkWidths(m *seq.MSA, inserts, pad bool) error {
+	if len(m.Entries) == 0 {
+		return nil
+	}
+	first := m.Entries[0]
+	width := rowWidth(first, inserts)
+	for i := range m.Entries {
+		row := &m.Entries[i]
+		w := rowWidth(*row, inserts)
+		if w == width {
+			continue
+		}
+		if !pad || w > width {
+			return fmt.Errorf("Sequence '%s' has %d columns, but the first "+
+				"sequence '%s' has %d columns.", row.Name, w, first.Name, width)
+		}
+		for ; w < width; w++ {
+			row.Residues = append(row.Residues, '-')
 		}
+	}
+	return nil
+}
 
-		fmt, ok = extToFmt[ext]
-		if !ok {
-			util.Fatalf("Could not detect format from extension '%s'.", ext)
+// consensus returns the most common residue in each column of the MSA given.
+// Gaps are ignored, and case is not significant. Ties are broken in favor of
+// the alphabetically smallest residue. A column with only gaps is given a '.'.
+func consensus(m seq.MSA) string {
+	rows := make([]seq.Sequence, len(m.Entries))
+	ncols := 0
+	for i := range m.Entries {
+		rows[i] = m.GetFasta(i)
+		if rows[i].Len() > ncols {
+			ncols = rows[i].Len()
 		}
 	}
 
-	io, ok := fmtToIO[fmt]
-	if !ok {
-		util.Fatalf("BUG: Could not find converters for format '%s'.", fmt)
+	cons := make([]byte, ncols)
+	for c := 0; c < ncols; c++ {
+		var counts [256]int
+		for _, row := range rows {
+			if c >= row.Len() {
+				continue
+			}
+			r := byte(row.Residues[c])
+			if r == '-' || r == '.' {
+				continue
+			}
+			if r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			counts[r]++
+		}
+
+		cons[c] = '.'
+		for r, count := range counts {
+			if count > 0 && (cons[c] == '.' || count > counts[cons[c]]) {
+				cons[c] = byte(r)
+			}
+		}
 	}
-	return io
+	return string(cons)
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// msaStats summarizes the dimensions and conservation of an MSA.
+type msaStats struct {
+	Sequences, Columns int
+	GapFraction        float64
+	MeanEntropy        float64
+}
+
+// printStats prints the statistics of each MSA given to stdout, either as
+// text or as CSV if the 'csv' flag is set.
+func printStats(ins []string) {
+	var csvw *csv.Writer
+	if flagCsv {
+		csvw = csv.NewWriter(os.Stdout)
+		util.Assert(csvw.Write([]string{
+			"msa", "sequences", "columns", "gap_fraction", "mean_entropy",
+		}))
+	}
+	for i, in := range ins {
+		msa, err := readMSA(in)
+		util.Assert(err)
+		stats := computeStats(msa)
+
+		if flagCsv {
+			util.Assert(csvw.Write([]string{
+				in,
+				fmt.Sprintf("%d", stats.Sequences),
+				fmt.Sprintf("%d", stats.Columns),
+				fmt.Sprintf("%f", stats.GapFraction),
+				fmt.Sprintf("%f", stats.MeanEntropy),
+			}))
+			continue
+		}
+		if i > 0 {
+			fmt.Println("")
+		}
+		fmt.Printf("MSA: %s\n", in)
+		fmt.Printf("Sequences: %d\n", stats.Sequences)
+		fmt.Printf("Columns: %d\n", stats.Columns)
+		fmt.Printf("Gap fraction: %f\n", stats.GapFraction)
+		fmt.Printf("Mean column entropy: %f\n", stats.MeanEntropy)
+	}
+	if flagCsv {
+		csvw.Flush()
+		util.Assert(csvw.Error())
+	}
+}
+
+// computeStats computes the statistics of the MSA given. Insertions are not
+// counted as columns. The entropy (in bits) of a column is computed from its
+// residues without regard to case, ignoring gaps. Columns with only gaps are
+// not included in the mean entropy.
+func computeStats(m seq.MSA) msaStats {
+	rows := make([]seq.Sequence, len(m.Entries))
+	ncols := 0
+	for i := range m.Entries {
+		rows[i] = m.GetFasta(i)
+		if rows[i].Len() > ncols {
+			ncols = rows[i].Len()
+		}
+	}
+	stats := msaStats{Sequences: len(rows), Columns: ncols}
+	if len(rows) == 0 || ncols == 0 {
+		return stats
+	}
+
+	gaps, entropySum, nonEmpty := 0, 0.0, 0
+	for c := 0; c < ncols; c++ {
+		var counts [256]int
+		residues := 0
+		for _, row := range rows {
+			if c >= row.Len() {
+				gaps++
+				continue
+			}
+			r := byte(row.Residues[c])
+			if r == '-' || r == '.' {
+				gaps++
+				continue
+			}
+			if r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			counts[r]++
+			residues++
+		}
+		if residues == 0 {
+			continue
+		}
+
+		entropy := 0.0
+		for _, count := range counts {
+			if count > 0 {
+				p := float64(count) / float64(residues)
+				entropy -= p * math.Log2(p)
+			}
+		}
+		entropySum += entropy
+		nonEmpty++
+	}
+	stats.GapFraction = float64(gaps) / float64(len(rows)*ncols)
+	if nonEmpty > 0 {
+		stats.MeanEntropy = entropySum / float64(nonEmpty)
+	}
+	return stats
+}
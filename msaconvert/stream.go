@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// checkStream fails unless the MSA formats given can be converted one row at
+// a time.
+func checkStream(inFmt, outFmt util.MSAFormat) {
+	if !hasInserts(inFmt) {
+		util.FatalfCode(util.ExitUsage,
+			"The '-stream' flag requires a2m or a3m input, but got "+
+				"%s.", inFmt.Name)
+	}
+	if outFmt.Name != "a3m" && outFmt.Name != "fasta" {
+		util.FatalfCode(util.ExitUsage,
+			"The '-stream' flag requires a3m or fasta output, but "+
+				"got %s.", outFmt.Name)
+	}
+}
+
+// convertStream converts the A2M or A3M file at `in` to `out` one row at a
+// time with util.A3MReader. Only the a3m and fasta output formats are
+// supported. Insertions are kept in a3m output (without '.' padding) and
+// removed from fasta output.
+func convertStream(in, out string, outFmt util.MSAFormat) error {
+	inf, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer inf.Close()
+
+	outf, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+
+	r := util.NewA3MReader(inf)
+	r.Pad = flagPad
+	w := bufio.NewWriter(outf)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("Error reading '%s': %s", in, err)
+		}
+		if outFmt.Name == "fasta" {
+			row = util.A3MMatchOnly(row)
+		} else {
+			row = withoutPadding(row)
+		}
+		// Errors are sticky in a bufio.Writer, so they are caught by Flush.
+		fmt.Fprintf(w, ">%s\n", row.Name)
+		for _, r := range row.Residues {
+			w.WriteByte(byte(r))
+		}
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Error writing '%s': %s", out, err)
+	}
+	return nil
+}
+
+// withoutPadding returns the row given without the '.' characters that pad
+// insertions in A2M rows.
+func withoutPadding(row seq.Sequence) seq.Sequence {
+	residues := row.Residues[:0]
+	for _, r := range row.Residues {
+		if r != '.' {
+			residues = append(residues, r)
+		}
+	}
+	row.Residues = residues
+	return row
+}
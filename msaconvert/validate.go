@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// validateAll prints every problem found in each MSA given and exits with a
+// non-zero status if there were any.
+func validateAll(ins []string) {
+	problems := 0
+	for _, in := range ins {
+		msa, inFmt, err := readRawMSA(in)
+		if err != nil {
+			fmt.Println(err)
+			problems++
+			continue
+		}
+		for _, problem := range validate(msa, hasInserts(inFmt)) {
+			fmt.Printf("%s: %s\n", in, problem)
+			problems++
+		}
+	}
+	if problems > 0 {
+		util.Fatalf("Found %d problems.", problems)
+	}
+}
+
+// validate returns a description of every problem with the MSA given: rows
+// with a different number of columns than the first row, the first residue
+// of each row that is not a letter, gap ('-' or '.') or stop ('*'), and
+// duplicate sequence names. If `inserts` is true, insertions are not counted
+// as columns.
+func validate(m seq.MSA, inserts bool) []string {
+	var problems []string
+	if len(m.Entries) == 0 {
+		return []string{"MSA has no sequences"}
+	}
+
+	first := m.Entries[0]
+	width := rowWidth(first, inserts)
+	seen := make(map[string]int, len(m.Entries))
+	for i, row := range m.Entries {
+		if w := rowWidth(row, inserts); w != width {
+			problems = append(problems, fmt.Sprintf(
+				"sequence %d ('%s') has %d columns, but the first sequence "+
+					"'%s' has %d columns", i+1, row.Name, w, first.Name, width))
+		}
+		for j, r := range row.Residues {
+			if !legalResidue(r) {
+				problems = append(problems, fmt.Sprintf(
+					"sequence %d ('%s') has illegal residue '%c' at "+
+						"position %d", i+1, row.Name, r, j+1))
+				break
+			}
+		}
+		if prev, ok := seen[row.Name]; ok {
+			problems = append(problems, fmt.Sprintf(
+				"sequence %d has the same name as sequence %d: '%s'",
+				i+1, prev, row.Name))
+		} else {
+			seen[row.Name] = i + 1
+		}
+	}
+	return problems
+}
+
+// legalResidue returns true if the residue is a letter, gap or stop.
+func legalResidue(r seq.Residue) bool {
+	switch {
+	case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		return true
+	case r == '-' || r == '.' || r == '*':
+		return true
+	}
+	return false
+}
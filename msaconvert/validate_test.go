@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+)
+
+// testMSA returns an MSA with a row for each 'name residues' pair given.
+func testMSA(rows ...string) seq.MSA {
+	var m seq.MSA
+	for _, row := range rows {
+		fields := strings.Fields(row)
+		m.Entries = append(m.Entries,
+			seq.NewSequenceString(fields[0], fields[1]))
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		msa     seq.MSA
+		inserts bool
+		problem string // empty if the MSA is valid
+	}{
+		{"valid", testMSA("a AC-DE", "b ACWD*"), false, ""},
+		{"inserts", testMSA("a AC-DE", "b AkC-DE."), true, ""},
+		{"empty", testMSA(), false, "no sequences"},
+		{"ragged", testMSA("a ACDE", "b ACD"), false, "has 3 columns"},
+		{"ragged-inserts", testMSA("a ACDE", "b ACDkE"), false,
+			"has 5 columns"},
+		{"illegal", testMSA("a ACDE", "b AC1E"), false,
+			"illegal residue '1' at position 3"},
+		{"duplicate", testMSA("a ACDE", "a ACDE"), false, "same name"},
+	}
+	for _, test := range tests {
+		problems := validate(test.msa, test.inserts)
+		if len(test.problem) == 0 {
+			if len(problems) > 0 {
+				t.Errorf("%s: unexpected problems %q", test.name, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 {
+			t.Errorf("%s: got problems %q, want one", test.name, problems)
+		} else if !strings.Contains(problems[0], test.problem) {
+			t.Errorf("%s: got problem %q, want one containing %q",
+				test.name, problems[0], test.problem)
+		}
+	}
+}
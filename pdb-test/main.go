@@ -25,7 +25,8 @@ func main() {
 
 	if len(flagChain) > 0 {
 		if len(flagChain) != 1 {
-			util.Fatalf("Chain identifiers must be a single character.")
+			util.FatalfCode(util.ExitUsage,
+				"Chain identifiers must be a single character.")
 		}
 		chain := entry.Chain(flagChain[0])
 		if chain == nil {
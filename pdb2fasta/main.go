@@ -79,20 +79,22 @@ func main() {
 		util.Fatalf("Could not find any chains with amino acids.")
 	}
 
-	var fasOut io.Writer
+	var fasOut io.WriteCloser
 	if flag.NArg() == 1 {
 		fasOut = os.Stdout
 	} else {
 		if len(flagSplit) > 0 {
-			util.Fatalf("The '--split' option is incompatible with a single " +
-				"output file.")
+			util.FatalfCode(util.ExitUsage,
+				"The '--split' option is incompatible with a single "+
+					"output file.")
 		}
-		fasOut = util.CreateFile(util.Arg(1))
+		fasOut = util.CreateFileMaybeGz(util.Arg(1))
 	}
 
 	if len(flagSplit) == 0 {
 		util.Assert(fasta.NewWriter(fasOut).WriteAll(fasEntries),
 			"Could not write FASTA file '%s'", fasOut)
+		util.Assert(fasOut.Close(), "Could not write FASTA file")
 	} else {
 		for _, entry := range fasEntries {
 			fp := path.Join(flagSplit, fmt.Sprintf("%s.fasta", entry.Name))
@@ -5,6 +5,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"sort"
 
 	"github.com/TuftsBCB/io/pdb/slct"
 	"github.com/ndaniels/tools/util"
@@ -12,6 +13,8 @@ import (
 
 var (
 	flagPaths = false
+	flagSort  = false
+	flagUniq  = false
 )
 
 func init() {
@@ -19,6 +22,12 @@ func init() {
 		"When set, the full path of each PDB chain identifier will be\n"+
 			"displayed, based on the value of the PDB_PATH environment\n"+
 			"variable.")
+	flag.BoolVar(&flagSort, "sort", flagSort,
+		"When set, the PDB chain identifiers are sorted before they are\n"+
+			"displayed (or resolved with '-paths').")
+	flag.BoolVar(&flagUniq, "uniq", flagUniq,
+		"When set, only the first occurrence of each PDB chain identifier\n"+
+			"is displayed.")
 
 	util.FlagParse("pdb-select-file",
 		"Given a file in the PDB Select format, output a list of PDB chain "+
@@ -33,11 +42,26 @@ func main() {
 	entries, err := slct.NewReader(pdbs).ReadAll()
 	util.Assert(err)
 
+	ids := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
 	for _, entry := range entries {
+		if flagUniq {
+			if seen[entry.ChainID] {
+				continue
+			}
+			seen[entry.ChainID] = true
+		}
+		ids = append(ids, entry.ChainID)
+	}
+	if flagSort {
+		sort.Strings(ids)
+	}
+
+	for _, id := range ids {
 		if flagPaths {
-			fmt.Println(util.PDBPath(entry.ChainID))
+			fmt.Println(util.PDBPath(id))
 		} else {
-			fmt.Println(entry.ChainID)
+			fmt.Println(id)
 		}
 	}
 }
@@ -3,9 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"math/rand"
 	"strings"
-	"time"
 
 	"github.com/ndaniels/tools/util"
 )
@@ -20,10 +18,8 @@ func init() {
 	flag.BoolVar(&flagPaths, "paths", flagPaths,
 		"When set, full file paths will be echoed instead of PDB ids.")
 
-	util.FlagUse("pdb-dir")
+	util.FlagUse("pdb-dir", "seed")
 	util.FlagParse("", "")
-
-	rand.Seed(time.Now().UnixNano())
 }
 
 func main() {
@@ -33,7 +29,7 @@ func main() {
 		var index int = -1
 		for index == -1 || !util.IsPDB(pdbFiles[index]) {
 			// not guaranteed to terminate O_O
-			index = rand.Intn(len(pdbFiles))
+			index = util.Rand.Intn(len(pdbFiles))
 		}
 		files = append(files, pdbFiles[index])
 		pdbFiles = append(pdbFiles[:index], pdbFiles[index+1:]...)
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/tools/util"
+)
+
+// hydroLib is a sequence library of three fragments of one residue:
+// fragment 0 matches 'H', fragment 1 matches masked residues and fragment 2
+// matches everything else.
+type hydroLib struct{}
+
+func (hydroLib) Save(w io.Writer) error      { return nil }
+func (hydroLib) Size() int                   { return 3 }
+func (hydroLib) FragmentSize() int           { return 1 }
+func (hydroLib) String() string              { return "hydro" }
+func (hydroLib) Name() string                { return "hydro" }
+func (hydroLib) Tag() string                 { return "sequence" }
+func (hydroLib) Fragment(i int) interface{}  { return nil }
+func (hydroLib) SubLibrary() fragbag.Library { return nil }
+
+func (hydroLib) BestSequenceFragment(s seq.Sequence) int {
+	switch s.Residues[0] {
+	case 'H':
+		return 0
+	case util.MaskResidue:
+		return 1
+	}
+	return 2
+}
+
+func TestRangeBowAlphabet(t *testing.T) {
+	s := seq.NewSequenceString("test", "KAVLDEGW")
+	freqs := func() []float32 {
+		return rangeBow(hydroLib{}, s, "test", 1, 7).Bow.Freqs
+	}
+
+	if got, want := freqs(), []float32{0, 0, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unmapped BOW = %v, want %v", got, want)
+	}
+
+	util.FlagAlphabet = util.Alphabet{'A': 'H', 'V': 'H', 'L': 'H', 'W': 'H'}
+	defer func() { util.FlagAlphabet = nil }()
+	if got, want := freqs(), []float32{3, 0, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remapped BOW = %v, want %v", got, want)
+	}
+	if b := rangeBow(hydroLib{}, s, "test", 1, 7); b.Id != "test/1-7" {
+		t.Errorf("BOW id = %q, want %q", b.Id, "test/1-7")
+	}
+}
+
+func TestRangeBowMask(t *testing.T) {
+	s := seq.NewSequenceString("test", "MKTAYIakqrQISFVKSHFSRQQQQQQQQQQQQ")
+	bowOf := func() []float32 {
+		return rangeBow(hydroLib{}, s, "test", 0, s.Len()).Bow.Freqs
+	}
+
+	unmasked := bowOf()
+	if want := []float32{1, 0, 32}; !reflect.DeepEqual(unmasked, want) {
+		t.Errorf("unmasked BOW = %v, want %v", unmasked, want)
+	}
+
+	defer func() { util.FlagMask = nil }()
+	util.FlagMask = util.MaskLowercase
+	lower := bowOf()
+	if want := []float32{1, 4, 28}; !reflect.DeepEqual(lower, want) {
+		t.Errorf("'-mask lower' BOW = %v, want %v", lower, want)
+	}
+
+	util.FlagMask, _ = util.ParseMasker("seg")
+	got := bowOf()
+	if got[1] < lower[1]+12 {
+		t.Errorf("'-mask seg' masked %v residues, want at least %v "+
+			"(lowercase and poly-Q)", got[1], lower[1]+12)
+	}
+	if got[0]+got[1]+got[2] != unmasked[0]+unmasked[2] {
+		t.Errorf("'-mask seg' BOW = %v, want %v windows", got,
+			unmasked[0]+unmasked[2])
+	}
+}
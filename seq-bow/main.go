@@ -0,0 +1,105 @@
+// Command seq-bow computes the BOW of a range of residues of a sequence in a
+// FASTA file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/TuftsBCB/io/fasta"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+	"github.com/ndaniels/tools/util"
+)
+
+var flagText = false
+
+func init() {
+	flag.BoolVar(&flagText, "text", flagText,
+		"When set, the BOW is written in a plain text format that can be\n"+
+			"read by 'bow-dist'. If 'out-bow' is '--', the text format is\n"+
+			"printed to stdout.")
+}
+
+func main() {
+	util.FlagUse("sparse", "norms", "alphabet", "mask")
+	util.FlagParse("seq-frag-lib fasta-file name start end out-bow",
+		"Computes and outputs a BOW file for the residues [start, end) of\n"+
+			"the sequence named 'name' in 'fasta-file', where 'start' and\n"+
+			"'end' are residue indices starting at 0. 'name' must match\n"+
+			"either the full header of a sequence or its first word. If\n"+
+			"several sequences match, the first is used. The BOW is labeled\n"+
+			"'name/start-end'. If 'out-bow' is '--', then a human readable\n"+
+			"version of the BOW will be printed to stdout instead.")
+	util.AssertNArg(6)
+
+	lib := util.SequenceLibrary(util.Arg(0))
+	fastaPath, name := util.Arg(1), util.Arg(2)
+	start, end := util.ParseInt(util.Arg(3)), util.ParseInt(util.Arg(4))
+	bowOut := util.Arg(5)
+
+	s, ok := findSequence(fastaPath, name)
+	if !ok {
+		util.Fatalf("Could not find a sequence named '%s' in '%s'.",
+			name, fastaPath)
+	}
+	if start < 0 || end > s.Len() || start >= end {
+		util.FatalfCode(util.ExitUsage,
+			"Invalid range [%d, %d) for sequence '%s' with %d residues.",
+			start, end, name, s.Len())
+	}
+	util.Assert(util.CheckFragmentSize(lib, end-start),
+		"Range [%d, %d) of '%s' is too short", start, end, name)
+	b := rangeBow(lib, s, name, start, end)
+
+	if flagText {
+		if bowOut == "--" {
+			util.BowWriteText(os.Stdout, lib, b)
+		} else {
+			out := util.CreateFile(bowOut)
+			util.BowWriteText(out, lib, b)
+			util.Assert(out.Close())
+		}
+	} else if bowOut == "--" {
+		fmt.Println(b)
+	} else {
+		util.BowWrite(util.CreateFile(bowOut), lib, b)
+	}
+}
+
+// rangeBow returns the BOW of the residues [start, end) of `s`, labeled
+// 'name/start-end'. The residues are passed through util.BowSequence first.
+func rangeBow(
+	lib fragbag.SequenceLibrary,
+	s seq.Sequence,
+	name string,
+	start, end int,
+) bow.Bowed {
+	sub := seq.Sequence{
+		Name:     fmt.Sprintf("%s/%d-%d", name, start, end),
+		Residues: s.Residues[start:end],
+	}
+	return bow.BowerFromSequence(util.BowSequence(sub)).SequenceBow(lib)
+}
+
+// findSequence returns the first sequence in the FASTA file at `fpath` whose
+// header or first word of its header is `name`.
+func findSequence(fpath, name string) (seq.Sequence, bool) {
+	r := fasta.NewReader(util.OpenFasta(fpath))
+	for {
+		s, err := r.Read()
+		if err == io.EOF {
+			return seq.Sequence{}, false
+		}
+		util.AssertCode(util.ExitParse, err, "Could not read '%s'", fpath)
+
+		fields := strings.Fields(s.Name)
+		if s.Name == name || (len(fields) > 0 && fields[0] == name) {
+			return s, true
+		}
+	}
+}
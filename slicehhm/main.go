@@ -1,26 +1,67 @@
 package main
 
 import (
+	"flag"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/TuftsBCB/io/hmm"
 	"github.com/ndaniels/tools/util"
 )
 
+var flagResnum = ""
+
 func init() {
-	util.FlagParse("hhm-file start end", "")
-	util.AssertNArg(3)
+	flag.StringVar(&flagResnum, "resnum", flagResnum,
+		"When set to 'pdb-file:chain-id', 'start' and 'end' are inclusive\n"+
+			"PDB residue numbers (with optional insertion codes, e.g.,\n"+
+			"'52A') of that chain, which must be the chain the HHM was\n"+
+			"built from. Each residue is mapped to its match state.")
+
+	util.FlagParse("hhm-file start end [out-hhm-file]",
+		"Writes the slice of an HHM from start to end to stdout, or to\n"+
+			"'out-hhm-file' if given. Either file may be gzip compressed,\n"+
+			"and the output is compressed if its name ends with '.gz'.")
+	if util.NArg() != 3 && util.NArg() != 4 {
+		util.Usage()
+	}
 }
 
 func main() {
 	hhmFile := util.Arg(0)
-	start := util.ParseInt(util.Arg(1))
-	end := util.ParseInt(util.Arg(2))
 
-	fhhm := util.OpenFile(hhmFile)
+	fhhm := util.OpenMaybeGz(hhmFile)
+	defer fhhm.Close()
 
 	qhhm, err := hmm.ReadHHM(fhhm)
 	util.Assert(err)
 
-	util.Assert(hmm.WriteHHM(os.Stdout, qhhm.Slice(start, end)))
+	var start, end int
+	if len(flagResnum) > 0 {
+		pieces := strings.Split(flagResnum, ":")
+		if len(pieces) != 2 || len(pieces[1]) != 1 {
+			util.FatalfCode(util.ExitUsage,
+				"Expected 'pdb-file:chain-id' for '-resnum' but "+
+					"got '%s'.", flagResnum)
+		}
+		states := matchStates(qhhm, pieces[0], pieces[1][0])
+		start = states.index(util.Arg(1))
+		end = states.index(util.Arg(2)) + 1
+		if start >= end {
+			util.FatalfCode(util.ExitUsage,
+				"Residue '%s' does not come before residue '%s'.",
+				util.Arg(1), util.Arg(2))
+		}
+	} else {
+		start = util.ParseInt(util.Arg(1))
+		end = util.ParseInt(util.Arg(2))
+	}
+
+	var out io.WriteCloser = os.Stdout
+	if util.NArg() == 4 {
+		out = util.CreateFileMaybeGz(util.Arg(3))
+	}
+	util.Assert(hmm.WriteHHM(out, qhhm.Slice(start, end)))
+	util.Assert(out.Close())
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/TuftsBCB/io/hmm"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/tools/util"
+)
+
+// residueStates maps PDB residue numbers (with insertion codes) of a chain
+// to the match state index (starting at 0) of an HHM.
+type residueStates struct {
+	chain  byte
+	states map[string]int
+}
+
+// index returns the match state of the residue number given. If there is no
+// such residue, the program quits.
+func (rs residueStates) index(resnum string) int {
+	i, ok := rs.states[resnum]
+	if !ok {
+		util.Fatalf("Residue '%s' of chain '%c' has no match state. (Either "+
+			"the residue does not exist or it has no alpha-carbon atom.)",
+			resnum, rs.chain)
+	}
+	return i
+}
+
+// matchStates maps the residues of a chain to the match states of an HHM
+// built from that chain's sequence. Each residue with an alpha-carbon atom
+// in the chain's first model corresponds to one match state, in order.
+//
+// The query sequence of the HHM (with gaps removed) must have the same
+// length as the chain, and the residues must agree (except for unknown
+// residues). Otherwise, the program quits.
+func matchStates(qhhm *hmm.HHM, pdbFile string, chainId byte) residueStates {
+	entry := util.PDBRead(pdbFile)
+	chain := entry.Chain(chainId)
+	if chain == nil || len(chain.Models) == 0 {
+		util.Fatalf("Could not find chain '%c' in '%s'.", chainId, pdbFile)
+	}
+
+	nums := make([]string, 0)
+	letters := make([]seq.Residue, 0)
+	for _, r := range chain.Models[0].Residues {
+		for _, atom := range r.Atoms {
+			if atom.Name != "CA" {
+				continue
+			}
+			num := strconv.Itoa(r.SequenceNum)
+			if r.InsertionCode != ' ' && r.InsertionCode != 0 {
+				num += string(r.InsertionCode)
+			}
+			nums = append(nums, num)
+			letters = append(letters, r.Name)
+			break
+		}
+	}
+
+	if len(qhhm.MSA.Entries) == 0 {
+		util.Fatalf("The HHM has no query sequence.")
+	}
+	query := make([]seq.Residue, 0)
+	for _, r := range qhhm.MSA.GetFasta(0).Residues {
+		if r != '-' && r != '.' {
+			query = append(query, r)
+		}
+	}
+	if len(query) != len(letters) {
+		util.Fatalf("The HHM has %d match states, but chain '%c' in '%s' "+
+			"has %d residues with alpha-carbon atoms.",
+			len(query), chainId, pdbFile, len(letters))
+	}
+	for i := range query {
+		if query[i] != letters[i] && query[i] != 'X' && letters[i] != 'X' {
+			util.Fatalf("Match state %d of the HHM is '%c', but residue "+
+				"'%s' of chain '%c' is '%c'.",
+				i+1, query[i], nums[i], chainId, letters[i])
+		}
+	}
+
+	rs := residueStates{chain: chainId, states: make(map[string]int)}
+	for i, num := range nums {
+		rs.states[num] = i
+	}
+	return rs
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/TuftsBCB/seq"
+)
+
+// A3MReader reads the rows of an A3M (or A2M) file one at a time, so that
+// memory use is bounded by the size of the largest row rather than the size
+// of the whole MSA (as it is with msa.Read). Rows are returned as they appear
+// in the file, including lowercase insertions.
+//
+// Every row must have the same number of match columns (uppercase letters
+// and '-') as the first row, or else an error is returned. Lines before the
+// first header (e.g., '#A3M#') are skipped.
+type A3MReader struct {
+	// When set, rows with fewer match columns than the first row are padded
+	// on the right with gaps instead of causing an error.
+	Pad bool
+
+	r       *bufio.Reader
+	next    string // header of the next row, read while reading the last row
+	hasNext bool
+	width   int // match columns of the first row, or -1 if none read yet
+	rows    int
+	done    bool
+}
+
+// NewA3MReader returns a reader for the A3M rows in `r`.
+func NewA3MReader(r io.Reader) *A3MReader {
+	return &A3MReader{r: bufio.NewReader(r), width: -1}
+}
+
+// Read returns the next row of the MSA. io.EOF is returned when there are no
+// more rows.
+func (ar *A3MReader) Read() (seq.Sequence, error) {
+	if ar.done {
+		return seq.Sequence{}, io.EOF
+	}
+	for !ar.hasNext {
+		line, err := ar.line()
+		if err == io.EOF {
+			ar.done = true
+			return seq.Sequence{}, io.EOF
+		} else if err != nil {
+			return seq.Sequence{}, err
+		}
+		if len(line) > 0 && line[0] == '>' {
+			ar.next, ar.hasNext = string(line[1:]), true
+		}
+	}
+
+	s := seq.Sequence{Name: ar.next}
+	ar.hasNext = false
+	for {
+		line, err := ar.line()
+		if err == io.EOF {
+			ar.done = true
+			break
+		} else if err != nil {
+			return seq.Sequence{}, err
+		}
+		if len(line) > 0 && line[0] == '>' {
+			ar.next, ar.hasNext = string(line[1:]), true
+			break
+		}
+		for _, b := range line {
+			s.Residues = append(s.Residues, seq.Residue(b))
+		}
+	}
+	ar.rows++
+	if err := ar.checkWidth(&s); err != nil {
+		return seq.Sequence{}, err
+	}
+	return s, nil
+}
+
+// checkWidth records the match columns of the first row and checks every
+// other row against it.
+func (ar *A3MReader) checkWidth(s *seq.Sequence) error {
+	w := A3MMatchColumns(*s)
+	if ar.width < 0 {
+		ar.width = w
+		return nil
+	}
+	if w == ar.width {
+		return nil
+	}
+	if !ar.Pad || w > ar.width {
+		return fmt.Errorf("Sequence '%s' (row %d) has %d columns, but the "+
+			"first sequence has %d columns.", s.Name, ar.rows, w, ar.width)
+	}
+	for ; w < ar.width; w++ {
+		s.Residues = append(s.Residues, '-')
+	}
+	return nil
+}
+
+// line returns the next line without surrounding whitespace. The slice
+// returned is only valid until the next call.
+func (ar *A3MReader) line() ([]byte, error) {
+	line, err := ar.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// Long rows are split over several reads.
+		buf := append([]byte(nil), line...)
+		for err == bufio.ErrBufferFull {
+			line, err = ar.r.ReadSlice('\n')
+			buf = append(buf, line...)
+		}
+		line = buf
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(line), nil
+}
+
+// A3MMatchColumns returns the number of match columns in an A3M or A2M row,
+// which excludes lowercase insertions and '.'.
+func A3MMatchColumns(s seq.Sequence) int {
+	width := 0
+	for _, r := range s.Residues {
+		if r != '.' && (r < 'a' || r > 'z') {
+			width++
+		}
+	}
+	return width
+}
+
+// A3MMatchOnly returns a copy of the row given without insertions. That is,
+// only match columns remain.
+func A3MMatchOnly(s seq.Sequence) seq.Sequence {
+	match := seq.Sequence{
+		Name:     s.Name,
+		Residues: make([]seq.Residue, 0, len(s.Residues)),
+	}
+	for _, r := range s.Residues {
+		if r != '.' && (r < 'a' || r > 'z') {
+			match.Residues = append(match.Residues, r)
+		}
+	}
+	return match
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/TuftsBCB/io/msa"
+	"github.com/TuftsBCB/seq"
+)
+
+// readA3M returns every row read from the A3M data given.
+func readA3M(data string, pad bool) ([]seq.Sequence, error) {
+	r := NewA3MReader(strings.NewReader(data))
+	r.Pad = pad
+	var rows []seq.Sequence
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestA3MReader(t *testing.T) {
+	// The insertion is longer than the default bufio buffer, so the row is
+	// read over several calls to ReadSlice.
+	long := strings.Repeat("k", 10000)
+	data := "#A3M#\n" +
+		">query\nMKV-\nLA\n" +
+		">hit1\nMK" + long + "V-LA\n" +
+		">hit2 description\n-KvV-LA\n" +
+		">hit3\nMK..V-LA\n"
+	rows, err := readA3M(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name     string
+		residues string
+	}{
+		{"query", "MKV-LA"},
+		{"hit1", "MK" + long + "V-LA"},
+		{"hit2 description", "-KvV-LA"},
+		{"hit3", "MK..V-LA"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].Name != w.name {
+			t.Errorf("row %d name = %q, want %q", i, rows[i].Name, w.name)
+		}
+		if got := fmt.Sprintf("%s", rows[i].Residues); got != w.residues {
+			t.Errorf("row %d residues = %.20q..., want %.20q...",
+				i, got, w.residues)
+		}
+		if n := A3MMatchColumns(rows[i]); n != 6 {
+			t.Errorf("row %d has %d match columns, want 6", i, n)
+		}
+	}
+	match := A3MMatchOnly(rows[2])
+	if got := fmt.Sprintf("%s", match.Residues); got != "-KV-LA" {
+		t.Errorf("match columns of row 2 = %q, want %q", got, "-KV-LA")
+	}
+}
+
+func TestA3MReaderWidth(t *testing.T) {
+	short := ">query\nMKVLA\n>hit\nMKv\n"
+	if _, err := readA3M(short, false); err == nil {
+		t.Errorf("a short row should be an error without padding")
+	}
+	rows, err := readA3M(short, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%s", rows[1].Residues); got != "MKv---" {
+		t.Errorf("padded row = %q, want %q", got, "MKv---")
+	}
+
+	long := ">query\nMKV\n>hit\nMKVLA\n"
+	if _, err := readA3M(long, true); err == nil {
+		t.Errorf("a long row should be an error, even with padding")
+	}
+}
+
+// benchA3M returns an A3M file whose rows have long insertions.
+func benchA3M(rows, inserts int) []byte {
+	buf := new(bytes.Buffer)
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(buf, ">row%d\n%s%s%s\n", i,
+			strings.Repeat("A", 50), strings.Repeat("k", inserts),
+			strings.Repeat("V", 50))
+	}
+	return buf.Bytes()
+}
+
+// liveHeap returns the bytes of heap in use after a garbage collection.
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// BenchmarkA3MPeakMemory reports the most heap in use while reading an A3M
+// file with msa.Read and with A3MReader, as "peak-bytes".
+func BenchmarkA3MPeakMemory(b *testing.B) {
+	data := benchA3M(300, 4000)
+
+	b.Run("msa.Read", func(b *testing.B) {
+		peak := uint64(0)
+		for i := 0; i < b.N; i++ {
+			base := liveHeap()
+			m, err := msa.Read(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if used := liveHeap() - base; used > peak {
+				peak = used
+			}
+			runtime.KeepAlive(m)
+		}
+		b.ReportMetric(float64(peak), "peak-bytes")
+	})
+	b.Run("A3MReader", func(b *testing.B) {
+		peak := uint64(0)
+		for i := 0; i < b.N; i++ {
+			base := liveHeap()
+			r := NewA3MReader(bytes.NewReader(data))
+			for n := 0; ; n++ {
+				row, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					b.Fatal(err)
+				}
+				if n%50 != 0 {
+					continue
+				}
+				if used := liveHeap() - base; used > peak {
+					peak = used
+				}
+				runtime.KeepAlive(row)
+			}
+		}
+		b.ReportMetric(float64(peak), "peak-bytes")
+	})
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// Aggregation modes for combining the BOWs of the chains of an entry.
+const (
+	AggregateChain = "chain"
+	AggregateEntry = "entry"
+)
+
+// AggregateBows returns a single BOW labeled `id` whose frequencies are the
+// element-wise sum of the frequencies of the BOWs given. The data of the
+// result is taken from the first BOW. An error is returned if no BOWs are
+// given or if they have different lengths.
+func AggregateBows(id string, bs []bow.Bowed) (bow.Bowed, error) {
+	if len(bs) == 0 {
+		return bow.Bowed{}, fmt.Errorf("no BOWs to aggregate for '%s'", id)
+	}
+	size := len(bs[0].Bow.Freqs)
+	sum := bow.NewBow(size)
+	for _, b := range bs {
+		if len(b.Bow.Freqs) != size {
+			return bow.Bowed{}, fmt.Errorf("BOW '%s' has %d fragments, but "+
+				"BOW '%s' has %d", b.Id, len(b.Bow.Freqs), bs[0].Id, size)
+		}
+		sum = sum.Add(b.Bow)
+	}
+	return bow.Bowed{Id: id, Data: bs[0].Data, Bow: sum}, nil
+}
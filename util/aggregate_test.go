@@ -0,0 +1,39 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+func TestAggregateBows(t *testing.T) {
+	chains := []bow.Bowed{
+		{Id: "1abcA", Bow: bow.Bow{Freqs: []float32{1, 0, 2, 0}}},
+		{Id: "1abcB", Bow: bow.Bow{Freqs: []float32{0, 3, 1, 0}}},
+		{Id: "1abcC", Bow: bow.Bow{Freqs: []float32{4, 1, 0, 0}}},
+	}
+	agg, err := AggregateBows("1abc", chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agg.Id != "1abc" {
+		t.Errorf("aggregate id = %q, want %q", agg.Id, "1abc")
+	}
+	want := bow.Bow{Freqs: []float32{5, 4, 3, 0}}
+	if !agg.Bow.Equal(want) {
+		t.Errorf("aggregate = %v, want %v", agg.Bow.Freqs, want.Freqs)
+	}
+
+	// The BOWs given must not be modified.
+	if !chains[0].Bow.Equal(bow.Bow{Freqs: []float32{1, 0, 2, 0}}) {
+		t.Errorf("first BOW was modified: %v", chains[0].Bow.Freqs)
+	}
+
+	if _, err := AggregateBows("1abc", nil); err == nil {
+		t.Errorf("aggregating no BOWs should fail")
+	}
+	short := bow.Bowed{Id: "1abcD", Bow: bow.Bow{Freqs: []float32{1}}}
+	if _, err := AggregateBows("1abc", append(chains, short)); err == nil {
+		t.Errorf("aggregating BOWs of different lengths should fail")
+	}
+}
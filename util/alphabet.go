@@ -0,0 +1,65 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/TuftsBCB/seq"
+)
+
+// Alphabet maps residues to the residues of a (usually reduced) alphabet.
+// Residues without a mapping are left unchanged.
+type Alphabet map[seq.Residue]seq.Residue
+
+// ReadAlphabet reads an alphabet from the file at `path`. Each line has the
+// form 'residues residue', where each of the residues in the first field is
+// mapped to the residue in the second field. For example, the line
+// 'AVLIMFWC H' maps each hydrophobic amino acid to 'H'. Mappings are case
+// insensitive. Blank lines and lines starting with '#' are ignored.
+func ReadAlphabet(path string) Alphabet {
+	f := OpenFile(path)
+	defer f.Close()
+
+	alpha := make(Alphabet)
+	for i, line := range ReadLines(f) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[1]) != 1 {
+			FatalfCode(ExitParse, "Expected 'residues residue' on line %d "+
+				"in '%s' but got '%s'.", i+1, path, line)
+		}
+		to := seq.Residue(strings.ToUpper(fields[1])[0])
+		for _, from := range strings.ToUpper(fields[0]) {
+			if _, ok := alpha[seq.Residue(from)]; ok {
+				FatalfCode(ExitParse, "Residue '%c' is mapped more than "+
+					"once in '%s'.", from, path)
+			}
+			alpha[seq.Residue(from)] = to
+			if from >= 'A' && from <= 'Z' {
+				alpha[seq.Residue(from-'A'+'a')] = to
+			}
+		}
+	}
+	return alpha
+}
+
+// Apply returns a copy of the sequence given with each residue remapped
+// through the alphabet. If the alphabet is nil, the sequence is returned
+// unchanged.
+func (alpha Alphabet) Apply(s seq.Sequence) seq.Sequence {
+	if alpha == nil {
+		return s
+	}
+	residues := make([]seq.Residue, len(s.Residues))
+	for i, r := range s.Residues {
+		if to, ok := alpha[r]; ok {
+			residues[i] = to
+		} else {
+			residues[i] = r
+		}
+	}
+	s.Residues = residues
+	return s
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+)
+
+func TestAlphabet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alphabet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "hp.txt")
+	contents := "# hydrophobic and polar\nAVLIMFWC H\n\nstnqde p\n"
+	if err := ioutil.WriteFile(fpath, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	alpha := ReadAlphabet(fpath)
+
+	s := seq.NewSequenceString("test", "AvKSdG")
+	got := string(residueBytes(alpha.Apply(s)))
+	if got != "HHKPPG" {
+		t.Errorf("Apply = %q, want %q", got, "HHKPPG")
+	}
+	if orig := string(residueBytes(s)); orig != "AvKSdG" {
+		t.Errorf("Apply changed its input to %q", orig)
+	}
+	if got := string(residueBytes(Alphabet(nil).Apply(s))); got != "AvKSdG" {
+		t.Errorf("nil alphabet changed the sequence to %q", got)
+	}
+}
+
+func residueBytes(s seq.Sequence) []byte {
+	bs := make([]byte, len(s.Residues))
+	for i, r := range s.Residues {
+		bs[i] = byte(r)
+	}
+	return bs
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"github.com/TuftsBCB/io/pdb"
+)
+
+// Ways of handling atoms with alternate locations (altlocs).
+const (
+	// AltLocFirst keeps only the first location of each atom in a residue,
+	// which is the 'A' location (or the only location) in files from the
+	// PDB.
+	AltLocFirst = "first"
+
+	// AltLocAll keeps every location of every atom, so a residue may have
+	// more than one alpha-carbon atom.
+	AltLocAll = "all"
+)
+
+// SelectAltLocs removes alternate locations of atoms from every residue of
+// the entry given according to FlagAltLoc. Since the PDB reader does not
+// keep altloc identifiers, an alternate location is recognized as an atom
+// with the same name as an earlier atom in the same residue. Entries are
+// left unchanged in tools that do not use the 'altloc' flag.
+//
+// This must be done before any alpha-carbon atoms are read from the entry.
+// Otherwise, residues with alternate conformations contribute more than one
+// alpha-carbon atom to methods like `CaAtoms`, which biases BOWs and best
+// fragments.
+func SelectAltLocs(entry *pdb.Entry) {
+	if !commonFlags["altloc"].use || FlagAltLoc == AltLocAll {
+		return
+	}
+	for _, chain := range entry.Chains {
+		for _, model := range chain.Models {
+			for _, r := range model.Residues {
+				r.Atoms = firstAltLocs(r.Atoms)
+			}
+		}
+	}
+}
+
+// firstAltLocs returns the first atom with each name in the order given.
+func firstAltLocs(atoms []pdb.Atom) []pdb.Atom {
+	kept := atoms[:0]
+	for _, atom := range atoms {
+		dup := false
+		for _, k := range kept {
+			if k.Name == atom.Name {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			kept = append(kept, atom)
+		}
+	}
+	return kept
+}
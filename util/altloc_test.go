@@ -0,0 +1,83 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// xLib is a structure library of two fragments of one alpha-carbon atom:
+// fragment 0 matches atoms with X < 10 and fragment 1 matches the rest.
+type xLib struct{ alaLib }
+
+func (xLib) BestStructureFragment(atoms []structure.Coords) int {
+	if atoms[0].X < 10 {
+		return 0
+	}
+	return 1
+}
+
+func (xLib) Atoms(i int) []structure.Coords { return nil }
+
+// altLocEntry returns an entry with one chain of three residues, where the
+// second residue has an alternate location for each of its atoms.
+func altLocEntry() *pdb.Entry {
+	atoms := func(x ...float64) []pdb.Atom {
+		var as []pdb.Atom
+		for _, xi := range x {
+			as = append(as, pdb.Atom{Name: "N"},
+				pdb.Atom{Name: "CA", Coords: structure.Coords{X: xi}})
+		}
+		return as
+	}
+	entry := &pdb.Entry{IdCode: "1alt"}
+	chain := &pdb.Chain{Entry: entry, Ident: 'A'}
+	model := &pdb.Model{Entry: entry, Chain: chain, Num: 1}
+	model.Residues = []*pdb.Residue{
+		{Name: 'A', SequenceNum: 1, Atoms: atoms(1)},
+		{Name: 'K', SequenceNum: 2, Atoms: atoms(2, 20)},
+		{Name: 'G', SequenceNum: 3, Atoms: atoms(3)},
+	}
+	chain.Models = []*pdb.Model{model}
+	entry.Chains = []*pdb.Chain{chain}
+	return entry
+}
+
+func TestSelectAltLocs(t *testing.T) {
+	freqs := func(entry *pdb.Entry) []float32 {
+		b := bow.BowerFromChain(entry.Chains[0]).StructureBow(xLib{})
+		return b.Bow.Freqs
+	}
+
+	// Tools that do not use the flag see every atom.
+	entry := altLocEntry()
+	SelectAltLocs(entry)
+	want := []float32{3, 1}
+	if got := freqs(entry); !reflect.DeepEqual(got, want) {
+		t.Errorf("without '-altloc', BOW = %v, want %v", got, want)
+	}
+
+	defer func() {
+		commonFlags["altloc"].use = false
+		FlagAltLoc = AltLocFirst
+	}()
+	commonFlags["altloc"].use = true
+	for _, test := range []struct {
+		altloc string
+		freqs  []float32
+	}{
+		{AltLocFirst, []float32{3, 0}},
+		{AltLocAll, []float32{3, 1}},
+	} {
+		FlagAltLoc = test.altloc
+		entry := altLocEntry()
+		SelectAltLocs(entry)
+		if got := freqs(entry); !reflect.DeepEqual(got, test.freqs) {
+			t.Errorf("'-altloc %s': BOW = %v, want %v",
+				test.altloc, got, test.freqs)
+		}
+	}
+}
@@ -1,13 +1,65 @@
 package util
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
-func printf(format string, v ...interface{}) {
+// LogLevel corresponds to the minimum severity of messages that are emitted
+// to stderr. Fatal errors are always emitted.
+type LogLevel int
+
+const (
+	LogError LogLevel = iota
+	LogWarn
+	LogInfo
+	LogDebug
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug"}
+
+func (lvl LogLevel) String() string {
+	if lvl < LogError || lvl > LogDebug {
+		return fmt.Sprintf("LogLevel(%d)", int(lvl))
+	}
+	return logLevelNames[lvl]
+}
+
+// ParseLogLevel returns the log level with the name given. Legal names are
+// "error", "warn", "info" and "debug".
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, lvlName := range logLevelNames {
+		if name == lvlName {
+			return LogLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown log level '%s'. Legal values are %s.",
+		name, strings.Join(logLevelNames, ", "))
+}
+
+// Fields corresponds to a set of named values attached to a structured log
+// record.
+type Fields map[string]interface{}
+
+// logJSON is true when the TOOLS_LOG_JSON environment variable is set to 1.
+// In that case, warnings and diagnostic output are emitted to stderr as one
+// JSON object per line with "level" and "message" keys (plus a "fields" key
+// if any fields were given). Terminal redraws (e.g., the progress bar) are
+// not emitted at all.
+var logJSON = os.Getenv("TOOLS_LOG_JSON") == "1"
+
+func printf(lvl LogLevel, fields Fields, format string, v ...interface{}) {
+	if lvl > FlagLogLevel {
+		return
+	}
+	if logJSON {
+		printJSON(lvl.String(), fields, format, v...)
+		return
+	}
 	if len(format) > 0 && format[0] == '\r' {
 		fmt.Fprintf(os.Stderr, format, v...)
 	} else {
@@ -15,14 +67,46 @@ func printf(format string, v ...interface{}) {
 	}
 }
 
-func Verbosef(format string, v ...interface{}) {
-	if !FlagQuiet {
-		printf(format, v...)
+func printJSON(level string, fields Fields, format string, v ...interface{}) {
+	if len(format) > 0 && format[0] == '\r' {
+		return
+	}
+	msg := strings.TrimSpace(fmt.Sprintf(format, v...))
+	if len(msg) == 0 {
+		return
+	}
+
+	record := map[string]interface{}{"level": level, "message": msg}
+	if len(fields) > 0 {
+		record["fields"] = fields
+	}
+	bs, err := json.Marshal(record)
+	if err != nil {
+		bs, _ = json.Marshal(map[string]interface{}{
+			"level":   level,
+			"message": fmt.Sprintf("%s (could not encode fields: %s)", msg, err),
+		})
 	}
+	fmt.Fprintf(os.Stderr, "%s\n", bs)
+}
+
+func Debugf(format string, v ...interface{}) {
+	printf(LogDebug, nil, format, v...)
+}
+
+func Verbosef(format string, v ...interface{}) {
+	printf(LogInfo, nil, format, v...)
 }
 
 func Warnf(format string, v ...interface{}) {
-	printf(format, v...)
+	printf(LogWarn, nil, format, v...)
+}
+
+// WarnFieldsf is like Warnf, except the fields given are included in the
+// record when structured logging is enabled. Otherwise, the fields are
+// ignored.
+func WarnFieldsf(fields Fields, format string, v ...interface{}) {
+	printf(LogWarn, fields, format, v...)
 }
 
 func Warning(err error, v ...interface{}) bool {
@@ -39,8 +123,10 @@ func Warning(err error, v ...interface{}) bool {
 	return false
 }
 
+// Fatalf emits an error message and exits with ExitError. (See FatalfCode
+// for other exit codes.)
 func Fatalf(format string, v ...interface{}) {
-	log.Fatalf(format, v...)
+	FatalfCode(ExitError, format, v...)
 }
 
 func Assert(err error, v ...interface{}) {
@@ -69,8 +155,8 @@ func AssertLeastNArg(n int) {
 
 func AssertIsDir(path string) {
 	info, err := os.Stat(path)
-	Assert(err, "Directory '%s' is not accessible", path)
+	AssertCode(ExitIO, err, "Directory '%s' is not accessible", path)
 	if !info.IsDir() {
-		Fatalf("'%s' is not a directory.", path)
+		FatalfCode(ExitIO, "'%s' is not a directory.", path)
 	}
 }
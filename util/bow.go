@@ -78,7 +78,8 @@ func ProcessBowers(
 						lib := lib.(fragbag.SequenceLibrary)
 						bw = b.(bow.SequenceBower).SequenceBow(lib)
 					} else {
-						Fatalf("Unknown fragment library %T", lib)
+						FatalfCode(ExitInternal,
+							"Unknown fragment library %T", lib)
 					}
 					results <- bw
 				}
@@ -123,6 +124,14 @@ func ProcessBowers(
 	return results
 }
 
+// BowSequence returns the sequence given masked with FlagMask and then
+// remapped through FlagAlphabet (which are nil unless the 'mask' and
+// 'alphabet' flags are used). Every tool that computes sequence BOWs should
+// pass its sequences through BowSequence first.
+func BowSequence(s seq.Sequence) seq.Sequence {
+	return FlagAlphabet.Apply(FlagMask.Apply(s))
+}
+
 // BowerErr corresponds to a value that is either a Bower or an error
 // indicating why a Bower value could not be constructed.
 type BowerErr struct {
@@ -147,12 +156,14 @@ type BowerErr struct {
 // `lib` is a fragment library that is used to help interpret what kind of
 // value must be in `r`. For example, if `lib` is a sequence fragment library,
 // then `BowerOpen` is guaranteed to return a `Bower` value that implements the
-// `bow.SequenceBower` interface.
+// `bow.SequenceBower` interface. Each sequence is passed through BowSequence
+// before its bower is created.
 //
 // As of now, `BowerOpen` can read these types of files:
 //
 //	File extension                 Format    Interpretation
 //	*.{ent.gz,pdb,ent}             PDB       whatever `lib` is
+//	*-pdb-bundle.tar{,.gz}         PDB       whatever `lib` is
 //	*.{fasta,fas,fasta.gz,fas.gz}  FASTA     sequence
 //	everything else                error     invalid
 //
@@ -181,10 +192,13 @@ type BowerErr struct {
 // Alternatively, `fpath` may be the name of a SCOP domain, and its
 // corresponding PDB file will be inferred from the value of the
 // `SCOP_PDB_PATH` environment variable.
+//
+// A PDB bundle (see IsPDBBundle) is read as a single PDB entry with the
+// chain ids of the full structure.
 func BowerOpen(fpath string, lib fragbag.Library, models bool) <-chan BowerErr {
 	if lib == nil {
-		Fatalf("Files can only be converted to Fragbag frequency vectors " +
-			"if a fragment library is specified.")
+		FatalfCode(ExitInternal, "Files can only be converted to Fragbag "+
+			"frequency vectors if a fragment library is specified.")
 	}
 
 	bowers := make(chan BowerErr, 100)
@@ -193,7 +207,7 @@ func BowerOpen(fpath string, lib fragbag.Library, models bool) <-chan BowerErr {
 		go func() {
 			defer close(bowers)
 
-			entry, chains, err := PDBOpen(fpath)
+			entry, chains, err := PDBOpen(fpath, 0)
 			if err != nil {
 				err = fmt.Errorf("Error reading '%s': %s", fpath, err)
 				bowers <- BowerErr{Err: err}
@@ -207,11 +221,11 @@ func BowerOpen(fpath string, lib fragbag.Library, models bool) <-chan BowerErr {
 					}
 
 					if !models {
-						b := bow.BowerFromChain(chains[i])
+						b := StructureBowerFromChain(chains[i])
 						bowers <- BowerErr{Bower: b}
 					} else {
 						for _, m := range chains[i].Models {
-							b := bow.BowerFromModel(m)
+							b := StructureBowerFromModel(m)
 							bowers <- BowerErr{Bower: b}
 						}
 					}
@@ -222,15 +236,18 @@ func BowerOpen(fpath string, lib fragbag.Library, models bool) <-chan BowerErr {
 						continue
 					}
 
-					s := chains[i].AsSequence()
+					s := ChainSequence(chains[i], SequenceSource(lib))
 					if s.Len() == 0 {
-						s = aminoFromStructure(chains[i])
-						if s.Len() == 0 {
-							Warnf("Chain '%s:%c' has no amino sequence.",
-								entry.IdCode, chains[i].Ident)
-							continue
-						}
+						WarnFieldsf(
+							Fields{
+								"entry": entry.IdCode,
+								"chain": string(chains[i].Ident),
+							},
+							"Chain '%s:%c' has no amino sequence.",
+							entry.IdCode, chains[i].Ident)
+						continue
 					}
+					s = BowSequence(s)
 					bowers <- BowerErr{Bower: bow.BowerFromSequence(s)}
 				}
 			}
@@ -259,6 +276,7 @@ func BowerOpen(fpath string, lib fragbag.Library, models bool) <-chan BowerErr {
 					bowers <- BowerErr{Err: err}
 					return
 				}
+				s = BowSequence(s)
 				bowers <- BowerErr{Bower: bow.BowerFromSequence(s)}
 			}
 		}()
@@ -0,0 +1,368 @@
+package util
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// BowFile is a BOW as it is written to and read from a file. Along with the
+// BOW, the name and size of the fragment library used to compute it are
+// stored so that BOWs computed from different libraries are not compared.
+//
+// The fields of a bow.Bowed value are included directly (rather than
+// embedded) so that files containing only a bow.Bowed value can still be
+// read. The library name and size of such files are empty.
+//
+// A BOW may also be the concatenation of BOWs computed from several
+// libraries (see NewEnsembleBowFile). In that case, Components lists each
+// library in the order in which its fragments appear in the BOW.
+//
+// When Sparse is set, Bow is empty and SparseFreqs lists every fragment with
+// a non-zero frequency instead (see FlagSparse). LibSize is then the size of
+// the dense BOW. BOWs are made dense again when read, so Sparse is never set
+// on a BOW returned by BowRead or BowOpen.
+//
+// Norm is the L2 norm of the BOW when it was stored with the BOW (see
+// FlagNorms), and 0 otherwise.
+type BowFile struct {
+	Id          string
+	Data        []byte
+	Bow         bow.Bow
+	LibName     string
+	LibSize     int
+	Components  []BowComponent
+	Sparse      bool
+	SparseFreqs []SparseFreq
+	Norm        float64
+}
+
+// SparseFreq is the frequency of a single fragment in a sparse BOW.
+type SparseFreq struct {
+	Frag int
+	Freq float32
+}
+
+// BowComponent is the name and size of one fragment library of an ensemble
+// BOW.
+type BowComponent struct {
+	LibName string
+	LibSize int
+}
+
+// NewBowFile associates a BOW with the fragment library used to compute it.
+func NewBowFile(lib fragbag.Library, b bow.Bowed) BowFile {
+	return BowFile{
+		Id:      b.Id,
+		Data:    b.Data,
+		Bow:     b.Bow,
+		LibName: lib.Name(),
+		LibSize: lib.Size(),
+	}
+}
+
+// NewEnsembleBowFile concatenates BOWs of the same structure (or sequence)
+// computed from each of the libraries given, in order. The library name of
+// the result is the names of the libraries joined by a ',', and its size is
+// the sum of their sizes. The id and data are taken from the first BOW.
+func NewEnsembleBowFile(libs []fragbag.Library, bs []bow.Bowed) BowFile {
+	Assert(checkEnsemble(libs, bs), "Could not create ensemble BOW")
+
+	bf := BowFile{Id: bs[0].Id, Data: bs[0].Data}
+	names := make([]string, len(libs))
+	for i, lib := range libs {
+		names[i] = lib.Name()
+		bf.LibSize += lib.Size()
+		bf.Components = append(bf.Components,
+			BowComponent{LibName: lib.Name(), LibSize: lib.Size()})
+	}
+	bf.LibName = strings.Join(names, ",")
+
+	bf.Bow = bow.NewBow(bf.LibSize)
+	offset := 0
+	for i, b := range bs {
+		copy(bf.Bow.Freqs[offset:], b.Bow.Freqs)
+		offset += libs[i].Size()
+	}
+	return bf
+}
+
+func checkEnsemble(libs []fragbag.Library, bs []bow.Bowed) error {
+	if len(libs) == 0 || len(libs) != len(bs) {
+		return fmt.Errorf("got %d libraries and %d BOWs", len(libs), len(bs))
+	}
+	for i, lib := range libs {
+		if fragbag.IsStructure(lib) != fragbag.IsStructure(libs[0]) {
+			return fmt.Errorf("library '%s' is not the same kind of library "+
+				"as '%s' (structure and sequence libraries cannot be mixed)",
+				lib.Name(), libs[0].Name())
+		}
+		if len(bs[i].Bow.Freqs) != lib.Size() {
+			return fmt.Errorf("BOW has %d fragments, but library '%s' has %d",
+				len(bs[i].Bow.Freqs), lib.Name(), lib.Size())
+		}
+	}
+	return nil
+}
+
+// Bowed returns the BOW without its library information.
+func (bf BowFile) Bowed() bow.Bowed {
+	return bow.Bowed{Id: bf.Id, Data: bf.Data, Bow: bf.Bow}
+}
+
+// Magnitude returns the L2 norm of the BOW. The stored norm is used if
+// there is one.
+func (bf BowFile) Magnitude() float64 {
+	if bf.Norm > 0 {
+		return bf.Norm
+	}
+	return bf.Bow.Magnitude()
+}
+
+// Cosine returns the cosine distance between two BOWs in the same way as
+// bow.Bow.Cosine, except that stored norms are used instead of computing
+// them again. The BOWs must have the same number of fragments.
+func (bf BowFile) Cosine(bf2 BowFile) float64 {
+	return CosineNorms(bf.Bow, bf2.Bow, bf.Magnitude(), bf2.Magnitude())
+}
+
+// CosineNorms returns the cosine distance between two BOWs in the same way
+// as bow.Bow.Cosine, given their L2 norms (see bow.Bow.Magnitude). This lets
+// a search compute the norm of each BOW once. If either norm is 0, then the
+// distance is 1.
+func CosineNorms(b1, b2 bow.Bow, norm1, norm2 float64) float64 {
+	if norm1 == 0 || norm2 == 0 {
+		return 1.0
+	}
+	return 1.0 - b1.Dot(b2)/(norm1*norm2)
+}
+
+// SameLibrary returns an error if the two BOWs given could not have been
+// computed from the same fragment library. The library names are only
+// compared when both are known, but the vector sizes are always compared.
+// (The library name of an ensemble BOW includes the name of every library,
+// so ensemble BOWs are only comparable with the same libraries in the same
+// order.)
+func (bf BowFile) SameLibrary(bf2 BowFile) error {
+	if len(bf.LibName) > 0 && len(bf2.LibName) > 0 &&
+		(bf.LibName != bf2.LibName || bf.LibSize != bf2.LibSize) {
+		return fmt.Errorf("BOW '%s' was computed with library '%s' (size %d), "+
+			"but BOW '%s' was computed with library '%s' (size %d)",
+			bf.Id, bf.LibName, bf.LibSize, bf2.Id, bf2.LibName, bf2.LibSize)
+	}
+	if len(bf.Bow.Freqs) != len(bf2.Bow.Freqs) {
+		return fmt.Errorf("BOW '%s' has %d fragments, but BOW '%s' has %d",
+			bf.Id, len(bf.Bow.Freqs), bf2.Id, len(bf2.Bow.Freqs))
+	}
+	return nil
+}
+
+// sparse returns a copy of the BOW with only the frequencies of fragments
+// that occur.
+func (bf BowFile) sparse() BowFile {
+	if bf.Sparse {
+		return bf
+	}
+	freqs := make([]SparseFreq, 0)
+	for i, f := range bf.Bow.Freqs {
+		if f != 0 {
+			freqs = append(freqs, SparseFreq{Frag: i, Freq: f})
+		}
+	}
+	bf.LibSize = len(bf.Bow.Freqs)
+	bf.Bow = bow.Bow{}
+	bf.Sparse, bf.SparseFreqs = true, freqs
+	return bf
+}
+
+// dense converts a sparse BOW back to a BOW with a frequency for every
+// fragment in the library. An error is returned if a fragment is not in the
+// library.
+func (bf *BowFile) dense() error {
+	if !bf.Sparse {
+		return nil
+	}
+	b := bow.NewBow(bf.LibSize)
+	for _, sf := range bf.SparseFreqs {
+		if sf.Frag < 0 || sf.Frag >= bf.LibSize {
+			return fmt.Errorf("sparse BOW '%s' has fragment %d, but its "+
+				"library has %d fragments", bf.Id, sf.Frag, bf.LibSize)
+		}
+		b.Freqs[sf.Frag] = sf.Freq
+	}
+	bf.Bow = b
+	bf.Sparse, bf.SparseFreqs = false, nil
+	return nil
+}
+
+// encoded returns the BOW as it is written to a file: with its norm when
+// FlagNorms is set, and in the sparse format when FlagSparse is set.
+func (bf BowFile) encoded() BowFile {
+	if FlagNorms {
+		bf.Norm = bf.Bow.Magnitude()
+	}
+	if FlagSparse {
+		bf = bf.sparse()
+	}
+	return bf
+}
+
+func BowRead(path string) BowFile {
+	var b BowFile
+	f := OpenFile(path)
+	defer f.Close()
+
+	r := gob.NewDecoder(f)
+	Assert(r.Decode(&b), "Could not GOB decode BOW '%s'", path)
+	Assert(b.dense(), "Could not read BOW '%s'", path)
+	return b
+}
+
+func BowWrite(w io.Writer, lib fragbag.Library, b bow.Bowed) {
+	BowFileWrite(w, NewBowFile(lib, b))
+}
+
+// BowFileWrite is like BowWrite, except the library information is taken
+// from the BOW file given. When FlagSparse is set, the BOW is written in the
+// sparse format. When FlagNorms is set, the norm of the BOW is stored.
+func BowFileWrite(w io.Writer, bf BowFile) {
+	encoder := gob.NewEncoder(w)
+	Assert(encoder.Encode(bf.encoded()), "Could not GOB encode BOW")
+}
+
+// BowWriteJSON is like BowWrite, except the BOW is JSON encoded.
+func BowWriteJSON(w io.Writer, lib fragbag.Library, b bow.Bowed) {
+	bf := NewBowFile(lib, b).encoded()
+	encoder := json.NewEncoder(w)
+	Assert(encoder.Encode(bf), "Could not JSON encode BOW")
+}
+
+// bowTextMagic is the first line of every BOW in the text format.
+const bowTextMagic = "BOW-TEXT"
+
+// BowWriteText writes a BOW in a plain text format that can be read by
+// BowReadAny. The first line is "BOW-TEXT", followed by an 'id {id}' line
+// and a 'library {size} {name}' line. Each remaining line has the form
+// '{fragment} {frequency}' for every fragment with a non-zero frequency.
+// Frequencies are written with enough precision to be read back exactly.
+//
+// The Data field of the BOW is not written.
+func BowWriteText(w io.Writer, lib fragbag.Library, b bow.Bowed) {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\nid %s\nlibrary %d %s\n",
+		bowTextMagic, b.Id, lib.Size(), lib.Name())
+	for i, f := range b.Bow.Freqs {
+		if f != 0 {
+			fmt.Fprintf(bw, "%d %s\n",
+				i, strconv.FormatFloat(float64(f), 'g', -1, 32))
+		}
+	}
+	Assert(bw.Flush(), "Could not write BOW")
+}
+
+// bowReadText reads a BOW written by BowWriteText.
+func bowReadText(r io.Reader) (BowFile, error) {
+	var b BowFile
+	lines := ReadLines(r)
+	if len(lines) < 3 || lines[0] != bowTextMagic {
+		return b, fmt.Errorf("missing text BOW header")
+	}
+	if !strings.HasPrefix(lines[1], "id ") {
+		return b, fmt.Errorf("expected 'id' on line 2 but got '%s'",
+			lines[1])
+	}
+	b.Id = lines[1][3:]
+
+	lib := strings.SplitN(lines[2], " ", 3)
+	if len(lib) != 3 || lib[0] != "library" {
+		return b, fmt.Errorf("expected 'library' on line 3 but got '%s'",
+			lines[2])
+	}
+	size, err := strconv.Atoi(lib[1])
+	if err != nil || size < 0 {
+		return b, fmt.Errorf("invalid library size '%s'", lib[1])
+	}
+	b.LibSize, b.LibName = size, lib[2]
+
+	b.Bow = bow.NewBow(size)
+	for i, line := range lines[3:] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return b, fmt.Errorf("expected 'fragment frequency' on line %d "+
+				"but got '%s'", i+4, line)
+		}
+		frag, err := strconv.Atoi(fields[0])
+		if err != nil || frag < 0 || frag >= size {
+			return b, fmt.Errorf("invalid fragment '%s' on line %d",
+				fields[0], i+4)
+		}
+		freq, err := strconv.ParseFloat(fields[1], 32)
+		if err != nil {
+			return b, fmt.Errorf("invalid frequency '%s' on line %d",
+				fields[1], i+4)
+		}
+		b.Bow.Freqs[frag] = float32(freq)
+	}
+	return b, nil
+}
+
+// BowReadAny reads a BOW from the file at `path`, which may be encoded as
+// GOB, JSON or the text format written by BowWriteText. Text BOWs are
+// detected by their "BOW-TEXT" header. Otherwise, the file is decoded as GOB
+// and, if that fails, as JSON. (The first byte of a GOB stream is a message
+// length, which may be any byte at all, so it cannot be used to tell GOB
+// and JSON apart.)
+func BowReadAny(path string) BowFile {
+	b, err := BowOpen(path)
+	Assert(err)
+	return b
+}
+
+// BowOpen is like BowReadAny, except an error is returned if the BOW could
+// not be read.
+func BowOpen(path string) (BowFile, error) {
+	var b BowFile
+	f, err := os.Open(path)
+	if err != nil {
+		return b, fmt.Errorf("Could not open BOW '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(bowTextMagic))
+	if len(magic) == 0 && err == io.EOF {
+		return b, fmt.Errorf("Could not read BOW '%s': file is empty", path)
+	}
+	if string(magic) == bowTextMagic {
+		b, err = bowReadText(br)
+		if err != nil {
+			return b, fmt.Errorf("Could not read text BOW '%s': %s", path, err)
+		}
+		return b, nil
+	}
+	if gobErr := gob.NewDecoder(br).Decode(&b); gobErr != nil {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return b, fmt.Errorf("Could not read BOW '%s': %s", path, err)
+		}
+		b = BowFile{}
+		if err := json.NewDecoder(f).Decode(&b); err != nil {
+			return b, fmt.Errorf("Could not decode BOW '%s' as GOB (%s) "+
+				"or as JSON (%s)", path, gobErr, err)
+		}
+	}
+	if err := b.dense(); err != nil {
+		return b, fmt.Errorf("Could not read BOW '%s': %s", path, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,279 @@
+package util
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// randomBow returns a BOW of the size given with random frequencies, about
+// half of which are zero.
+func randomBow(rng *rand.Rand, size int) bow.Bow {
+	b := bow.NewBow(size)
+	for i := range b.Freqs {
+		if rng.Intn(2) == 0 {
+			b.Freqs[i] = float32(rng.Intn(20))
+		}
+	}
+	return b
+}
+
+func TestCosineNorms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		b1, b2 := randomBow(rng, 400), randomBow(rng, 400)
+		want := b1.Cosine(b2)
+		got := CosineNorms(b1, b2, b1.Magnitude(), b2.Magnitude())
+		if got != want {
+			t.Fatalf("CosineNorms = %v, but bow.Bow.Cosine = %v", got, want)
+		}
+
+		bf1 := BowFile{Bow: b1, Norm: b1.Magnitude()}
+		bf2 := BowFile{Bow: b2}
+		if got := bf1.Cosine(bf2); got != want {
+			t.Fatalf("BowFile.Cosine with a stored norm = %v, want %v",
+				got, want)
+		}
+	}
+
+	zero := BowFile{Bow: bow.NewBow(400)}
+	other := BowFile{Bow: randomBow(rng, 400)}
+	if d := zero.Cosine(other); d != 1 {
+		t.Errorf("cosine distance to an all-zero BOW = %v, want 1", d)
+	}
+	if d := zero.Cosine(zero); d != 1 {
+		t.Errorf("cosine distance between all-zero BOWs = %v, want 1", d)
+	}
+}
+
+// sizedLib is a library with the number of fragments given.
+type sizedLib struct {
+	alaLib
+	size int
+}
+
+func (lib sizedLib) Size() int { return lib.size }
+
+// sparseBowFile returns a BOW of a library with `size` fragments where only
+// `n` fragments occur.
+func sparseBowFile(rng *rand.Rand, id string, size, n int) BowFile {
+	b := bow.NewBow(size)
+	for i := 0; i < n; i++ {
+		b.Freqs[rng.Intn(size)] += float32(1 + rng.Intn(5))
+	}
+	return BowFile{Id: id, Bow: b, LibName: "lib", LibSize: size}
+}
+
+func TestSparseDistances(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { FlagSparse = false }()
+	FlagSparse = true
+
+	rng := rand.New(rand.NewSource(1))
+	bf1 := sparseBowFile(rng, "a", 4000, 30)
+	bf2 := sparseBowFile(rng, "b", 4000, 30)
+	write := map[string]func(fpath string, bf BowFile){
+		"gob": func(fpath string, bf BowFile) {
+			f := CreateFile(fpath)
+			BowFileWrite(f, bf)
+			Assert(f.Close())
+		},
+		"json": func(fpath string, bf BowFile) {
+			f := CreateFile(fpath)
+			BowWriteJSON(f, sizedLib{size: bf.LibSize}, bf.Bowed())
+			Assert(f.Close())
+		},
+	}
+	for format, w := range write {
+		var read []BowFile
+		for _, bf := range []BowFile{bf1, bf2} {
+			fpath := filepath.Join(dir, bf.Id+"."+format)
+			w(fpath, bf)
+			contents, err := ioutil.ReadFile(fpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sparse := []byte(`"Sparse":true`)
+			if format == "json" && !bytes.Contains(contents, sparse) {
+				t.Errorf("JSON BOW was not written in the sparse format")
+			}
+			rbf, err := BowOpen(fpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			read = append(read, rbf)
+		}
+		if !read[0].Bow.Equal(bf1.Bow) || !read[1].Bow.Equal(bf2.Bow) {
+			t.Errorf("%s: sparse BOWs are not read back as written", format)
+		}
+		if got, want := read[0].Cosine(read[1]), bf1.Cosine(bf2); got != want {
+			t.Errorf("%s: cosine distance of sparse BOWs = %v, want %v",
+				format, got, want)
+		}
+	}
+}
+
+func BenchmarkSparseSize(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	bf := sparseBowFile(rng, "a", 4000, 30)
+	for _, sparse := range []bool{false, true} {
+		name := map[bool]string{false: "dense", true: "sparse"}[sparse]
+		b.Run(name, func(b *testing.B) {
+			defer func() { FlagSparse = false }()
+			FlagSparse = sparse
+			size := 0
+			for i := 0; i < b.N; i++ {
+				buf := new(bytes.Buffer)
+				BowFileWrite(buf, bf)
+				size = buf.Len()
+			}
+			b.ReportMetric(float64(size), "bytes/bow")
+		})
+	}
+}
+
+func TestBowOpenFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bowopen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rng := rand.New(rand.NewSource(1))
+	lib := sizedLib{size: 50}
+	b := bow.Bowed{Id: "1abcA", Bow: randomBow(rng, lib.Size())}
+	write := map[string]func(buf *bytes.Buffer){
+		"gob":  func(buf *bytes.Buffer) { BowWrite(buf, lib, b) },
+		"json": func(buf *bytes.Buffer) { BowWriteJSON(buf, lib, b) },
+		"json-space": func(buf *bytes.Buffer) {
+			buf.WriteString(" \n\t")
+			BowWriteJSON(buf, lib, b)
+		},
+		"text": func(buf *bytes.Buffer) { BowWriteText(buf, lib, b) },
+	}
+	for format, w := range write {
+		buf := new(bytes.Buffer)
+		w(buf)
+		fpath := filepath.Join(dir, format)
+		if err := ioutil.WriteFile(fpath, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		bf, err := BowOpen(fpath)
+		if err != nil {
+			t.Errorf("%s: %s", format, err)
+			continue
+		}
+		if bf.Id != b.Id || !bf.Bow.Equal(b.Bow) {
+			t.Errorf("%s: BOW is not read back as written", format)
+		}
+		if bf.LibName != lib.Name() || bf.LibSize != lib.Size() {
+			t.Errorf("%s: got library '%s' (size %d), want '%s' (size %d)",
+				format, bf.LibName, bf.LibSize, lib.Name(), lib.Size())
+		}
+	}
+
+	bad := map[string]string{
+		"empty":      "",
+		"whitespace": " \n\t\n",
+		"bad-json":   `{"Id": `,
+		"bad-gob":    "not a BOW",
+		"bad-text":   bowTextMagic + "\nid x\n",
+	}
+	for name, contents := range bad {
+		fpath := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fpath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := BowOpen(fpath); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := BowOpen(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("missing: expected an error")
+	}
+}
+
+// TestBowOpenAfterGob writes a GOB encoded BOW after another type has been
+// GOB encoded, which changes the type ids in the BOW's stream and so its
+// first byte. (With the types in this tree, the first byte becomes '{'.)
+// Type ids are assigned once per process, so the BOW is written and read in
+// a child process.
+func TestBowOpenAfterGob(t *testing.T) {
+	if fpath := os.Getenv("UTIL_BOW_AFTER_GOB"); len(fpath) > 0 {
+		type fragment struct{ Name string }
+		enc := gob.NewEncoder(ioutil.Discard)
+		if err := enc.Encode(fragment{}); err != nil {
+			t.Fatal(err)
+		}
+		lib := sizedLib{size: 50}
+		b := bow.Bowed{Id: "1abcA", Bow: bow.NewBow(lib.Size())}
+		b.Bow.Freqs[3] = 7
+
+		buf := new(bytes.Buffer)
+		BowWrite(buf, lib, b)
+		t.Logf("first byte of GOB BOW: %q", buf.Bytes()[0])
+		if err := ioutil.WriteFile(fpath, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		bf, err := BowOpen(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bf.Id != b.Id || !bf.Bow.Equal(b.Bow) {
+			t.Fatalf("BOW is not read back as written")
+		}
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "bowopen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command(os.Args[0],
+		"-test.run=^TestBowOpenAfterGob$", "-test.v")
+	cmd.Env = append(os.Environ(),
+		"UTIL_BOW_AFTER_GOB="+filepath.Join(dir, "bow"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+}
+
+func TestSameLibrary(t *testing.T) {
+	bf := func(name string, size, freqs int) BowFile {
+		return BowFile{Id: name, Bow: bow.NewBow(freqs),
+			LibName: name, LibSize: size}
+	}
+	tests := []struct {
+		name   string
+		b1, b2 BowFile
+		same   bool
+	}{
+		{"same", bf("lib", 10, 10), bf("lib", 10, 10), true},
+		{"name", bf("lib", 10, 10), bf("other", 10, 10), false},
+		{"size", bf("lib", 10, 10), bf("lib", 12, 10), false},
+		{"unknown", bf("lib", 10, 10), bf("", 0, 10), true},
+		{"unknown-freqs", bf("lib", 10, 10), bf("", 0, 12), false},
+		{"freqs", bf("lib", 10, 10), bf("lib", 10, 12), false},
+	}
+	for _, test := range tests {
+		err := test.b1.SameLibrary(test.b2)
+		if test.same && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if !test.same && err == nil {
+			t.Errorf("%s: expected a library mismatch", test.name)
+		}
+	}
+}
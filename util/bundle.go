@@ -0,0 +1,234 @@
+package util
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"strings"
+
+	"github.com/TuftsBCB/io/pdb"
+)
+
+// Suffixes of the files in a PDB bundle. Very large structures that do not
+// fit in a single PDB file are distributed as a bundle of PDB files (e.g.,
+// "4v4b-pdb-bundle1.pdb"), along with a file mapping the chain ids used in
+// each PDB file to the chain ids of the full structure. A bundle is either a
+// directory or a (possibly gzipped) tar file (e.g., "4v4b-pdb-bundle.tar.gz")
+// containing these files.
+const (
+	bundleMappingSuffix = "-chain-id-mapping.txt"
+	bundleTarSuffix     = "-pdb-bundle.tar"
+)
+
+// IsPDBBundle returns true if `fpath` is a PDB bundle: either a tar file
+// whose name ends with "-pdb-bundle.tar" (or ".tar.gz"), or a directory
+// with a chain id mapping file.
+func IsPDBBundle(fpath string) bool {
+	base := path.Base(fpath)
+	if strings.HasSuffix(base, bundleTarSuffix) ||
+		strings.HasSuffix(base, bundleTarSuffix+".gz") {
+		return true
+	}
+	if !IsDir(fpath) {
+		return false
+	}
+	_, err := bundleMappingFile(fpath)
+	return err == nil
+}
+
+// bundleMappingFile returns the path of the chain id mapping file in the
+// bundle directory given.
+func bundleMappingFile(dir string) (string, error) {
+	matches, err := path.Glob(path.Join(dir, "*"+bundleMappingSuffix))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) != 1 {
+		return "", fmt.Errorf("expected one '*%s' file in '%s' but found %d",
+			bundleMappingSuffix, dir, len(matches))
+	}
+	return matches[0], nil
+}
+
+// bundleFile is one PDB file of a bundle, along with a map from the chain ids
+// used in it to the chain ids of the full structure.
+type bundleFile struct {
+	name  string
+	remap map[string]string
+}
+
+// readBundleMapping reads a chain id mapping file of a PDB bundle. It lists
+// each PDB file of the bundle (as a line 'name:'), followed by a line 'new
+// original' for each of its chains. The files are returned in the order in
+// which they are listed.
+func readBundleMapping(r io.Reader) ([]bundleFile, error) {
+	var files []bundleFile
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0 || strings.Contains(line, "chain ID"):
+			continue
+		case strings.HasSuffix(line, ":"):
+			name := strings.TrimSpace(line[:len(line)-1])
+			files = append(files, bundleFile{name, map[string]string{}})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(files) == 0 {
+			return nil, fmt.Errorf("expected 'new-id original-id' on line "+
+				"%d but got '%s'", n, line)
+		}
+		files[len(files)-1].remap[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no PDB files are listed")
+	}
+	return files, nil
+}
+
+// bundleContents returns the contents of every file in the PDB bundle at
+// `fpath`, keyed by file name, along with the name of the mapping file.
+func bundleContents(fpath string) (map[string][]byte, string, error) {
+	contents := make(map[string][]byte)
+	if IsDir(fpath) {
+		mapping, err := bundleMappingFile(fpath)
+		if err != nil {
+			return nil, "", err
+		}
+		names, err := path.Glob(path.Join(fpath, "*"))
+		if err != nil {
+			return nil, "", err
+		}
+		for _, name := range names {
+			if IsDir(name) {
+				continue
+			}
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return nil, "", err
+			}
+			contents[path.Base(name)] = data
+		}
+		return contents, path.Base(mapping), nil
+	}
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	r, err := MaybeGzipReader(f)
+	if err != nil {
+		return nil, "", err
+	}
+	mapping := ""
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, "", err
+		}
+		name := path.Base(hdr.Name)
+		contents[name] = data
+		if strings.HasSuffix(name, bundleMappingSuffix) {
+			mapping = name
+		}
+	}
+	if len(mapping) == 0 {
+		return nil, "", fmt.Errorf("no '*%s' file in '%s'",
+			bundleMappingSuffix, fpath)
+	}
+	return contents, mapping, nil
+}
+
+// pdbBundleRead reads every PDB file of the bundle at `fpath` into a single
+// entry, in the order listed by the bundle's chain id mapping. Each chain
+// is given its id in the full structure. If that id has more than one
+// character, then the chain keeps its id from its PDB file (with a warning),
+// so such chain ids may not be unique in the entry returned.
+func pdbBundleRead(fpath string) (*pdb.Entry, error) {
+	contents, mappingName, err := bundleContents(fpath)
+	if err != nil {
+		return nil, err
+	}
+	files, err := readBundleMapping(bytes.NewReader(contents[mappingName]))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read '%s': %s", mappingName, err)
+	}
+
+	var entry *pdb.Entry
+	kept := 0
+	for _, file := range files {
+		data, ok := contents[file.name]
+		if !ok {
+			return nil, fmt.Errorf("'%s' is listed in '%s' but is not in "+
+				"the bundle", file.name, mappingName)
+		}
+		r, err := MaybeGzipReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading '%s': %s", file.name, err)
+		}
+		part, err := pdb.Read(r, path.Join(fpath, file.name))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading '%s': %s", file.name, err)
+		}
+		partChains := part.Chains
+		if entry == nil {
+			entry = part
+			entry.Path = fpath
+			entry.Chains = nil
+			if len(entry.IdCode) == 0 {
+				entry.IdCode = strings.SplitN(path.Base(fpath), "-", 2)[0]
+			}
+		}
+		for _, chain := range partChains {
+			orig, ok := file.remap[string(chain.Ident)]
+			if ok && len(orig) == 1 {
+				chain.Ident = orig[0]
+			} else if ok {
+				kept++
+			}
+			chain.Entry = entry
+			for _, m := range chain.Models {
+				m.Entry = entry
+			}
+			entry.Chains = append(entry.Chains, chain)
+		}
+	}
+	if kept > 0 {
+		Warnf("%d chains of the PDB bundle '%s' have original chain ids "+
+			"with more than one character. They keep the chain ids of "+
+			"their bundle files, which may not be unique.", kept, fpath)
+	}
+	return entry, nil
+}
+
+// pdbRead reads the PDB file at `fpath`, which may be gzipped or a PDB
+// bundle.
+func pdbRead(fpath string) (*pdb.Entry, error) {
+	if IsPDBBundle(fpath) {
+		return pdbBundleRead(fpath)
+	}
+	return pdb.ReadPDB(fpath)
+}
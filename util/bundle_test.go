@@ -0,0 +1,146 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bundlePDB returns a PDB file with a single alanine at the origin for each
+// of the chains given.
+func bundlePDB(chains string) string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "HEADER    %-40s%-9s   %s\n", "RIBOSOME", "01-JAN-00",
+		"9XYZ")
+	for i := 0; i < len(chains); i++ {
+		fmt.Fprintf(buf, "ATOM  %5d  %-3s %3s %c%4d    %8.3f%8.3f%8.3f"+
+			"  1.00  0.00\n", i+1, "CA", "ALA", chains[i], 1, 0.0, 0.0, 0.0)
+	}
+	buf.WriteString("END\n")
+	return buf.String()
+}
+
+// bundleFiles is a synthetic PDB bundle of two files. The mapping lists the
+// second file first, and gives chain 'B' of the second file an original
+// chain id that is too long to keep.
+var bundleFiles = map[string]string{
+	"9xyz-pdb-bundle1.pdb": bundlePDB("AB"),
+	"9xyz-pdb-bundle2.pdb": bundlePDB("AB"),
+	"9xyz-chain-id-mapping.txt": `
+    New chain ID            Original chain ID
+
+9xyz-pdb-bundle2.pdb:
+               A            C
+               B           AA
+
+9xyz-pdb-bundle1.pdb:
+               A            B
+               B            A
+`,
+}
+
+// writeBundleTar writes the bundle files to a gzipped tar file at `fpath`.
+func writeBundleTar(fpath string) error {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range bundleFiles {
+		hdr := &tar.Header{
+			Name:     "9xyz-pdb-bundle/" + name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fpath, buf.Bytes(), 0644)
+}
+
+func TestPDBBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundleDir := filepath.Join(dir, "9xyz")
+	if err := os.Mkdir(bundleDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range bundleFiles {
+		fpath := filepath.Join(bundleDir, name)
+		if err := ioutil.WriteFile(fpath, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundleTar := filepath.Join(dir, "9xyz-pdb-bundle.tar.gz")
+	if err := writeBundleTar(bundleTar); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fpath := range []string{bundleDir, bundleTar} {
+		if !IsPDBBundle(fpath) {
+			t.Errorf("%s is not recognized as a bundle", fpath)
+			continue
+		}
+		entry, chains, err := PDBOpen(fpath, 0)
+		if err != nil {
+			t.Errorf("%s: %s", fpath, err)
+			continue
+		}
+		if entry.IdCode != "9xyz" || entry.Path != fpath {
+			t.Errorf("%s: got id '%s' and path '%s'",
+				fpath, entry.IdCode, entry.Path)
+		}
+
+		// Chains are in the order of the mapping, with their original ids
+		// when those have one character.
+		var idents []byte
+		for _, chain := range chains {
+			idents = append(idents, chain.Ident)
+			if chain.Entry != entry || chain.Models[0].Entry != entry {
+				t.Errorf("%s: chain '%c' does not belong to the merged "+
+					"entry", fpath, chain.Ident)
+			}
+			if n := len(chain.CaAtoms()); n != 1 {
+				t.Errorf("%s: chain '%c' has %d atoms, want 1",
+					fpath, chain.Ident, n)
+			}
+		}
+		if want := []byte("CBBA"); !reflect.DeepEqual(idents, want) {
+			t.Errorf("%s: got chains %q, want %q", fpath, idents, want)
+		}
+		if !reflect.DeepEqual(entry.Chains, chains) {
+			t.Errorf("%s: PDBOpen without chain ids should return every "+
+				"chain of the entry", fpath)
+		}
+	}
+
+	// A file listed in the mapping must be in the bundle.
+	missing := filepath.Join(bundleDir, "9xyz-pdb-bundle1.pdb")
+	if err := os.Remove(missing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pdbBundleRead(bundleDir); err == nil ||
+		!strings.Contains(err.Error(), "9xyz-pdb-bundle1.pdb") {
+		t.Errorf("expected an error naming the missing file, got %v", err)
+	}
+}
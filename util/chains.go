@@ -0,0 +1,46 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ListChains returns the chain identifiers of every chain in the PDB or
+// PDBx/mmCIF file at `fpath`. The special chain selection syntax accepted by
+// PDBOpen (e.g., "1ctf.ent.gz:A,B" or "1ctfA") is respected, so that only the
+// selected chains are returned.
+//
+// Chains from PDB files are returned in the order in which they appear in the
+// file. Chains from mmCIF files are sorted by their identifier.
+func ListChains(fpath string) ([]byte, error) {
+	switch {
+	case IsCIF(fpath):
+		_, chains, err := CIFOpen(fpath)
+		if err != nil {
+			return nil, err
+		}
+		idents := make([]byte, len(chains))
+		for i := range chains {
+			idents[i] = chains[i].Id
+		}
+		sort.Sort(byteSlice(idents))
+		return idents, nil
+	case IsPDB(fpath):
+		_, chains, err := PDBOpen(fpath, 0)
+		if err != nil {
+			return nil, err
+		}
+		idents := make([]byte, len(chains))
+		for i := range chains {
+			idents[i] = chains[i].Ident
+		}
+		return idents, nil
+	}
+	return nil, fmt.Errorf("'%s' is not a PDB or mmCIF file.", fpath)
+}
+
+type byteSlice []byte
+
+func (bs byteSlice) Len() int           { return len(bs) }
+func (bs byteSlice) Less(i, j int) bool { return bs[i] < bs[j] }
+func (bs byteSlice) Swap(i, j int)      { bs[i], bs[j] = bs[j], bs[i] }
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// Exit codes used by every tool when it fails, so that wrapping scripts can
+// tell kinds of failures apart. These values are stable.
+const (
+	// ExitError is used for failures that do not fit another category
+	// (e.g., a problem found by a validation tool).
+	ExitError = 1
+
+	// ExitUsage is used for invalid arguments or flags, including bad
+	// combinations of flags and malformed identifiers.
+	ExitUsage = 2
+
+	// ExitIO is used when a file cannot be found, opened or created.
+	ExitIO = 3
+
+	// ExitParse is used when the contents of an input are malformed.
+	ExitParse = 4
+
+	// ExitInternal is used for failures that indicate a bug.
+	ExitInternal = 5
+)
+
+// FatalfCode is like Fatalf, except the process exits with `code`.
+func FatalfCode(code int, format string, v ...interface{}) {
+	if logJSON {
+		printJSON("fatal", nil, format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+	os.Exit(code)
+}
+
+// AssertCode is like Assert, except the process exits with `code`.
+func AssertCode(code int, err error, v ...interface{}) {
+	if err != nil {
+		if len(v) == 0 {
+			FatalfCode(code, "ERROR: %s", err)
+		} else {
+			format := v[0].(string)
+			v = v[1:]
+			FatalfCode(code, "%s: %s", fmt.Sprintf(format, v...), err)
+		}
+	}
+}
+
+// readCode returns the exit code for an error from reading a file: ExitIO if
+// the file could not be opened and ExitParse otherwise.
+func readCode(err error) int {
+	if _, ok := err.(*os.PathError); ok {
+		return ExitIO
+	}
+	return ExitParse
+}
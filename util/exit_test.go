@@ -0,0 +1,77 @@
+package util
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// exitCases fail in a way that belongs to each category of exit code. They
+// are run in a child process, since they exit.
+var exitCases = map[string]struct {
+	code int
+	fail func(dir string)
+}{
+	"error": {ExitError, func(dir string) {
+		Assert(errors.New("validation failed"))
+	}},
+	"usage": {ExitUsage, func(dir string) {
+		PDBOpen(filepath.Join(dir, "1ctf:A:B"), 0)
+	}},
+	"io": {ExitIO, func(dir string) {
+		OpenFile(filepath.Join(dir, "missing"))
+	}},
+	"parse": {ExitParse, func(dir string) {
+		fpath := filepath.Join(dir, "residues")
+		if err := ioutil.WriteFile(fpath, []byte("MSE\n"), 0644); err != nil {
+			panic(err)
+		}
+		LoadResidueMap(fpath)
+	}},
+	"internal": {ExitInternal, func(dir string) {
+		BowerOpen(filepath.Join(dir, "1ctf.pdb"), nil, false)
+	}},
+}
+
+func TestExitCodes(t *testing.T) {
+	if name := os.Getenv("UTIL_EXIT_CASE"); len(name) > 0 {
+		exitCases[name].fail(os.Getenv("UTIL_EXIT_DIR"))
+		os.Exit(0)
+	}
+
+	dir, err := ioutil.TempDir("", "exit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, c := range exitCases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExitCodes$")
+		cmd.Env = append(os.Environ(),
+			"UTIL_EXIT_CASE="+name, "UTIL_EXIT_DIR="+dir)
+		err := cmd.Run()
+		exit, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Errorf("%s: expected the process to exit with code %d, "+
+				"but got %v", name, c.code, err)
+			continue
+		}
+		if got := exit.ExitCode(); got != c.code {
+			t.Errorf("%s: exit code %d, want %d", name, got, c.code)
+		}
+	}
+}
+
+func TestReadCode(t *testing.T) {
+	_, err := os.Open(filepath.Join(os.TempDir(), "no-such-file"))
+	if code := readCode(err); code != ExitIO {
+		t.Errorf("readCode(%v) = %d, want ExitIO", err, code)
+	}
+	err = errors.New("malformed header")
+	if code := readCode(err); code != ExitParse {
+		t.Errorf("readCode(%v) = %d, want ExitParse", err, code)
+	}
+}
@@ -0,0 +1,18 @@
+package util
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/ndaniels/esfragbag"
+)
+
+// LibraryFingerprint returns a hex encoded SHA-1 hash of the serialized
+// fragment library given. Unlike its name, the fingerprint changes whenever
+// any fragment of the library changes.
+func LibraryFingerprint(lib fragbag.Library) string {
+	h := sha1.New()
+	Assert(lib.Save(h), "Could not compute fingerprint of library '%s'",
+		lib.Name())
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -8,6 +8,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/TuftsBCB/apps/hhsuite"
 	"github.com/TuftsBCB/hhfrag"
@@ -27,7 +28,42 @@ var (
 
 	HHfragConf = hhfrag.DefaultConfig
 
-	FlagQuiet = false
+	flagLog      = LogInfo.String()
+	FlagLogLevel = LogInfo
+
+	flagVerbose = false
+
+	flagAlphabet = ""
+	FlagAlphabet Alphabet
+
+	flagMask = ""
+	FlagMask Masker
+
+	FlagSource = ""
+
+	FlagProgressInterval = 200 * time.Millisecond
+
+	FlagAltLoc = AltLocFirst
+
+	FlagHetBreaks = false
+
+	FlagSeed = int64(1)
+
+	FlagSparse = false
+	FlagNorms  = false
+
+	flagResidueMap = ""
+	FlagResidueMap ResidueMap
+)
+
+// Sources of the amino acid sequence of a chain in a structure file.
+const (
+	// SourceEntity is the declared sequence of the chain (e.g., SEQRES
+	// records), including residues without coordinates.
+	SourceEntity = "entity"
+
+	// SourceAtom is the sequence of residues with coordinates.
+	SourceAtom = "atom"
 )
 
 func init() {
@@ -109,14 +145,163 @@ var commonFlags = map[string]*commonFlag{
 				"The sliding window increment for HHfrag.")
 		},
 	},
+	"alphabet": {
+		set: func() {
+			flag.StringVar(&flagAlphabet, "alphabet", flagAlphabet,
+				"When set, every sequence is remapped through the alphabet\n"+
+					"in this file before computing sequence BOWs. Each line\n"+
+					"has the form 'residues residue' (e.g., 'AVLIMFWC H').")
+		},
+		init: func() {
+			if len(flagAlphabet) > 0 {
+				FlagAlphabet = ReadAlphabet(flagAlphabet)
+			}
+		},
+	},
+	"mask": {
+		set: func() {
+			flag.StringVar(&flagMask, "mask", flagMask,
+				"When set, residues are masked (replaced with 'X') before\n"+
+					"computing sequence BOWs. Legal values are 'lower', which\n"+
+					"masks lowercase residues, and 'seg', which also masks\n"+
+					"low complexity regions.")
+		},
+		init: func() {
+			var err error
+			FlagMask, err = ParseMasker(flagMask)
+			Assert(err)
+		},
+	},
+	"progress-interval": {
+		set: func() {
+			flag.DurationVar(&FlagProgressInterval, "progress-interval",
+				FlagProgressInterval,
+				"The minimum time between updates of the progress line.\n"+
+					"Every job is still counted, and the final count is always\n"+
+					"shown.")
+		},
+	},
+	"altloc": {
+		set: func() {
+			flag.StringVar(&FlagAltLoc, "altloc", FlagAltLoc,
+				"How atoms with alternate locations in PDB files are handled.\n"+
+					"Legal values are 'first', which keeps only the first\n"+
+					"location of each atom (the 'A' location in PDB files), and\n"+
+					"'all', which keeps every location.")
+		},
+		init: func() {
+			if FlagAltLoc != AltLocFirst && FlagAltLoc != AltLocAll {
+				FatalfCode(ExitUsage, "Unknown altloc handling '%s'. Legal "+
+					"values are 'first' and 'all'.", FlagAltLoc)
+			}
+		},
+	},
+	"het-breaks": {
+		set: func() {
+			flag.BoolVar(&FlagHetBreaks, "het-breaks", FlagHetBreaks,
+				"When set, non-standard residues in a chain (e.g.,\n"+
+					"ligands and ions) are treated as chain breaks, so that\n"+
+					"no fragment window spans one. Their alpha-carbon atoms\n"+
+					"(e.g., a calcium ion named 'CA') are never used.")
+		},
+	},
+	"residue-map": {
+		set: func() {
+			flag.StringVar(&flagResidueMap, "residue-map", flagResidueMap,
+				"When set, three letter residue codes are mapped to one\n"+
+					"letter amino acid codes with the file given, where each\n"+
+					"line has the form 'code residue' (e.g., 'MSE M'). These\n"+
+					"mappings override the built-in ones. Residues without a\n"+
+					"mapping are written as 'X' with a warning.")
+		},
+		init: func() {
+			if len(flagResidueMap) > 0 {
+				FlagResidueMap = LoadResidueMap(flagResidueMap)
+			}
+		},
+	},
+	"seed": {
+		set: func() {
+			flag.Int64Var(&FlagSeed, "seed", FlagSeed,
+				"The seed of the random number generator used for sampling.\n"+
+					"The same seed always gives the same results. When 0, a\n"+
+					"seed is chosen from the current time and reported with\n"+
+					"'-log info'.")
+		},
+		init: seedRand,
+	},
+	"sparse": {
+		set: func() {
+			flag.BoolVar(&FlagSparse, "sparse", FlagSparse,
+				"When set, GOB and JSON encoded BOWs are written in a sparse\n"+
+					"format that only stores the frequencies of fragments\n"+
+					"that occur, along with the size of the library. This is\n"+
+					"smaller when most fragments do not occur. Sparse BOWs\n"+
+					"are read like any other BOW.")
+		},
+	},
+	"norms": {
+		set: func() {
+			flag.BoolVar(&FlagNorms, "norms", FlagNorms,
+				"When set, the L2 norm of each BOW is stored in GOB and\n"+
+					"JSON encoded BOW files, so that it is not computed\n"+
+					"again every time the BOW is compared (e.g., by\n"+
+					"'bow-dist').")
+		},
+	},
+	"source": {
+		set: func() {
+			flag.StringVar(&FlagSource, "source", FlagSource,
+				"The source of the amino acid sequence of each chain in a\n"+
+					"structure file. Legal values are 'entity', the declared\n"+
+					"sequence (e.g., SEQRES), and 'atom', the residues with\n"+
+					"coordinates. By default, 'entity' is used with\n"+
+					"sequence libraries and 'atom' with structure libraries,\n"+
+					"whose BOWs are always computed from the residues with\n"+
+					"coordinates. Use 'atom' with a sequence library when\n"+
+					"its BOWs must be comparable to structure BOWs. When\n"+
+					"'entity' is used and a chain has no declared sequence,\n"+
+					"'atom' is used for that chain.")
+		},
+		init: func() {
+			switch FlagSource {
+			case "", SourceEntity, SourceAtom:
+			default:
+				FatalfCode(ExitUsage, "Unknown sequence source '%s'. Legal "+
+					"values are 'entity' and 'atom'.", FlagSource)
+			}
+		},
+	},
+	// Deprecated in favor of "-log info". Tools using this flag hide
+	// diagnostic output by default.
 	"verbose": {
 		set: func() {
-			flag.BoolVar(&FlagQuiet, "verbose", !FlagQuiet,
-				"When set, diagnostic output will be shown on stderr.")
+			flag.BoolVar(&flagVerbose, "verbose", flagVerbose,
+				"When set, diagnostic output will be shown on stderr.\n"+
+					"(Deprecated. Use '-log info' instead.)")
+		},
+		init: func() {
+			if flagVerbose {
+				if FlagLogLevel < LogInfo {
+					FlagLogLevel = LogInfo
+				}
+			} else if !isFlagSet("log") {
+				FlagLogLevel = LogWarn
+			}
 		},
 	},
 }
 
+// isFlagSet returns true if the flag with the given name was set on the
+// command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(fl *flag.Flag) {
+		set = set || fl.Name == name
+	})
+	return set
+}
+
 func FlagUse(names ...string) {
 	for _, name := range names {
 		commonFlags[name].use = true
@@ -147,6 +332,9 @@ func NArg() int {
 }
 
 func FlagParse(positional string, desc string) {
+	flag.StringVar(&flagLog, "log", flagLog,
+		"The minimum severity of messages shown on stderr. Legal values\n"+
+			"are error, warn, info and debug.")
 	for _, fl := range commonFlags {
 		if fl.use {
 			fl.set()
@@ -169,10 +357,14 @@ func FlagParse(positional string, desc string) {
 			log.Printf("-%s%s\n", fl.Name, def)
 			log.Printf("    %s\n", usage)
 		})
-		os.Exit(1)
+		os.Exit(ExitUsage)
 	}
 	flag.Parse()
 
+	lvl, err := ParseLogLevel(flagLog)
+	Assert(err)
+	FlagLogLevel = lvl
+
 	for _, fl := range commonFlags {
 		if fl.use && fl.init != nil {
 			fl.init()
@@ -0,0 +1,17 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/ndaniels/esfragbag"
+)
+
+// CheckFragmentSize returns an error if an input with `n` residues is too
+// short to contain a single fragment of the library given.
+func CheckFragmentSize(lib fragbag.Library, n int) error {
+	if n < lib.FragmentSize() {
+		return fmt.Errorf("input has %d residues, library '%s' requires at "+
+			"least %d", n, lib.Name(), lib.FragmentSize())
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// CaSegment is a half-open range of indices into the alpha-carbon atoms of a
+// model (as returned by `CaAtoms`).
+type CaSegment struct {
+	Start, End int
+}
+
+// IsStandardResidue returns false if the residue given is a hetero residue
+// (e.g., a ligand or an ion) that is not one of the 20 standard amino acids.
+// Modified amino acids that the PDB reader maps to a standard amino acid
+// (e.g., selenomethionine) are considered standard.
+func IsStandardResidue(r *pdb.Residue) bool {
+	het := false
+	for _, atom := range r.Atoms {
+		het = het || atom.Het
+	}
+	if !het {
+		return true
+	}
+	switch r.Name {
+	case 'A', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'K', 'L',
+		'M', 'N', 'P', 'Q', 'R', 'S', 'T', 'V', 'W', 'Y':
+		return true
+	}
+	return false
+}
+
+// CaSegments returns the runs of alpha-carbon atoms of the model given that
+// may be used for fragment windows. When FlagHetBreaks is not set, this is a
+// single segment with every alpha-carbon atom.
+//
+// Otherwise, non-standard residues (see IsStandardResidue) are treated as
+// chain breaks. Namely, their alpha-carbon atoms (e.g., a calcium ion named
+// 'CA') are excluded, and no segment spans a non-standard residue, even if
+// it has no alpha-carbon atom.
+func CaSegments(m *pdb.Model) []CaSegment {
+	natoms := len(m.CaAtoms())
+	if !FlagHetBreaks {
+		return []CaSegment{{0, natoms}}
+	}
+
+	segs := make([]CaSegment, 0, 1)
+	start, i := 0, 0
+	for _, r := range m.Residues {
+		nca := 0
+		for _, atom := range r.Atoms {
+			if atom.Name == "CA" {
+				nca++
+			}
+		}
+		if IsStandardResidue(r) {
+			i += nca
+			continue
+		}
+		if i > start {
+			segs = append(segs, CaSegment{start, i})
+		}
+		i += nca
+		start = i
+	}
+	if i > start {
+		segs = append(segs, CaSegment{start, i})
+	}
+	if i != natoms {
+		Warnf("Could not find the residues of the %d alpha-carbon atoms of "+
+			"model %d. Non-standard residues are not treated as breaks.",
+			natoms, m.Num)
+		return []CaSegment{{0, natoms}}
+	}
+	return segs
+}
+
+// InCaSegments returns true if the window of alpha-carbon atoms [start, end)
+// is entirely within one of the segments given.
+func InCaSegments(segs []CaSegment, start, end int) bool {
+	for _, seg := range segs {
+		if start >= seg.Start && end <= seg.End {
+			return true
+		}
+	}
+	return false
+}
+
+// StructureBowerFromChain is like bow.BowerFromChain, except fragment
+// windows respect FlagHetBreaks (see CaSegments). Only the first model of
+// the chain is used.
+func StructureBowerFromChain(chain *pdb.Chain) bow.StructureBower {
+	if !FlagHetBreaks {
+		return bow.BowerFromChain(chain)
+	}
+	return segmentBower{bow.BowerFromChain(chain), chain.Models[0]}
+}
+
+// StructureBowerFromModel is like bow.BowerFromModel, except fragment
+// windows respect FlagHetBreaks (see CaSegments).
+func StructureBowerFromModel(m *pdb.Model) bow.StructureBower {
+	if !FlagHetBreaks {
+		return bow.BowerFromModel(m)
+	}
+	return segmentBower{bow.BowerFromModel(m), m}
+}
+
+// segmentBower computes a structure BOW from only the windows of a model
+// that are within its alpha-carbon segments. Its id and data are those of
+// the bower it wraps.
+type segmentBower struct {
+	bow.StructureBower
+	model *pdb.Model
+}
+
+func (b segmentBower) StructureBow(lib fragbag.StructureLibrary) bow.Bowed {
+	return bow.Bowed{
+		Id:   b.Id(),
+		Data: b.Data(),
+		Bow:  segmentBow(lib, b.model.CaAtoms(), CaSegments(b.model)),
+	}
+}
+
+// segmentBow counts the best fragment of every window of `atoms` that is
+// within one of the segments given.
+func segmentBow(
+	lib fragbag.StructureLibrary,
+	atoms []structure.Coords,
+	segs []CaSegment,
+) bow.Bow {
+	b := bow.NewBow(lib.Size())
+	fsize := lib.FragmentSize()
+	for _, seg := range segs {
+		for i := seg.Start; i+fsize <= seg.End; i++ {
+			b.Freqs[lib.BestStructureFragment(atoms[i:i+fsize])]++
+		}
+	}
+	return b
+}
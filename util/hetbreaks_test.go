@@ -0,0 +1,99 @@
+package util
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/seq"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+)
+
+// triLib is a structure library of one fragment of three residues.
+type triLib struct{}
+
+func (triLib) Save(w io.Writer) error      { return nil }
+func (triLib) Size() int                   { return 1 }
+func (triLib) FragmentSize() int           { return 3 }
+func (triLib) String() string              { return "tri" }
+func (triLib) Name() string                { return "tri" }
+func (triLib) Tag() string                 { return "structure" }
+func (triLib) Fragment(i int) interface{}  { return nil }
+func (triLib) SubLibrary() fragbag.Library { return nil }
+
+func (triLib) BestStructureFragment([]structure.Coords) int { return 0 }
+func (triLib) Atoms(i int) []structure.Coords               { return nil }
+
+// ligandModel returns a model of nine amino acids with a ligand intercalated
+// after the third and a calcium ion (whose atom is named 'CA') after the
+// fifth, which is a selenomethionine.
+func ligandModel() *pdb.Model {
+	m := &pdb.Model{Num: 1}
+	add := func(name seq.Residue, het bool, atoms ...string) {
+		r := &pdb.Residue{Name: name, SequenceNum: len(m.Residues) + 1}
+		for _, atom := range atoms {
+			r.Atoms = append(r.Atoms, pdb.Atom{Name: atom, Het: het})
+		}
+		m.Residues = append(m.Residues, r)
+	}
+	for i := 0; i < 3; i++ {
+		add('A', false, "N", "CA", "C")
+	}
+	add('X', true, "C1", "N1", "O1")
+	add('G', false, "N", "CA", "C")
+	add('M', true, "N", "CA", "C", "SE")
+	add('X', true, "CA")
+	for i := 0; i < 4; i++ {
+		add('L', false, "N", "CA", "C")
+	}
+	return m
+}
+
+func TestCaSegmentsLigand(t *testing.T) {
+	defer func() { FlagHetBreaks = false }()
+	m := ligandModel()
+	lib := triLib{}
+
+	FlagHetBreaks = false
+	if segs := CaSegments(m); !reflect.DeepEqual(segs, []CaSegment{{0, 10}}) {
+		t.Errorf("without -het-breaks, got segments %v, want [{0 10}]", segs)
+	}
+	b := StructureBowerFromModel(m).StructureBow(lib)
+	if b.Bow.Freqs[0] != 8 {
+		t.Errorf("without -het-breaks, got %v windows, want 8",
+			b.Bow.Freqs[0])
+	}
+
+	// The ligand splits the first segment from the second. The calcium
+	// ion's alpha-carbon atom (index 5) is in neither.
+	FlagHetBreaks = true
+	segs := CaSegments(m)
+	want := []CaSegment{{0, 3}, {3, 5}, {6, 10}}
+	if !reflect.DeepEqual(segs, want) {
+		t.Fatalf("got segments %v, want %v", segs, want)
+	}
+	windows := []struct {
+		start, end int
+		in         bool
+	}{
+		{0, 3, true},
+		{1, 4, false},
+		{3, 5, true},
+		{3, 6, false},
+		{4, 7, false},
+		{6, 9, true},
+		{7, 10, true},
+	}
+	for _, w := range windows {
+		if in := InCaSegments(segs, w.start, w.end); in != w.in {
+			t.Errorf("InCaSegments(%d, %d) = %v, want %v",
+				w.start, w.end, in, w.in)
+		}
+	}
+	b = StructureBowerFromModel(m).StructureBow(lib)
+	if b.Bow.Freqs[0] != 3 {
+		t.Errorf("with -het-breaks, got %v windows, want 3", b.Bow.Freqs[0])
+	}
+}
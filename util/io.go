@@ -2,6 +2,7 @@ package util
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -18,6 +19,25 @@ func ReadLines(r io.Reader) []string {
 	return lines
 }
 
+// ReadLinesFile returns the lines of the file at `path`, which may be gzip
+// compressed, for files that list one item (e.g., an id or a path) per line.
+// Surrounding whitespace is removed from each line, and blank lines and lines
+// starting with '#' are skipped.
+func ReadLinesFile(path string) []string {
+	f := OpenMaybeGz(path)
+	defer f.Close()
+
+	lines := make([]string, 0)
+	for _, line := range ReadLines(f) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 func CopyFile(src, dest string) {
 	_, err := io.Copy(CreateFile(dest), OpenFile(src))
 	Assert(err, "Could not copy '%s' to '%s'", src, dest)
@@ -43,35 +63,136 @@ func AssertOverwritable(path string, overwritable bool) {
 	}
 }
 
+// AllFilesFromArgs is like AllFilesFromArgsErr, except each error is emitted
+// as a warning.
 func AllFilesFromArgs(fileArgs []string) []string {
+	files, errs := AllFilesFromArgsErr(fileArgs)
+	for _, err := range errs {
+		Warnf("%s", err)
+	}
+	return files
+}
+
+// AllFilesFromArgsErr expands a list of file arguments into a list of files.
+// Directories are replaced by every file in them, recursively. Arguments that
+// do not exist but contain shell glob characters ('*', '?' or '[') are
+// expanded with filepath.Glob, and each match is expanded in turn.
+//
+// Other arguments that do not exist are included unchanged, since they may
+// use special syntax understood by the reader (e.g., "1ctf.ent.gz:A" or a
+// PDB identifier). An error is returned for each argument that exists but
+// cannot be opened for reading, and for each glob that is malformed or
+// matches nothing. Such arguments are not included.
+func AllFilesFromArgsErr(fileArgs []string) ([]string, []error) {
 	files := make([]string, 0)
-	for _, fordir := range fileArgs {
-		var more []string
-		if IsDir(fordir) {
-			more = RecursiveFiles(fordir)
-		} else {
-			more = []string{fordir}
+	var errs []error
+	for _, arg := range fileArgs {
+		fi, err := os.Stat(arg)
+		switch {
+		case err == nil && fi.IsDir():
+			files = append(files, RecursiveFiles(arg)...)
+		case err == nil:
+			f, err := os.Open(arg)
+			if err != nil {
+				err = fmt.Errorf("Could not read '%s': %s", arg, err)
+				errs = append(errs, err)
+				continue
+			}
+			f.Close()
+			files = append(files, arg)
+		case !os.IsNotExist(err):
+			err = fmt.Errorf("Could not read '%s': %s", arg, err)
+			errs = append(errs, err)
+		case strings.ContainsAny(arg, "*?["):
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				err = fmt.Errorf("Invalid glob '%s': %s", arg, err)
+				errs = append(errs, err)
+				continue
+			}
+			if len(matches) == 0 {
+				errs = append(errs, fmt.Errorf("No files match '%s'.", arg))
+				continue
+			}
+			more, moreErrs := AllFilesFromArgsErr(matches)
+			files = append(files, more...)
+			errs = append(errs, moreErrs...)
+		default:
+			files = append(files, arg)
 		}
-		files = append(files, more...)
 	}
-	return files
+	return files, errs
 }
 
 func RecursiveFiles(dir string) []string {
+	return RecursiveFilesFiltered(dir, WalkOptions{})
+}
+
+// WalkOptions controls which files are returned by RecursiveFilesFiltered.
+type WalkOptions struct {
+	// When set, files and directories whose names start with a '.' are
+	// skipped.
+	SkipHidden bool
+
+	// When set, symbolic links to directories are followed. (Symbolic links
+	// to files are always included.) Each directory is visited at most once,
+	// so cycles are not a problem.
+	FollowSymlinks bool
+}
+
+// RecursiveFilesFiltered returns every file in the directory given,
+// recursively. Directories themselves are not included.
+func RecursiveFilesFiltered(dir string, opts WalkOptions) []string {
 	if !strings.HasSuffix(dir, "/") {
 		dir = dir + "/"
 	}
 	files := make([]string, 0)
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			Warnf("Could not read '%s' because: %s\n", path, err)
-			return nil
+	visited := make(map[string]bool)
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if visited[real] {
+				return
+			}
+			visited[real] = true
 		}
-		if info.IsDir() {
+
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				Warnf("Could not read '%s' because: %s\n", path, err)
+				return nil
+			}
+			hidden := strings.HasPrefix(info.Name(), ".") && path != dir
+			if info.IsDir() {
+				if opts.SkipHidden && hidden {
+					return filepath.SkipDir
+				}
+				// Directories may also be reached through followed links.
+				if opts.FollowSymlinks && path != dir {
+					real, err := filepath.EvalSymlinks(path)
+					if err == nil {
+						if visited[real] {
+							return filepath.SkipDir
+						}
+						visited[real] = true
+					}
+				}
+				return nil
+			}
+			if opts.SkipHidden && hidden {
+				return nil
+			}
+			if opts.FollowSymlinks && info.Mode()&os.ModeSymlink != 0 {
+				if IsDir(path) {
+					walk(path + "/")
+					return nil
+				}
+			}
+			files = append(files, path)
 			return nil
-		}
-		files = append(files, path)
-		return nil
-	})
+		})
+	}
+	walk(dir)
 	return files
 }
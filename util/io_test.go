@@ -0,0 +1,173 @@
+package util
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAllFilesFromArgsErr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := func(name string) string { return filepath.Join(dir, name) }
+	if err := os.Mkdir(p("sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.pdb", "b.pdb", "c.fasta", "sub/d.pdb"} {
+		if err := ioutil.WriteFile(p(name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+		errs  int
+	}{
+		{"file", []string{p("a.pdb")}, []string{p("a.pdb")}, 0},
+		{"directory", []string{p("sub")}, []string{p("sub/d.pdb")}, 0},
+		{"glob", []string{p("*.pdb")}, []string{p("a.pdb"), p("b.pdb")}, 0},
+		{"glob-dirs", []string{p("s*")}, []string{p("sub/d.pdb")}, 0},
+		{"no-match", []string{p("*.ent")}, nil, 1},
+		{"bad-glob", []string{p("[")}, nil, 1},
+		{"missing", []string{p("x.pdb"), "1ctf.ent.gz:A"},
+			[]string{p("x.pdb"), "1ctf.ent.gz:A"}, 0},
+	}
+	for _, test := range tests {
+		files, errs := AllFilesFromArgsErr(test.args)
+		sort.Strings(files)
+		if len(files) == 0 {
+			files = nil
+		}
+		if !reflect.DeepEqual(files, test.files) {
+			t.Errorf("%s: got files %q, want %q", test.name, files, test.files)
+		}
+		if len(errs) != test.errs {
+			t.Errorf("%s: got errors %v, want %d", test.name, errs, test.errs)
+		}
+	}
+
+	if os.Geteuid() == 0 {
+		return // root can read any file
+	}
+	if err := os.Chmod(p("c.fasta"), 0); err != nil {
+		t.Fatal(err)
+	}
+	files, errs := AllFilesFromArgsErr([]string{p("c.fasta"), p("a.pdb")})
+	if !reflect.DeepEqual(files, []string{p("a.pdb")}) || len(errs) != 1 {
+		t.Errorf("unreadable file: got files %q and errors %v", files, errs)
+	}
+}
+
+func TestReadLinesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := "# PDB chains\n1ctfA\n\n  2abcB  \n\t\n  # indented comment\n" +
+		"3xyz.ent.gz:C\r\n4def#D\n"
+	want := []string{"1ctfA", "2abcB", "3xyz.ent.gz:C", "4def#D"}
+
+	plain := filepath.Join(dir, "chains")
+	if err := ioutil.WriteFile(plain, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gzipped := filepath.Join(dir, "chains.gz")
+	w := CreateFileMaybeGz(gzipped)
+	if _, err := io.WriteString(w, list); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fpath := range []string{plain, gzipped} {
+		if lines := ReadLinesFile(fpath); !reflect.DeepEqual(lines, want) {
+			t.Errorf("%s: got %q, want %q", filepath.Base(fpath), lines, want)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty")
+	err = ioutil.WriteFile(empty, []byte("# nothing\n\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := ReadLinesFile(empty); len(lines) != 0 {
+		t.Errorf("empty: got %q, want no lines", lines)
+	}
+}
+
+func TestRecursiveFilesFiltered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := func(name string) string { return filepath.Join(dir, name) }
+	for _, sub := range []string{".git", "sub"} {
+		if err := os.Mkdir(p(sub), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []string{"a.pdb", ".hidden.pdb", ".git/config", "sub/b.pdb",
+		"sub/.c.pdb"}
+	for _, name := range files {
+		if err := ioutil.WriteFile(p(name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 'link' is a second way into 'sub', and 'sub/loop' leads back to the
+	// top directory.
+	links := map[string]string{"link": "sub", "file.pdb": "a.pdb",
+		"sub/loop": ".."}
+	for name, target := range links {
+		if err := os.Symlink(target, p(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		opts  WalkOptions
+		files []string
+	}{
+		{"default", WalkOptions{}, []string{".git/config", ".hidden.pdb",
+			"a.pdb", "file.pdb", "link", "sub/.c.pdb", "sub/b.pdb",
+			"sub/loop"}},
+		{"skip-hidden", WalkOptions{SkipHidden: true}, []string{"a.pdb",
+			"file.pdb", "link", "sub/b.pdb", "sub/loop"}},
+		// 'link' is walked before 'sub', so 'sub' is not walked again.
+		{"follow", WalkOptions{FollowSymlinks: true}, []string{
+			".git/config", ".hidden.pdb", "a.pdb", "file.pdb",
+			"link/.c.pdb", "link/b.pdb"}},
+		{"follow-skip-hidden",
+			WalkOptions{SkipHidden: true, FollowSymlinks: true},
+			[]string{"a.pdb", "file.pdb", "link/b.pdb"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, fpath := range RecursiveFilesFiltered(dir, test.opts) {
+			rel, err := filepath.Rel(dir, fpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, rel)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.files) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.files)
+		}
+	}
+}
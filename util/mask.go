@@ -0,0 +1,114 @@
+package util
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/TuftsBCB/seq"
+)
+
+// MaskResidue is the residue that masked residues are replaced with.
+const MaskResidue seq.Residue = 'X'
+
+const (
+	// segWindow is the size of the window used to find low complexity
+	// regions.
+	segWindow = 12
+
+	// segThreshold is the Shannon entropy (in bits) below which a window is
+	// considered to have low complexity. This corresponds to the trigger
+	// complexity of SEG's default parameters.
+	segThreshold = 2.2
+)
+
+// Masker replaces residues in a sequence that should not contribute to its
+// BOW with MaskResidue.
+type Masker func(s seq.Sequence) seq.Sequence
+
+// ParseMasker returns the masker with the name given. Legal names are
+// "lower", which masks lowercase residues (i.e., pre-masked input), and
+// "seg", which also masks low complexity regions. An empty name returns a
+// nil masker.
+func ParseMasker(name string) (Masker, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "lower":
+		return MaskLowercase, nil
+	case "seg":
+		return func(s seq.Sequence) seq.Sequence {
+			return MaskLowComplexity(MaskLowercase(s))
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown masking method '%s'.", name)
+}
+
+// Apply returns the sequence given with its residues masked. If the masker
+// is nil, the sequence is returned unchanged.
+func (m Masker) Apply(s seq.Sequence) seq.Sequence {
+	if m == nil {
+		return s
+	}
+	return m(s)
+}
+
+// MaskLowercase returns a copy of the sequence given where every lowercase
+// residue is replaced with MaskResidue.
+func MaskLowercase(s seq.Sequence) seq.Sequence {
+	residues := make([]seq.Residue, len(s.Residues))
+	for i, r := range s.Residues {
+		if r >= 'a' && r <= 'z' {
+			residues[i] = MaskResidue
+		} else {
+			residues[i] = r
+		}
+	}
+	s.Residues = residues
+	return s
+}
+
+// MaskLowComplexity returns a copy of the sequence given where every residue
+// in a low complexity region is replaced with MaskResidue. In the spirit of
+// SEG, a region has low complexity if it is covered by windows of 12
+// residues whose Shannon entropy is less than 2.2 bits. Masked residues
+// already in the sequence are not counted in a window's entropy.
+func MaskLowComplexity(s seq.Sequence) seq.Sequence {
+	residues := make([]seq.Residue, len(s.Residues))
+	copy(residues, s.Residues)
+	for start := 0; start+segWindow <= len(s.Residues); start++ {
+		window := s.Residues[start : start+segWindow]
+		if entropy(window) < segThreshold {
+			for i := start; i < start+segWindow; i++ {
+				residues[i] = MaskResidue
+			}
+		}
+	}
+	s.Residues = residues
+	return s
+}
+
+// entropy returns the Shannon entropy (in bits) of the residue composition
+// of the window given, ignoring masked residues. A window with only masked
+// residues has maximal entropy so that it is not masked again.
+func entropy(window []seq.Residue) float64 {
+	var counts [256]int
+	total := 0
+	for _, r := range window {
+		if r != MaskResidue {
+			counts[r]++
+			total++
+		}
+	}
+	if total == 0 {
+		return math.Inf(1)
+	}
+
+	h := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(total)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
@@ -0,0 +1,216 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/TuftsBCB/io/msa"
+	"github.com/TuftsBCB/seq"
+)
+
+type (
+	MSAReader func(io.Reader) (seq.MSA, error)
+	MSAWriter func(io.Writer, seq.MSA) error
+	MSAFormat struct {
+		Name  string
+		Read  MSAReader
+		Write MSAWriter
+
+		// Exts are the file extensions (without the leading '.') of the
+		// format. The first is used when naming new files.
+		Exts []string
+	}
+)
+
+var (
+	// MSAExtToFormat maps file extensions (without the leading '.') to the
+	// name of the MSA format they correspond to.
+	MSAExtToFormat = map[string]string{}
+
+	// MSAFormats maps the name of each MSA format to its reader and writer.
+	MSAFormats = map[string]MSAFormat{}
+)
+
+func init() {
+	RegisterMSAFormat("fasta", []string{"fasta", "fa", "fas", "ali"},
+		msa.ReadFasta, msa.WriteFasta)
+	RegisterMSAFormat("stockholm", []string{"sto"},
+		msa.ReadStockholm, msa.WriteStockholm)
+	RegisterMSAFormat("a2m", []string{"a2m"}, msa.Read, msa.WriteA2M)
+	RegisterMSAFormat("a3m", []string{"a3m"}, msa.Read, msa.WriteA3M)
+}
+
+// RegisterMSAFormat adds an MSA format to the registry used by tools that
+// read and write MSAs. Each extension in `exts` (without the leading '.')
+// will be detected as the format `name`. Registering a name or an extension
+// that already exists overwrites the previous entry.
+func RegisterMSAFormat(name string, exts []string, r MSAReader, w MSAWriter) {
+	MSAFormats[name] = MSAFormat{name, r, w, exts}
+	for _, ext := range exts {
+		MSAExtToFormat[ext] = name
+	}
+}
+
+// MSAFormatFromFile returns the MSA format for the file path given based on
+// its extension. If `force` is non-empty, then it is used as the format name
+// instead.
+func MSAFormatFromFile(fpath, force string) MSAFormat {
+	format, err := MSAFormatDetect(fpath, force)
+	Assert(err)
+	return format
+}
+
+// MSAFormatDetect is like MSAFormatFromFile, except an error is returned if
+// the format could not be determined.
+func MSAFormatDetect(fpath, force string) (MSAFormat, error) {
+	var name string
+	if len(force) > 0 {
+		name = force
+	} else {
+		var ok bool
+		ext := path.Ext(fpath)
+		if len(ext) > 0 {
+			ext = ext[1:]
+		}
+
+		name, ok = MSAExtToFormat[ext]
+		if !ok {
+			return MSAFormat{}, fmt.Errorf(
+				"Could not detect format from extension '%s'.", ext)
+		}
+	}
+
+	format, ok := MSAFormats[name]
+	if !ok {
+		return MSAFormat{}, fmt.Errorf(
+			"Could not find converters for format '%s'.", name)
+	}
+	return format, nil
+}
+
+// StockholmGC is a per-column annotation of an MSA in Stockholm format. It is
+// written as a '#=GC {Feature} {Annotation}' line, where the annotation must
+// have one character for each column.
+type StockholmGC struct {
+	Feature    string
+	Annotation string
+}
+
+// StockholmMeta holds the annotations of an MSA in Stockholm format that
+// are not kept by seq.MSA.
+type StockholmMeta struct {
+	// GF are the per-file annotations, in the order they were read.
+	GF []StockholmGF
+
+	// GS are the per-sequence annotations, in the order they were read.
+	GS []StockholmGS
+}
+
+// StockholmGF is written as a '#=GF {Feature} {Text}' line.
+type StockholmGF struct {
+	Feature string
+	Text    string
+}
+
+// StockholmGS is written as a '#=GS {Name} {Feature} {Text}' line.
+type StockholmGS struct {
+	Name    string
+	Feature string
+	Text    string
+}
+
+// Len returns the total number of annotations.
+func (meta StockholmMeta) Len() int {
+	return len(meta.GF) + len(meta.GS)
+}
+
+// ReadStockholmMeta reads the '#=GF' and '#=GS' lines of the first
+// alignment in the Stockholm file in `r`. Other lines are ignored, so this
+// is used alongside msa.ReadStockholm.
+func ReadStockholmMeta(r io.Reader) (StockholmMeta, error) {
+	var meta StockholmMeta
+	for i, line := range ReadLines(r) {
+		if strings.TrimSpace(line) == "//" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "#=GF "):
+			fields := strings.SplitN(strings.TrimSpace(line[5:]), " ", 2)
+			if len(fields) < 2 {
+				fields = append(fields, "")
+			}
+			meta.GF = append(meta.GF, StockholmGF{
+				Feature: fields[0],
+				Text:    strings.TrimSpace(fields[1]),
+			})
+		case strings.HasPrefix(line, "#=GS "):
+			fields := strings.Fields(line[5:])
+			if len(fields) < 2 {
+				return StockholmMeta{}, fmt.Errorf(
+					"Expected '#=GS name feature text' on line %d but got "+
+						"'%s'.", i+1, line)
+			}
+			rest := strings.TrimSpace(line[5:])
+			for _, f := range fields[:2] {
+				rest = strings.TrimSpace(strings.TrimPrefix(rest, f))
+			}
+			meta.GS = append(meta.GS, StockholmGS{
+				Name:    fields[0],
+				Feature: fields[1],
+				Text:    rest,
+			})
+		}
+	}
+	return meta, nil
+}
+
+// WriteStockholmGC writes the MSA given in Stockholm format along with the
+// per-column annotations given. The annotations are written immediately
+// before the '//' terminator.
+func WriteStockholmGC(w io.Writer, m seq.MSA, gcs []StockholmGC) error {
+	return WriteStockholmMeta(w, m, StockholmMeta{}, gcs)
+}
+
+// WriteStockholmMeta is like WriteStockholmGC, except the per-file and
+// per-sequence annotations in `meta` are also written immediately after the
+// Stockholm header.
+func WriteStockholmMeta(
+	w io.Writer,
+	m seq.MSA,
+	meta StockholmMeta,
+	gcs []StockholmGC,
+) error {
+	buf := new(bytes.Buffer)
+	if err := msa.WriteStockholm(buf, m); err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if meta.Len() > 0 && !strings.HasPrefix(lines[0], "# STOCKHOLM") {
+		return fmt.Errorf("Could not find the Stockholm header.")
+	}
+	if len(lines) < 2 || strings.TrimSpace(lines[len(lines)-1]) != "//" {
+		return fmt.Errorf("Could not find the end of the Stockholm alignment.")
+	}
+
+	out := []string{lines[0]}
+	for _, gf := range meta.GF {
+		out = append(out, fmt.Sprintf("#=GF %s %s", gf.Feature, gf.Text))
+	}
+	for _, gs := range meta.GS {
+		line := fmt.Sprintf("#=GS %s %s %s", gs.Name, gs.Feature, gs.Text)
+		out = append(out, line)
+	}
+	out = append(out, lines[1:len(lines)-1]...)
+	for _, gc := range gcs {
+		line := fmt.Sprintf("#=GC %s %s", gc.Feature, gc.Annotation)
+		out = append(out, line)
+	}
+	out = append(out, lines[len(lines)-1])
+
+	_, err := io.WriteString(w, strings.Join(out, "\n")+"\n")
+	return err
+}
@@ -1,32 +1,68 @@
 package util
 
+import (
+	"fmt"
+	"time"
+)
+
 type Progress struct {
-	errs chan error
-	done chan struct{}
+	errs  chan error
+	done  chan struct{}
+	start time.Time
+	stats ProgressStats
+}
+
+// ProgressStats summarizes the jobs reported to a Progress value.
+type ProgressStats struct {
+	Total, Completed, Errors int
+	Elapsed                  time.Duration
+}
+
+func (s ProgressStats) String() string {
+	return fmt.Sprintf("%d of %d jobs complete, %d errors, in %s",
+		s.Completed, s.Total, s.Errors, s.Elapsed)
 }
 
 func NewProgress(total int) *Progress {
-	p := &Progress{make(chan error), make(chan struct{})}
+	p := &Progress{
+		errs:  make(chan error),
+		done:  make(chan struct{}),
+		start: time.Now(),
+	}
+	p.stats.Total = total
 	go func() {
 		completed := 0
 		errorCount := 0
+		var lastPrint time.Time
+		show := func() {
+			ratio := 100.0 * (float64(completed) / float64(total))
+			Verbosef("\r%d of %d jobs complete (%0.2f%% done, %d errors)",
+				completed, total, ratio, errorCount)
+			lastPrint = time.Now()
+		}
 		for err := range p.errs {
 			if err == nil {
 				completed += 1
 			} else {
 				errorCount += 1
-				if FlagQuiet {
+				if FlagLogLevel < LogInfo || logJSON {
 					Warnf("%s", err)
 				} else {
 					Warnf("\r%s                                    \n", err)
 				}
 			}
 
-			ratio := 100.0 * (float64(completed) / float64(total))
-			Verbosef("\r%d of %d jobs complete (%0.2f%% done, %d errors)",
-				completed, total, ratio, errorCount)
+			// Errors are always shown immediately, so the progress line
+			// is redrawn after them too.
+			if err != nil || time.Since(lastPrint) >= FlagProgressInterval {
+				show()
+			}
 		}
+		show()
 		Verbosef("\n")
+
+		p.stats.Completed, p.stats.Errors = completed, errorCount
+		p.stats.Elapsed = time.Since(p.start)
 		p.done <- struct{}{}
 	}()
 	return p
@@ -39,6 +75,17 @@ func (p *Progress) JobDone(err error) {
 	p.errs <- err
 }
 
+// Verbosef logs a message at the info level on its own line so that it does
+// not clobber the progress line.
+func (p *Progress) Verbosef(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if p == nil || logJSON {
+		Verbosef("%s", msg)
+	} else {
+		Verbosef("\r%s                                    \n", msg)
+	}
+}
+
 func (p *Progress) Close() {
 	if p == nil {
 		return
@@ -46,3 +93,12 @@ func (p *Progress) Close() {
 	close(p.errs)
 	<-p.done
 }
+
+// Stats returns a summary of the jobs reported. It must only be called after
+// Close.
+func (p *Progress) Stats() ProgressStats {
+	if p == nil {
+		return ProgressStats{}
+	}
+	return p.stats
+}
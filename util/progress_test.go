@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// discardStderr sends progress and log output to /dev/null until the
+// function returned is called.
+func discardStderr(tb testing.TB) func() {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	stderr, logOut := os.Stderr, log.Writer()
+	os.Stderr = null
+	log.SetOutput(null)
+	return func() {
+		os.Stderr = stderr
+		log.SetOutput(logOut)
+		null.Close()
+	}
+}
+
+func TestProgressCountsEveryJob(t *testing.T) {
+	defer discardStderr(t)()
+
+	p := NewProgress(1000)
+	for i := 0; i < 1000; i++ {
+		if i%100 == 0 {
+			p.JobDone(errors.New("failed"))
+		} else {
+			p.JobDone(nil)
+		}
+	}
+	p.Close()
+	stats := p.Stats()
+	if stats.Total != 1000 || stats.Completed != 990 || stats.Errors != 10 {
+		t.Errorf("got %+v, want 990 of 1000 complete with 10 errors", stats)
+	}
+}
+
+// BenchmarkProgress reports a million no-op jobs, redrawing the progress
+// line on every job and at most every 200ms.
+func BenchmarkProgress(b *testing.B) {
+	const jobs = 1000000
+	intervals := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"every-job", 0},
+		{"throttled", 200 * time.Millisecond},
+	}
+	defer discardStderr(b)()
+	defer func(d time.Duration) { FlagProgressInterval = d }(
+		FlagProgressInterval)
+
+	for _, iv := range intervals {
+		b.Run(iv.name, func(b *testing.B) {
+			FlagProgressInterval = iv.interval
+			for i := 0; i < b.N; i++ {
+				p := NewProgress(jobs)
+				for j := 0; j < jobs; j++ {
+					p.JobDone(nil)
+				}
+				p.Close()
+			}
+		})
+	}
+}
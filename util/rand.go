@@ -0,0 +1,48 @@
+package util
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Rand is the random number generator shared by every tool that samples
+// its input. It is seeded with FlagSeed by the "seed" common flag, so that
+// the same seed always gives the same results.
+//
+// Rand draws from a mutex-guarded source, so it may be used concurrently
+// (except for its Read method). Note that concurrent callers may still
+// draw numbers in a different order from run to run.
+var Rand = rand.New(newLockedSource(FlagSeed))
+
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	sync.Mutex
+	src rand.Source
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.src.Seed(seed)
+}
+
+// seedRand seeds Rand with FlagSeed. When FlagSeed is 0, a seed is chosen
+// from the current time instead and reported.
+func seedRand() {
+	if FlagSeed == 0 {
+		FlagSeed = time.Now().UnixNano()
+		Verbosef("Using seed %d.", FlagSeed)
+	}
+	Rand = rand.New(newLockedSource(FlagSeed))
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/TuftsBCB/seq"
+)
+
+// ResidueMap maps three letter residue codes (e.g., "MSE") to one letter
+// amino acid codes.
+type ResidueMap map[string]seq.Residue
+
+// DefaultResidueMap maps the standard amino acids (and "UNK") to their one
+// letter codes, and common modified amino acids to the one letter code of
+// their parent amino acid.
+var DefaultResidueMap = ResidueMap{
+	"ALA": 'A', "ARG": 'R', "ASN": 'N', "ASP": 'D', "CYS": 'C',
+	"GLN": 'Q', "GLU": 'E', "GLY": 'G', "HIS": 'H', "ILE": 'I',
+	"LEU": 'L', "LYS": 'K', "MET": 'M', "PHE": 'F', "PRO": 'P',
+	"SER": 'S', "THR": 'T', "TRP": 'W', "TYR": 'Y', "VAL": 'V',
+	"UNK": 'X',
+
+	"MSE": 'M', "FME": 'M', "CXM": 'M',
+	"SEP": 'S', "TPO": 'T', "PTR": 'Y', "TYS": 'Y',
+	"CSO": 'C', "CSD": 'C', "CME": 'C', "CAS": 'C', "CSS": 'C', "OCS": 'C',
+	"MLY": 'K', "M3L": 'K', "KCX": 'K', "ALY": 'K', "LLP": 'K', "MLZ": 'K',
+	"HYP": 'P', "PCA": 'E', "CGU": 'E', "NEP": 'H', "HIC": 'H',
+	"AGM": 'R', "DAL": 'A', "AIB": 'A', "MEN": 'N', "SAC": 'S',
+}
+
+// LoadResidueMap reads a residue map from the file at `path`. Each line has
+// the form 'code residue' (e.g., 'MSE M'), where 'code' is a three letter
+// residue code and 'residue' is a one letter amino acid code. The mappings
+// are added to a copy of DefaultResidueMap, and override it. Codes are case
+// insensitive. Blank lines and lines starting with '#' are ignored.
+func LoadResidueMap(path string) ResidueMap {
+	f := OpenFile(path)
+	defer f.Close()
+
+	rm := make(ResidueMap, len(DefaultResidueMap))
+	for code, r := range DefaultResidueMap {
+		rm[code] = r
+	}
+	seen := make(map[string]bool)
+	for i, line := range ReadLines(f) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[1]) != 1 {
+			FatalfCode(ExitParse, "Expected 'code residue' on line %d "+
+				"in '%s' but got '%s'.", i+1, path, line)
+		}
+		code := strings.ToUpper(fields[0])
+		if seen[code] {
+			FatalfCode(ExitParse, "Residue '%s' is mapped more than once "+
+				"in '%s'.", code, path)
+		}
+		seen[code] = true
+		rm[code] = seq.Residue(strings.ToUpper(fields[1])[0])
+	}
+	return rm
+}
+
+var (
+	unmappedLock sync.Mutex
+	unmapped     = make(map[string]bool)
+)
+
+// Residue returns the one letter code of the three letter residue code
+// given. Residues without a mapping are 'X', and a warning is emitted the
+// first time each one is seen.
+func (rm ResidueMap) Residue(code string) seq.Residue {
+	if r, ok := rm[strings.ToUpper(code)]; ok {
+		return r
+	}
+
+	unmappedLock.Lock()
+	defer unmappedLock.Unlock()
+	if !unmapped[code] {
+		unmapped[code] = true
+		Warnf("No one letter code for residue '%s'. Using 'X'.", code)
+	}
+	return 'X'
+}
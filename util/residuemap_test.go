@@ -0,0 +1,44 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TuftsBCB/seq"
+)
+
+func TestLoadResidueMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "residuemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "residues")
+	data := "# overrides\n\nmse x\n  PYL O  \n"
+	if err := ioutil.WriteFile(fpath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rm := LoadResidueMap(fpath)
+	tests := []struct {
+		code            string
+		custom, builtIn seq.Residue
+	}{
+		{"MSE", 'X', 'M'},
+		{"pyl", 'O', 'X'},
+		{"Ala", 'A', 'A'},
+		{"ZZZ", 'X', 'X'},
+	}
+	for _, test := range tests {
+		if r := rm.Residue(test.code); r != test.custom {
+			t.Errorf("custom map: %s is '%c', want '%c'",
+				test.code, r, test.custom)
+		}
+		if r := DefaultResidueMap.Residue(test.code); r != test.builtIn {
+			t.Errorf("built-in map: %s is '%c', want '%c'",
+				test.code, r, test.builtIn)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/gob"
 	"fmt"
@@ -11,32 +13,60 @@ import (
 	"strings"
 
 	"github.com/ndaniels/esfragbag"
-	"github.com/ndaniels/esfragbag/bow"
 	"github.com/ndaniels/esfragbag/bowdb"
 	"github.com/TuftsBCB/hhfrag"
 	"github.com/TuftsBCB/io/msa"
 	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/io/pdbx"
 	"github.com/TuftsBCB/seq"
 )
 
+// Library opens the fragment library at `fpath`. If `fpath` does not exist,
+// then it is looked up in each directory of the colon-separated list of
+// directories in the FRAGLIB_PATH environment variable, in order. A '.json'
+// extension is added to these look ups when it is missing.
 func Library(fpath string) fragbag.Library {
-	libPath := os.Getenv("FRAGLIB_PATH")
-	if !Exists(fpath) && len(libPath) > 0 {
-		fpath = path.Join(libPath, fpath)
-		if !strings.HasSuffix(fpath, ".json") {
-			fpath += ".json"
+	if !Exists(fpath) {
+		found, tried := findLibrary(fpath, os.Getenv("FRAGLIB_PATH"))
+		if len(found) > 0 {
+			fpath = found
+		} else if len(tried) > 0 {
+			FatalfCode(ExitIO,
+				"Could not find fragment library '%s'. Tried:\n%s",
+				fpath, strings.Join(tried, "\n"))
 		}
 	}
 	lib, err := fragbag.Open(OpenFile(fpath))
-	Assert(err, "Could not open fragment library '%s'", fpath)
+	AssertCode(ExitParse, err, "Could not open fragment library '%s'", fpath)
 	return lib
 }
 
+// findLibrary returns the first file named `name` in the directories of the
+// colon-separated list `libPath`, along with every path that was tried. If
+// no such file exists, the empty string is returned.
+func findLibrary(name, libPath string) (string, []string) {
+	if !strings.HasSuffix(name, ".json") {
+		name += ".json"
+	}
+	tried := make([]string, 0)
+	for _, dir := range strings.Split(libPath, ":") {
+		if len(dir) == 0 {
+			continue
+		}
+		fpath := path.Join(dir, name)
+		if Exists(fpath) {
+			return fpath, tried
+		}
+		tried = append(tried, fpath)
+	}
+	return "", tried
+}
+
 func StructureLibrary(path string) fragbag.StructureLibrary {
 	lib := Library(path)
 	libStruct, ok := lib.(fragbag.StructureLibrary)
 	if !ok {
-		Fatalf("%s (%T) is not a structure library.", path, lib)
+		FatalfCode(ExitUsage, "%s (%T) is not a structure library.", path, lib)
 	}
 	return libStruct
 }
@@ -45,7 +75,7 @@ func SequenceLibrary(path string) fragbag.SequenceLibrary {
 	lib := Library(path)
 	libSeq, ok := lib.(fragbag.SequenceLibrary)
 	if !ok {
-		Fatalf("%s (%T) is not a sequence library.", path, lib)
+		FatalfCode(ExitUsage, "%s (%T) is not a sequence library.", path, lib)
 	}
 	return libSeq
 }
@@ -53,27 +83,42 @@ func SequenceLibrary(path string) fragbag.SequenceLibrary {
 func MSA(path string) seq.MSA {
 	if strings.HasSuffix(path, "a2m") || strings.HasSuffix(path, "a3m") {
 		aligned, err := msa.Read(OpenFile(path))
-		Assert(err, "Could not read MSA (a2m/a3m) from '%s'", path)
+		AssertCode(ExitParse, err, "Could not read MSA (a2m/a3m) from '%s'",
+			path)
 		return aligned
 	}
 	aligned, err := msa.ReadFasta(OpenFile(path))
-	Assert(err, "Could not read MSA (fasta) from '%s'", path)
+	AssertCode(ExitParse, err, "Could not read MSA (fasta) from '%s'", path)
 	return aligned
 }
 
 func OpenBowDB(path string) *bowdb.DB {
 	db, err := bowdb.Open(path)
-	Assert(err, "Could not open BOW database '%s'", path)
+	if err != nil {
+		AssertCode(readCode(err), err, "Could not open BOW database '%s'",
+			path)
+	}
 	return db
 }
 
-func PDBOpenMust(fpath string) (*pdb.Entry, []*pdb.Chain) {
-	entry, chains, err := PDBOpen(fpath)
-	Assert(err)
+func PDBOpenMust(fpath string, model int) (*pdb.Entry, []*pdb.Chain) {
+	entry, chains, err := PDBOpen(fpath, model)
+	if err != nil {
+		AssertCode(readCode(err), err)
+	}
 	return entry, chains
 }
 
-func PDBOpen(fpath string) (*pdb.Entry, []*pdb.Chain, error) {
+// PDBOpen reads the PDB entry corresponding to `fpath` and returns the entry
+// along with the chains selected by `fpath`. (See BowerOpen for the special
+// syntax accepted.)
+//
+// If `model` is greater than zero, then only the model at that position
+// (starting with 1) is kept in each chain returned, so that it is used by
+// methods like `CaAtoms`. Chains without that many models are omitted, and an
+// error is returned if no chain has that many models. If `model` is zero or
+// less, then all models are kept.
+func PDBOpen(fpath string, model int) (*pdb.Entry, []*pdb.Chain, error) {
 	pdbNameParse := func(fpath string) (string, []byte, string) {
 		dir, base := path.Dir(fpath), path.Base(fpath)
 		pieces := strings.Split(base, ":")
@@ -81,13 +126,15 @@ func PDBOpen(fpath string) (*pdb.Entry, []*pdb.Chain, error) {
 		var idents []byte
 		base = pieces[0]
 		if len(pieces) > 2 {
-			Fatalf("Too many colons in PDB file path '%s'.", fpath)
+			FatalfCode(ExitUsage, "Too many colons in PDB file path '%s'.",
+				fpath)
 		} else if len(pieces) == 2 {
 			chains := strings.Split(pieces[1], ",")
 			idents = make([]byte, len(chains))
 			for i := range chains {
 				if len(chains[i]) > 1 {
-					Fatalf("Chain '%s' is more than one character.", chains[i])
+					FatalfCode(ExitUsage,
+						"Chain '%s' is more than one character.", chains[i])
 				}
 				idents[i] = byte(chains[i][0])
 			}
@@ -96,35 +143,30 @@ func PDBOpen(fpath string) (*pdb.Entry, []*pdb.Chain, error) {
 			base = base[0:4]
 		}
 
-		if dir == "." {
-			switch len(base) {
-			case 4:
-				return PDBPath(base), idents, base
-			case 6:
-				return CathPath(base), idents, base
-			case 7:
-				if base[0] == 'd' {
-					return ScopPath(base), idents, base
-				} else {
-					return CathPath(base), idents, base
-				}
+		// A 5 character name left here had a chain list after it (e.g.,
+		// '1ctfA:B'), so it is a file name rather than a PDB id.
+		if dir == "." && len(base) != 5 && len(idKind(base)) > 0 {
+			fp, _, err := ResolveId(base)
+			if err != nil {
+				FatalfCode(ExitUsage, "%s", err)
 			}
+			return fp, idents, base
 		}
 		return path.Join(dir, base), idents, ""
 	}
 
 	fp, idents, idcode := pdbNameParse(fpath)
-	entry, err := pdb.ReadPDB(fp)
+	entry, err := pdbRead(fp)
 	if err != nil {
 		err = fmt.Errorf("Error reading '%s': %s", fp, err)
 		return nil, nil, err
 	}
-	if len(idcode) > 0 {
-		if len(idcode) == 6 || (len(idcode) == 7 && idcode[0] != 'd') {
-			entry.Cath = idcode
-		} else if len(idcode) == 7 && idcode[0] == 'd' {
-			entry.Scop = idcode
-		}
+	SelectAltLocs(entry)
+	switch idKind(idcode) {
+	case IdCATH:
+		entry.Cath = idcode
+	case IdSCOP:
+		entry.Scop = idcode
 	}
 
 	var chains []*pdb.Chain
@@ -142,36 +184,225 @@ func PDBOpen(fpath string) (*pdb.Entry, []*pdb.Chain, error) {
 			chains = append(chains, chain)
 		}
 	}
+	if model > 0 {
+		withModel := make([]*pdb.Chain, 0, len(chains))
+		for _, chain := range chains {
+			if model <= len(chain.Models) {
+				chain.Models = chain.Models[model-1 : model]
+				withModel = append(withModel, chain)
+			}
+		}
+		if len(withModel) == 0 {
+			err = fmt.Errorf("Model %d does not exist in '%s'.", model, fp)
+			return nil, nil, err
+		}
+		chains = withModel
+	}
 	return entry, chains, nil
 }
 
+// PDBRead reads the PDB file at `path`. If `path` is "-", then the PDB file
+// is read from stdin instead. Alternate locations of atoms are handled as
+// described by SelectAltLocs.
 func PDBRead(path string) *pdb.Entry {
+	if path == "-" {
+		entry, err := PDBReadFrom(os.Stdin, "stdin")
+		AssertCode(ExitParse, err, "Could not read PDB file from stdin")
+		return entry
+	}
 	entry, err := pdb.ReadPDB(path)
-	Assert(err, "Could not open PDB file '%s'", path)
+	if err != nil {
+		AssertCode(readCode(err), err, "Could not open PDB file '%s'", path)
+	}
+	SelectAltLocs(entry)
 	return entry
 }
 
+// PDBReadFrom reads a PDB entry from `r`, which may be gzip compressed. Since
+// `r` may not be seekable (e.g., stdin), compression is detected from the
+// first bytes read rather than from a file name. `name` is used as the path
+// of the entry.
+func PDBReadFrom(r io.Reader, name string) (*pdb.Entry, error) {
+	r, err := MaybeGzipReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading '%s': %s", name, err)
+	}
+	entry, err := pdb.Read(r, name)
+	if err != nil {
+		return nil, err
+	}
+	SelectAltLocs(entry)
+	return entry, nil
+}
+
+// MaybeGzipReader returns a reader that decompresses `r` if it starts with
+// the gzip magic number. Otherwise, the bytes of `r` are returned unchanged.
+func MaybeGzipReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// CIFOpen reads the PDBx/mmCIF file at `fpath`, which may be gzipped. As with
+// PDBOpen, a list of chain identifiers may be appended to the file name
+// (e.g., "1ctf.cif.gz:A,B"), in which case only those chains are returned.
+// Otherwise, every chain of every entity in the entry is returned.
+func CIFOpen(fpath string) (*pdbx.Entry, []*pdbx.Chain, error) {
+	var idents []byte
+	pieces := strings.Split(fpath, ":")
+	if len(pieces) > 2 {
+		FatalfCode(ExitUsage, "Too many colons in mmCIF file path '%s'.",
+			fpath)
+	} else if len(pieces) == 2 {
+		for _, c := range strings.Split(pieces[1], ",") {
+			if len(c) != 1 {
+				FatalfCode(ExitUsage,
+					"Chain '%s' is not exactly one character.", c)
+			}
+			idents = append(idents, c[0])
+		}
+	}
+	fpath = pieces[0]
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(fpath, ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error reading '%s': %s", fpath, err)
+		}
+		r = gr
+	}
+	entry, err := pdbx.Read(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading '%s': %s", fpath, err)
+	}
+
+	chains := make([]*pdbx.Chain, 0, 5)
+	for _, ent := range entry.Entities {
+		for _, chain := range ent.Chains {
+			if len(idents) == 0 || bytes.IndexByte(idents, chain.Id) > -1 {
+				chains = append(chains, chain)
+			}
+		}
+	}
+	for _, c := range idents {
+		found := false
+		for _, chain := range chains {
+			found = found || chain.Id == c
+		}
+		if !found {
+			Warnf("Chain '%c' does not exist for '%s'.", c, entry.Id)
+		}
+	}
+	return entry, chains, nil
+}
+
+// Kinds of identifiers returned by ResolveId.
+const (
+	IdPDB  = "pdb"
+	IdSCOP = "scop"
+	IdCATH = "cath"
+)
+
+// ResolveId returns the full path to the PDB file of the identifier given,
+// along with its kind. Identifiers are recognized by their length:
+//
+//	4 or 5 characters: a PDB id, optionally with a chain id (e.g., "1ctfA")
+//	7 characters starting with 'd': a SCOP domain id (e.g., "d3ciua1")
+//	6 or 7 characters otherwise: a CATH domain id (e.g., "2h5xB03")
+//
+// An error is returned if `id` is not an identifier (e.g., it has a
+// directory) or if the environment variable for its kind is not set. (See
+// PDBPath, ScopPath and CathPath.)
+func ResolveId(id string) (string, string, error) {
+	var fpath string
+	var err error
+
+	kind := idKind(id)
+	switch kind {
+	case IdPDB:
+		fpath, err = resolvePDB(id)
+	case IdSCOP:
+		fpath, err = resolveScop(id)
+	case IdCATH:
+		fpath, err = resolveCath(id)
+	default:
+		return "", "", fmt.Errorf("'%s' is not a PDB, SCOP or CATH id.", id)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return fpath, kind, nil
+}
+
+// idKind returns the kind of the identifier given as described by ResolveId,
+// or an empty string if `id` is not an identifier.
+func idKind(id string) string {
+	if path.Dir(id) != "." {
+		return ""
+	}
+	switch {
+	case IsPDBID(id) || IsChainID(id):
+		return IdPDB
+	case len(id) == 7 && id[0] == 'd':
+		return IdSCOP
+	case len(id) == 6 || len(id) == 7:
+		return IdCATH
+	}
+	return ""
+}
+
 // PDBPath takes a PDB identifier (e.g., "1ctf" or "1ctfA") and returns
 // the full path to the PDB file on the file system.
 //
+// The file names "pdb{id}.ent.gz", "pdb{id}.ent" and "pdb{id}.pdb" are tried
+// in that order, and the first one that exists is returned. If none exist,
+// the path to the ".ent.gz" file is returned.
+//
 // The PDB_PATH environment variable must be set.
 func PDBPath(pid string) string {
+	fpath, err := resolvePDB(pid)
+	if err != nil {
+		FatalfCode(ExitUsage, "%s", err)
+	}
+	return fpath
+}
+
+func resolvePDB(pid string) (string, error) {
 	if !IsPDBID(pid) && !IsChainID(pid) {
-		Fatalf("PDB ids must contain 4 or 5 characters, but '%s' has %d.",
-			pid, len(pid))
+		return "", fmt.Errorf("PDB ids must contain 4 or 5 characters, "+
+			"but '%s' has %d.", pid, len(pid))
 	}
 	pdbPath := os.Getenv("PDB_PATH")
 	if len(pdbPath) == 0 || !IsDir(pdbPath) {
-		Fatalf("The PDB_PATH environment variable must be set to open " +
-			"PDB chains by just their ID.\n" +
-			"PDB_PATH should be set to the directory containing a full " +
-			"copy of the PDB database.")
+		return "", fmt.Errorf(
+			"The PDB_PATH environment variable must be set to open " +
+				"PDB chains by just their ID.\n" +
+				"PDB_PATH should be set to the directory containing a full " +
+				"copy of the PDB database.")
 	}
 
 	pdbid := strings.ToLower(pid[0:4])
 	group := pdbid[1:3]
-	basename := fmt.Sprintf("pdb%s.ent.gz", pdbid)
-	return path.Join(pdbPath, group, basename)
+	for _, ext := range []string{"ent.gz", "ent", "pdb"} {
+		p := path.Join(pdbPath, group, fmt.Sprintf("pdb%s.%s", pdbid, ext))
+		if Exists(p) {
+			return p, nil
+		}
+	}
+	return path.Join(pdbPath, group, fmt.Sprintf("pdb%s.ent.gz", pdbid)), nil
 }
 
 // ScopPath takes a SCOP identifier (e.g., "d3ciua1" or "d1g09c_") and returns
@@ -179,21 +410,30 @@ func PDBPath(pid string) string {
 //
 // The SCOP_PDB_PATH environment variable must be set.
 func ScopPath(pid string) string {
+	fpath, err := resolveScop(pid)
+	if err != nil {
+		FatalfCode(ExitUsage, "%s", err)
+	}
+	return fpath
+}
+
+func resolveScop(pid string) (string, error) {
 	if len(pid) != 7 {
-		Fatalf("SCOP domain ids must contain 7 characters, but '%s' has %d.",
-			pid, len(pid))
+		return "", fmt.Errorf("SCOP domain ids must contain 7 characters, "+
+			"but '%s' has %d.", pid, len(pid))
 	}
 	pdbPath := os.Getenv("SCOP_PDB_PATH")
 	if len(pdbPath) == 0 || !IsDir(pdbPath) {
-		Fatalf("The SCOP_PDB_PATH environment variable must be set to open " +
-			"PDB files of SCOP domain by just their ID.\n" +
-			"SCOP_PDB_PATH should be set to the directory containing a full " +
-			"copy of the SCOP database as PDB formatted files.")
+		return "", fmt.Errorf(
+			"The SCOP_PDB_PATH environment variable must be set to open " +
+				"PDB files of SCOP domain by just their ID.\n" +
+				"SCOP_PDB_PATH should be set to the directory containing " +
+				"a full copy of the SCOP database as PDB formatted files.")
 	}
 
 	group := pid[2:4]
 	basename := fmt.Sprintf("%s.ent", pid)
-	return path.Join(pdbPath, group, basename)
+	return path.Join(pdbPath, group, basename), nil
 }
 
 // CathPath takes a CATH identifier (e.g., "2h5xB03") and returns
@@ -201,16 +441,25 @@ func ScopPath(pid string) string {
 //
 // The CATH_PDB_PATH environment variable must be set.
 func CathPath(pid string) string {
+	fpath, err := resolveCath(pid)
+	if err != nil {
+		FatalfCode(ExitUsage, "%s", err)
+	}
+	return fpath
+}
+
+func resolveCath(pid string) (string, error) {
 	if len(pid) < 6 || len(pid) > 7 {
-		Fatalf("CATH domain ids must contain 6 or 7 characters, but '%s' "+
-			"has %d.", pid, len(pid))
+		return "", fmt.Errorf("CATH domain ids must contain 6 or 7 "+
+			"characters, but '%s' has %d.", pid, len(pid))
 	}
 	pdbPath := os.Getenv("CATH_PDB_PATH")
 	if len(pdbPath) == 0 || !IsDir(pdbPath) {
-		Fatalf("The CATH_PDB_PATH environment variable must be set to open " +
-			"PDB files of CATH domain by just their ID.\n" +
-			"CATH_PDB_PATH should be set to the directory containing a full " +
-			"copy of the CATH PDB database as PDB formatted files.")
+		return "", fmt.Errorf(
+			"The CATH_PDB_PATH environment variable must be set to open " +
+				"PDB files of CATH domain by just their ID.\n" +
+				"CATH_PDB_PATH should be set to the directory containing " +
+				"a full copy of the CATH PDB database as PDB formatted files.")
 	}
 
 	// We have to deal with some old data sets using 6-character domain IDs.
@@ -220,12 +469,12 @@ func CathPath(pid string) string {
 		if pid[4] == '0' {
 			pid_ := fmt.Sprintf("%sA%s", pid[0:4], pid[4:6])
 			if p := path.Join(pdbPath, pid_); Exists(p) {
-				return p
+				return p, nil
 			}
 		}
 		pid = fmt.Sprintf("%s0%c", pid[0:5], pid[5])
 	}
-	return path.Join(pdbPath, pid)
+	return path.Join(pdbPath, pid), nil
 }
 
 func PDBReadId(pid string) (*pdb.Entry, *pdb.Chain) {
@@ -233,7 +482,7 @@ func PDBReadId(pid string) (*pdb.Entry, *pdb.Chain) {
 	if IsChainID(pid) {
 		chain := e.Chain(pid[4])
 		if chain == nil {
-			Fatalf("Could not find chain '%s' in PDB entry '%s'.", pid[4], pid)
+			Fatalf("Could not find chain '%c' in PDB entry '%s'.", pid[4], pid)
 		}
 		return e, chain
 	}
@@ -251,7 +500,8 @@ func GetFmap(fpath string) *hhfrag.FragmentMap {
 	case IsFmap(fpath):
 		fmap = FmapRead(fpath)
 	default:
-		Fatalf("File '%s' is not a fasta or fmap file.", fpath)
+		FatalfCode(ExitUsage, "File '%s' is not a fasta or fmap file.",
+			fpath)
 	}
 
 	return fmap
@@ -272,33 +522,76 @@ func FmapWrite(w io.Writer, fmap *hhfrag.FragmentMap) {
 	Assert(encoder.Encode(fmap), "Could not GOB encode fragment map")
 }
 
-func BowRead(path string) bow.Bowed {
-	var b bow.Bowed
-	f := OpenFile(path)
-	defer f.Close()
-
-	r := gob.NewDecoder(f)
-	Assert(r.Decode(&b), "Could not GOB decode BOW '%s'", path)
-	return b
-}
-
-func BowWrite(w io.Writer, b bow.Bowed) {
-	encoder := gob.NewEncoder(w)
-	Assert(encoder.Encode(b), "Could not GOB encode BOW")
-}
-
 func OpenFile(path string) *os.File {
 	f, err := os.Open(path)
-	Assert(err, "Could not open file '%s'", path)
+	AssertCode(ExitIO, err, "Could not open file '%s'", path)
 	return f
 }
 
 func CreateFile(path string) *os.File {
 	f, err := os.Create(path)
-	Assert(err, "Could not create file '%s'", path)
+	AssertCode(ExitIO, err, "Could not create file '%s'", path)
 	return f
 }
 
+// CreateFileMaybeGz creates the file at `path` and returns a writer to it. If
+// `path` ends with ".gz", then everything written is gzip compressed.
+//
+// The writer must be closed, since closing it writes the gzip trailer (if
+// any) before closing the underlying file.
+func CreateFileMaybeGz(path string) io.WriteCloser {
+	w, err := CreateMaybeGz(path)
+	AssertCode(ExitIO, err, "Could not create file '%s'", path)
+	return w
+}
+
+// CreateMaybeGz is like CreateFileMaybeGz, except an error is returned if
+// the file could not be created.
+func CreateMaybeGz(path string) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	return &gzipFile{gzip.NewWriter(f), f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (gf *gzipFile) Close() error {
+	if err := gf.Writer.Close(); err != nil {
+		gf.f.Close()
+		return err
+	}
+	return gf.f.Close()
+}
+
+// OpenMaybeGz opens the file at `path` for reading. If the file is gzip
+// compressed, then its contents are decompressed as they are read. The file
+// name is not used to detect compression.
+//
+// Closing the reader returned closes the underlying file.
+func OpenMaybeGz(path string) io.ReadCloser {
+	f := OpenFile(path)
+	r, err := MaybeGzipReader(f)
+	AssertCode(ExitParse, err, "Could not open '%s'", path)
+	return &maybeGzFile{r, f}
+}
+
+type maybeGzFile struct {
+	io.Reader
+	f *os.File
+}
+
+func (gf *maybeGzFile) Close() error {
+	return gf.f.Close()
+}
+
 func ParseInt(str string) int {
 	num, err := strconv.ParseInt(str, 10, 32)
 	Assert(err, "Could not parse '%s' as an integer", str)
@@ -336,7 +629,15 @@ func IsPDB(fpath string) bool {
 	suffix := func(ext string) bool {
 		return strings.HasSuffix(base, ext)
 	}
-	return suffix(".ent.gz") || suffix(".pdb") || suffix(".ent")
+	if suffix(".ent.gz") || suffix(".pdb") || suffix(".ent") {
+		return true
+	}
+	return IsPDBBundle(path.Join(path.Dir(fpath), base))
+}
+
+func IsCIF(fpath string) bool {
+	base := strings.Split(path.Base(fpath), ":")[0]
+	return strings.HasSuffix(base, ".cif") || strings.HasSuffix(base, ".cif.gz")
 }
 
 func IsChainID(s string) bool {
@@ -0,0 +1,249 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateFileMaybeGz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maybegz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := bytes.Repeat([]byte("ATOM  CA  ALA A   1\n"), 100)
+	for _, name := range []string{"plain.txt", "zipped.txt.gz"} {
+		fpath := filepath.Join(dir, name)
+		w := CreateFileMaybeGz(fpath)
+		if _, err := w.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gzipped := len(raw) >= 2 && raw[0] == 0x1f && raw[1] == 0x8b
+		if want := filepath.Ext(name) == ".gz"; gzipped != want {
+			t.Errorf("%s: gzipped is %v, want %v", name, gzipped, want)
+		}
+
+		r := OpenMaybeGz(fpath)
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, contents) {
+			t.Errorf("%s: contents are not read back as written", name)
+		}
+	}
+}
+
+func TestMaybeGzipReaderShort(t *testing.T) {
+	// Inputs shorter than the gzip magic number are read as they are.
+	for _, s := range []string{"", "x"} {
+		r, err := MaybeGzipReader(bytes.NewReader([]byte(s)))
+		if err != nil {
+			t.Fatalf("%q: %s", s, err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		if string(got) != s {
+			t.Errorf("read %q, want %q", got, s)
+		}
+	}
+}
+
+func TestFindLibrary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fraglibpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"),
+		filepath.Join(dir, "c")
+	for _, d := range []string{a, b, c} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, fpath := range []string{
+		filepath.Join(b, "lib.json"),
+		filepath.Join(c, "lib.json"),
+		filepath.Join(c, "other.json"),
+	} {
+		if err := ioutil.WriteFile(fpath, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	libPath := strings.Join([]string{a, "", b, c}, ":")
+	tests := []struct {
+		name  string
+		found string
+		tried []string
+	}{
+		{"lib", filepath.Join(b, "lib.json"),
+			[]string{filepath.Join(a, "lib.json")}},
+		{"lib.json", filepath.Join(b, "lib.json"),
+			[]string{filepath.Join(a, "lib.json")}},
+		{"other", filepath.Join(c, "other.json"),
+			[]string{filepath.Join(a, "other.json"),
+				filepath.Join(b, "other.json")}},
+		{"missing", "",
+			[]string{filepath.Join(a, "missing.json"),
+				filepath.Join(b, "missing.json"),
+				filepath.Join(c, "missing.json")}},
+	}
+	for _, test := range tests {
+		found, tried := findLibrary(test.name, libPath)
+		if found != test.found {
+			t.Errorf("%s: found '%s', want '%s'", test.name, found, test.found)
+		}
+		if !reflect.DeepEqual(tried, test.tried) {
+			t.Errorf("%s: tried %q, want %q", test.name, tried, test.tried)
+		}
+	}
+}
+
+func TestResolveId(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := func(elems ...string) string {
+		return filepath.Join(append([]string{dir}, elems...)...)
+	}
+	for _, d := range []string{"pdb/ab", "scop", "cath"} {
+		if err := os.MkdirAll(p(d), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, fpath := range []string{p("pdb/ab/pdb2abc.pdb"), p("cath/1cukA01")} {
+		if err := ioutil.WriteFile(fpath, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PDB_PATH", p("pdb"))
+	t.Setenv("SCOP_PDB_PATH", p("scop"))
+	t.Setenv("CATH_PDB_PATH", p("cath"))
+
+	tests := []struct {
+		id, fpath, kind string
+	}{
+		{"1ctf", p("pdb/ct/pdb1ctf.ent.gz"), IdPDB},
+		{"1CTFA", p("pdb/ct/pdb1ctf.ent.gz"), IdPDB},
+		{"2abcB", p("pdb/ab/pdb2abc.pdb"), IdPDB},
+		{"d3ciua1", p("scop/ci/d3ciua1.ent"), IdSCOP},
+		{"d1g09c_", p("scop/g0/d1g09c_.ent"), IdSCOP},
+		{"2h5xB03", p("cath/2h5xB03"), IdCATH},
+		{"1cuk01", p("cath/1cukA01"), IdCATH},
+		{"2h5xB3", p("cath/2h5xB03"), IdCATH},
+		{"1abc", p("pdb/ab/pdb1abc.ent.gz"), IdPDB},
+		{"dabcdef", p("scop/bc/dabcdef.ent"), IdSCOP},
+		{"abcdefg", p("cath/abcdefg"), IdCATH},
+	}
+	for _, test := range tests {
+		fpath, kind, err := ResolveId(test.id)
+		if err != nil {
+			t.Errorf("%s: %s", test.id, err)
+			continue
+		}
+		if fpath != test.fpath || kind != test.kind {
+			t.Errorf("%s: got (%s, %s), want (%s, %s)",
+				test.id, fpath, kind, test.fpath, test.kind)
+		}
+	}
+
+	for _, id := range []string{"", "1ct", "1ctf.pdb", "dir/1ctf", "d1g09c_1"} {
+		if fpath, _, err := ResolveId(id); err == nil {
+			t.Errorf("%q is not an id, but resolved to '%s'", id, fpath)
+		}
+	}
+
+	// Each kind needs its own environment variable.
+	t.Setenv("SCOP_PDB_PATH", "")
+	if _, _, err := ResolveId("d3ciua1"); err == nil {
+		t.Errorf("resolved a SCOP id without SCOP_PDB_PATH")
+	}
+	if _, _, err := ResolveId("2h5xB03"); err != nil {
+		t.Errorf("could not resolve a CATH id without SCOP_PDB_PATH: %s", err)
+	}
+}
+
+func TestPDBPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pdbpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := func(name string) string { return filepath.Join(dir, name) }
+	files := []string{
+		"aa/pdb1aaa.ent.gz",
+		"bb/pdb1bbb.ent",
+		"cc/pdb1ccc.pdb",
+		"dd/pdb1ddd.ent.gz", "dd/pdb1ddd.ent", "dd/pdb1ddd.pdb",
+		"ee/pdb1eee.ent", "ee/pdb1eee.pdb",
+	}
+	for _, name := range files {
+		if err := os.MkdirAll(filepath.Dir(p(name)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p(name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PDB_PATH", dir)
+
+	tests := []struct {
+		id, fpath string
+	}{
+		{"1aaa", "aa/pdb1aaa.ent.gz"},
+		{"1bbb", "bb/pdb1bbb.ent"},
+		{"1ccc", "cc/pdb1ccc.pdb"},
+		{"1CCCA", "cc/pdb1ccc.pdb"},
+		{"1ddd", "dd/pdb1ddd.ent.gz"},
+		{"1eee", "ee/pdb1eee.ent"},
+		{"1fff", "ff/pdb1fff.ent.gz"},
+	}
+	for _, test := range tests {
+		fpath, err := resolvePDB(test.id)
+		if err != nil {
+			t.Errorf("%s: %s", test.id, err)
+			continue
+		}
+		if fpath != p(test.fpath) {
+			t.Errorf("%s: got '%s', want '%s'", test.id, fpath, p(test.fpath))
+		}
+		if fpath != PDBPath(test.id) {
+			t.Errorf("%s: PDBPath and resolvePDB differ", test.id)
+		}
+	}
+
+	for _, pdbPath := range []string{"", p("aa/pdb1aaa.ent.gz"), p("zz")} {
+		t.Setenv("PDB_PATH", pdbPath)
+		if fpath, err := resolvePDB("1aaa"); err == nil {
+			t.Errorf("PDB_PATH '%s' is not a directory, but '1aaa' "+
+				"resolved to '%s'", pdbPath, fpath)
+		}
+	}
+}
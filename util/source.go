@@ -0,0 +1,86 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/seq"
+	"github.com/ndaniels/esfragbag"
+)
+
+// SequenceSource returns the source of chain sequences (SourceEntity or
+// SourceAtom) to use with the fragment library given. This is FlagSource if
+// it is set, and otherwise SourceEntity for sequence libraries and
+// SourceAtom for structure libraries.
+func SequenceSource(lib fragbag.Library) string {
+	switch {
+	case len(FlagSource) > 0:
+		return FlagSource
+	case fragbag.IsStructure(lib):
+		return SourceAtom
+	}
+	return SourceEntity
+}
+
+// ChainSequence returns the amino acid sequence of the chain given from
+// `source`. If `source` is SourceEntity and the chain has no declared
+// sequence, then the residues with coordinates are used instead.
+func ChainSequence(chain *pdb.Chain, source string) seq.Sequence {
+	var s seq.Sequence
+	if source == SourceEntity {
+		s = chain.AsSequence()
+	}
+	if s.Len() == 0 && len(chain.Models) > 0 {
+		s = aminoFromStructure(chain)
+	}
+	return s
+}
+
+// SequenceFromId finds the amino acid sequence of the source of a BOW from
+// its id. Only ids that name a PDB entry or chain (or a SCOP or CATH domain)
+// can be resolved, as with ResolveId. If a PDB file has more than one
+// protein chain, the first is used. The sequence is read from the source
+// that SequenceSource gives for `lib`. The name of the sequence returned is
+// the id given.
+func SequenceFromId(id string, lib fragbag.Library) (seq.Sequence, error) {
+	fpath, kind, err := ResolveId(id)
+	if err != nil {
+		return seq.Sequence{}, fmt.Errorf("cannot find the source of '%s': "+
+			"%s", id, err)
+	}
+	if kind == IdPDB && IsChainID(id) {
+		fpath = fmt.Sprintf("%s:%c", fpath, id[4])
+	}
+	_, chains, err := PDBOpen(fpath, 0)
+	if err != nil {
+		return seq.Sequence{}, err
+	}
+	for _, chain := range chains {
+		if !chain.IsProtein() {
+			continue
+		}
+		if s := ChainSequence(chain, SequenceSource(lib)); s.Len() > 0 {
+			s.Name = id
+			return s, nil
+		}
+	}
+	return seq.Sequence{}, fmt.Errorf("'%s' has no protein sequence", id)
+}
+
+// SafeFileName returns a file name for the id given that is safe to use on
+// any file system.
+func SafeFileName(id string) string {
+	name := []byte(id)
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.' || c == '-' || c == '_':
+		default:
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] == '.' {
+		name = append([]byte{'_'}, name...)
+	}
+	return string(name)
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/TuftsBCB/io/pdb"
+	"github.com/TuftsBCB/seq"
+	"github.com/TuftsBCB/structure"
+	"github.com/ndaniels/esfragbag"
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// alaLib is a library of two fragments of one residue: fragment 0 matches
+// alanine and fragment 1 matches everything else.
+type alaLib struct{}
+
+func (alaLib) Save(w io.Writer) error      { return nil }
+func (alaLib) Size() int                   { return 2 }
+func (alaLib) FragmentSize() int           { return 1 }
+func (alaLib) String() string              { return "ala" }
+func (alaLib) Name() string                { return "ala" }
+func (alaLib) Tag() string                 { return "" }
+func (alaLib) Fragment(i int) interface{}  { return nil }
+func (alaLib) SubLibrary() fragbag.Library { return nil }
+
+func (alaLib) BestSequenceFragment(s seq.Sequence) int {
+	if s.Residues[0] == 'A' {
+		return 0
+	}
+	return 1
+}
+
+// alaStructLib is a structure library with the same fragments as alaLib.
+type alaStructLib struct{ alaLib }
+
+func (alaStructLib) BestStructureFragment([]structure.Coords) int { return 0 }
+func (alaStructLib) Atoms(i int) []structure.Coords               { return nil }
+
+// sourceChain returns a chain whose declared sequence is 'MKTAYIAKQR' and
+// whose residues with coordinates are 'KTAYI'.
+func sourceChain() *pdb.Chain {
+	entry := &pdb.Entry{IdCode: "1abc"}
+	chain := &pdb.Chain{Entry: entry, Ident: 'A'}
+	chain.Sequence = seq.NewSequenceString("", "MKTAYIAKQR").Residues
+	model := &pdb.Model{Entry: entry, Chain: chain, Num: 1}
+	for i, r := range "KTAYI" {
+		model.Residues = append(model.Residues, &pdb.Residue{
+			Name:        seq.Residue(r),
+			SequenceNum: i + 2,
+			Atoms:       []pdb.Atom{{Name: "CA"}},
+		})
+	}
+	chain.Models = []*pdb.Model{model}
+	entry.Chains = []*pdb.Chain{chain}
+	return chain
+}
+
+func TestSequenceSource(t *testing.T) {
+	defer func() { FlagSource = "" }()
+
+	FlagSource = ""
+	if src := SequenceSource(alaLib{}); src != SourceEntity {
+		t.Errorf("default source of a sequence library = %q, want %q",
+			src, SourceEntity)
+	}
+	if src := SequenceSource(alaStructLib{}); src != SourceAtom {
+		t.Errorf("default source of a structure library = %q, want %q",
+			src, SourceAtom)
+	}
+	FlagSource = SourceAtom
+	if src := SequenceSource(alaLib{}); src != SourceAtom {
+		t.Errorf("'-source atom' with a sequence library = %q, want %q",
+			src, SourceAtom)
+	}
+}
+
+func TestChainSequence(t *testing.T) {
+	chain := sourceChain()
+	tests := []struct {
+		source   string
+		residues string
+		freqs    []float32
+	}{
+		{SourceEntity, "MKTAYIAKQR", []float32{2, 8}},
+		{SourceAtom, "KTAYI", []float32{1, 4}},
+	}
+	for _, test := range tests {
+		s := ChainSequence(chain, test.source)
+		if got := string(residueBytes(s)); got != test.residues {
+			t.Errorf("'%s' sequence = %q, want %q",
+				test.source, got, test.residues)
+		}
+		b := bow.BowerFromSequence(s).SequenceBow(alaLib{})
+		if !reflect.DeepEqual(b.Bow.Freqs, test.freqs) {
+			t.Errorf("'%s' BOW = %v, want %v",
+				test.source, b.Bow.Freqs, test.freqs)
+		}
+	}
+
+	chain.Sequence = nil
+	s := ChainSequence(chain, SourceEntity)
+	if got := string(residueBytes(s)); got != "KTAYI" {
+		t.Errorf("'entity' sequence of a chain without SEQRES = %q, want %q",
+			got, "KTAYI")
+	}
+}
+
+func TestSequenceFromIdUnresolved(t *testing.T) {
+	// Ids that cannot be resolved are errors, so that callers may skip
+	// them, rather than fatal errors.
+	t.Setenv("PDB_PATH", "")
+	for _, id := range []string{"1ctfA", "query.fasta", "dir/1ctf"} {
+		if _, err := SequenceFromId(id, alaLib{}); err == nil {
+			t.Errorf("found a sequence for '%s'", id)
+		}
+	}
+}
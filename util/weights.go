@@ -0,0 +1,95 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+// BowWeights is a weight for each fragment in a fragment library. Weighting
+// BOWs before comparing them decreases the influence of fragments that are
+// common to most BOWs.
+type BowWeights []float32
+
+// ComputeIDF computes inverse document frequency weights for a fragment
+// library with `size` fragments from the BOWs given. The weight of fragment
+// i is
+//
+//	idf(i) = ln((1 + N) / (1 + df(i))) + 1
+//
+// where N is the number of BOWs and df(i) is the number of BOWs in which
+// fragment i has a non-zero frequency. The weights are smoothed so that a
+// fragment appearing in every BOW still has a weight of 1, and a fragment
+// appearing in no BOW does not cause a division by zero.
+func ComputeIDF(entries []bow.Bowed, size int) BowWeights {
+	df := make([]int, size)
+	for _, entry := range entries {
+		for i, f := range entry.Bow.Freqs {
+			if i < size && f > 0 {
+				df[i]++
+			}
+		}
+	}
+
+	n := float64(len(entries))
+	weights := make(BowWeights, size)
+	for i := range weights {
+		weights[i] = float32(math.Log((1+n)/(1+float64(df[i]))) + 1)
+	}
+	return weights
+}
+
+// Apply returns a new BOW where the frequency of each fragment is multiplied
+// by its weight. (i.e., a TF-IDF vector when the weights are from ComputeIDF.)
+// The BOW given must have the same number of fragments as there are weights.
+func (ws BowWeights) Apply(b bow.Bow) bow.Bow {
+	Assert(ws.check(b), "Cannot apply weights")
+
+	weighted := bow.NewBow(len(ws))
+	for i, w := range ws {
+		weighted.Freqs[i] = b.Freqs[i] * w
+	}
+	return weighted
+}
+
+func (ws BowWeights) check(b bow.Bow) error {
+	if len(b.Freqs) != len(ws) {
+		return fmt.Errorf("BOW has %d fragments, but there are %d weights",
+			len(b.Freqs), len(ws))
+	}
+	return nil
+}
+
+// WriteBowWeights writes weights as plain text with one weight per line, in
+// the order of the fragments in the library.
+func WriteBowWeights(w io.Writer, ws BowWeights) error {
+	for _, weight := range ws {
+		if _, err := fmt.Fprintf(w, "%f\n", weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBowWeights reads weights written by WriteBowWeights from the file at
+// `path`. Blank lines and lines starting with '#' are ignored.
+func ReadBowWeights(path string) BowWeights {
+	f := OpenFile(path)
+	defer f.Close()
+
+	var ws BowWeights
+	for i, line := range ReadLines(f) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		w, err := strconv.ParseFloat(line, 32)
+		Assert(err, "Could not parse weight on line %d in '%s'", i+1, path)
+		ws = append(ws, float32(w))
+	}
+	return ws
+}
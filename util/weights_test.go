@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndaniels/esfragbag/bow"
+)
+
+func TestComputeIDF(t *testing.T) {
+	bowed := func(freqs ...float32) bow.Bowed {
+		return bow.Bowed{Bow: bow.Bow{Freqs: freqs}}
+	}
+	// Fragment 0 is in every BOW, fragment 1 in two, fragment 2 in one and
+	// fragment 3 in none.
+	db := []bow.Bowed{
+		bowed(1, 2, 0, 0),
+		bowed(3, 1, 0, 0),
+		bowed(1, 0, 5, 0),
+		bowed(2, 0, 0, 0),
+	}
+	ws := ComputeIDF(db, 4)
+	want := []float64{
+		1,
+		math.Log(5.0/3.0) + 1,
+		math.Log(5.0/2.0) + 1,
+		math.Log(5.0) + 1,
+	}
+	for i := range want {
+		if math.Abs(float64(ws[i])-want[i]) > 1e-6 {
+			t.Errorf("weight of fragment %d = %f, want %f", i, ws[i], want[i])
+		}
+	}
+
+	weighted := ws.Apply(db[2].Bow)
+	for i, f := range db[2].Bow.Freqs {
+		if weighted.Freqs[i] != f*ws[i] {
+			t.Errorf("weighted frequency of fragment %d = %f, want %f",
+				i, weighted.Freqs[i], f*ws[i])
+		}
+	}
+
+	// Rare fragments count for more, so BOWs that share only a common
+	// fragment become farther apart.
+	b1, b2 := bowed(1, 0, 1, 0).Bow, bowed(1, 1, 0, 0).Bow
+	if ws.Apply(b1).Cosine(ws.Apply(b2)) <= b1.Cosine(b2) {
+		t.Errorf("weighting did not increase the distance of BOWs that " +
+			"only share a common fragment")
+	}
+}
+
+func TestBowWeightsRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "weights")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ws := BowWeights{1, 1.5, 2.25, 3.125}
+	buf := new(bytes.Buffer)
+	if err := WriteBowWeights(buf, ws); err != nil {
+		t.Fatal(err)
+	}
+	contents := append([]byte("# idf weights\n\n"), buf.Bytes()...)
+	fpath := filepath.Join(dir, "weights")
+	if err := ioutil.WriteFile(fpath, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := ReadBowWeights(fpath)
+	if len(got) != len(ws) {
+		t.Fatalf("read %d weights, want %d", len(got), len(ws))
+	}
+	for i := range ws {
+		if got[i] != ws[i] {
+			t.Errorf("weight %d = %f, want %f", i, got[i], ws[i])
+		}
+	}
+}